@@ -0,0 +1,156 @@
+package bus
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHistorySize = 1024
+	subscriberBuffer   = 256
+)
+
+const (
+	TypeGitCommit             = "git.commit"
+	TypeGitPollFailed         = "git.poll_failed"
+	TypeCommitSkipped         = "git.commit_skipped"
+	TypeCommitRejected        = "git.commit_rejected"
+	TypeTagDowngradeRefused   = "git.downgrade_refused"
+	TypeReconcileStarted      = "reconcile.started"
+	TypeReconcileFinished     = "reconcile.finished"
+	TypeDriftDetected         = "drift.detected"
+	TypeDriftRemediated       = "drift.remediated"
+	TypeApprovalRequested     = "approval.requested"
+	TypeServiceDeployStarted  = "deploy.service.started"
+	TypeServiceImagePulled    = "deploy.service.pulled"
+	TypeServiceUnchanged      = "deploy.service.unchanged"
+	TypeServiceDeployFinished = "deploy.service.finished"
+	TypeServiceDeployFailed   = "deploy.service.failed"
+	TypeServicePruned         = "deploy.service.pruned"
+	TypeConfigReloaded        = "config.reloaded"
+	TypeConfigRejected        = "config.rejected"
+)
+
+type Event struct {
+	ID      uint64         `json:"id"`
+	Type    string         `json:"type"`
+	Repo    string         `json:"repo,omitempty"`
+	App     string         `json:"app,omitempty"`
+	Service string         `json:"service,omitempty"`
+	Commit  string         `json:"commit,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+	Time    time.Time      `json:"time"`
+}
+
+type Filter struct {
+	Repos []string
+	Apps  []string
+	Types []string
+}
+
+func (f Filter) Matches(e *Event) bool {
+	if len(f.Repos) > 0 && !slices.Contains(f.Repos, e.Repo) {
+		return false
+	}
+	if len(f.Apps) > 0 && !slices.Contains(f.Apps, e.App) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(f.Types, func(t string) bool {
+		return e.Type == t || strings.HasPrefix(e.Type, t+".")
+	})
+}
+
+type subscriber struct {
+	ch     chan Event
+	filter Filter
+}
+
+type Bus struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Event
+	historySize int
+	subs        map[*subscriber]struct{}
+}
+
+func New(historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Bus{
+		historySize: historySize,
+		subs:        make(map[*subscriber]struct{}),
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	e.ID = b.nextID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > b.historySize {
+		b.history = slices.Clone(b.history[len(b.history)-b.historySize:])
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.Matches(&e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+func (b *Bus) Subscribe(filter Filter, lastID uint64) (events <-chan Event, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if lastID > 0 && lastID <= b.nextID {
+		for i := range b.history {
+			if b.history[i].ID > lastID && filter.Matches(&b.history[i]) {
+				replay = append(replay, b.history[i])
+			}
+		}
+	}
+
+	sub := &subscriber{
+		ch:     make(chan Event, subscriberBuffer+len(replay)),
+		filter: filter,
+	}
+	for _, e := range replay {
+		sub.ch <- e
+	}
+	b.subs[sub] = struct{}{}
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[sub]; ok {
+				delete(b.subs, sub)
+				close(sub.ch)
+			}
+		})
+	}
+}
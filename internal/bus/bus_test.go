@@ -0,0 +1,133 @@
+package bus
+
+import (
+	"testing"
+	"time"
+)
+
+const testRepo = "test-repo"
+
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	b := New(0)
+	events, cancel := b.Subscribe(Filter{}, 0)
+	defer cancel()
+
+	b.Publish(Event{Type: TypeGitCommit, Repo: testRepo, Commit: "abc"})
+
+	e := receive(t, events)
+	if e.ID != 1 {
+		t.Errorf("id: got %d, want 1", e.ID)
+	}
+	if e.Time.IsZero() {
+		t.Error("expected time to be set")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		event  Event
+		want   bool
+	}{
+		{"empty", Filter{}, Event{Type: TypeGitCommit}, true},
+		{"repo match", Filter{Repos: []string{testRepo}}, Event{Type: TypeGitCommit, Repo: testRepo}, true},
+		{"repo mismatch", Filter{Repos: []string{"other"}}, Event{Type: TypeGitCommit, Repo: testRepo}, false},
+		{"type prefix", Filter{Types: []string{"deploy"}}, Event{Type: TypeServiceDeployStarted}, true},
+		{"type exact", Filter{Types: []string{TypeDriftDetected}}, Event{Type: TypeDriftDetected}, true},
+		{"partial word", Filter{Types: []string{"dep"}}, Event{Type: TypeServiceDeployStarted}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(&tt.event); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeResume(t *testing.T) {
+	b := New(0)
+	for range 3 {
+		b.Publish(Event{Type: TypeGitCommit, Repo: testRepo})
+	}
+	b.Publish(Event{Type: TypeGitCommit, Repo: "other"})
+
+	events, cancel := b.Subscribe(Filter{Repos: []string{testRepo}}, 1)
+	defer cancel()
+
+	for _, want := range []uint64{2, 3} {
+		if e := receive(t, events); e.ID != want {
+			t.Errorf("id: got %d, want %d", e.ID, want)
+		}
+	}
+
+	b.Publish(Event{Type: TypeGitCommit, Repo: testRepo})
+	if e := receive(t, events); e.ID != 5 {
+		t.Errorf("id: got %d, want 5", e.ID)
+	}
+}
+
+func TestHistoryBounded(t *testing.T) {
+	b := New(2)
+	for range 5 {
+		b.Publish(Event{Type: TypeGitCommit})
+	}
+
+	events, cancel := b.Subscribe(Filter{}, 1)
+	defer cancel()
+
+	if e := receive(t, events); e.ID != 4 {
+		t.Errorf("id: got %d, want 4", e.ID)
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	b := New(0)
+	events, cancel := b.Subscribe(Filter{}, 0)
+	defer cancel()
+
+	for range subscriberBuffer + 1 {
+		b.Publish(Event{Type: TypeGitCommit})
+	}
+
+	count := 0
+	for range events {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("received: got %d, want %d", count, subscriberBuffer)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	b := New(0)
+	events, cancel := b.Subscribe(Filter{}, 0)
+	cancel()
+	cancel()
+
+	if _, ok := <-events; ok {
+		t.Error("expected channel to be closed")
+	}
+	b.Publish(Event{Type: TypeGitCommit})
+}
+
+func TestNilBus(t *testing.T) {
+	var b *Bus
+	b.Publish(Event{Type: TypeGitCommit})
+}
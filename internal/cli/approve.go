@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/controller"
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/reconcile"
+)
+
+var reviewFlags struct {
+	as string
+}
+
+var approveCmd = &cobra.Command{
+	Use:   "approve <id>",
+	Short: "Approve a deployment awaiting approval",
+	Long:  `Apply a deployment that is waiting for approval in manual or notify mode and record who approved it.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runApprove,
+}
+
+func init() {
+	approveCmd.Flags().StringVar(&reviewFlags.as, "as", "", "Identity recorded as the approver (defaults to the current user)")
+	rootCmd.AddCommand(approveCmd)
+}
+
+func runApprove(cmd *cobra.Command, args []string) error {
+	id, err := parseDeploymentID(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	ctrl, err := newStandaloneController(ctx)
+	if err != nil {
+		return err
+	}
+	defer ctrl.Close()
+
+	deployment, err := ctrl.Approve(ctx, id, reviewerIdentity())
+	if err != nil {
+		return fmt.Errorf("approve deployment %d: %w", id, err)
+	}
+
+	fmt.Printf("Approved deployment %d (commit %s)\n", deployment.ID, lo.Substring(deployment.CommitHash, 0, 8))
+	return nil
+}
+
+func newStandaloneController(ctx context.Context) (*controller.Controller, error) {
+	if err := requireTarget(); err != nil {
+		return nil, err
+	}
+	ctrlCfg := controllerConfig()
+	var opts []controller.Option
+	if repo.TrustKeysPath != "" {
+		keys, err := git.LoadKeyring(repo.TrustKeysPath)
+		if err != nil {
+			return nil, err
+		}
+		ctrlCfg.TrustKeys, ctrlCfg.RequireSignedTags = keys, repo.SignedTags
+
+		var watcherOpts []git.WatcherOption
+		if repo.TagPattern != "" {
+			watcherOpts = append(watcherOpts, git.WithTags(repo.TagPattern, repo.TagConstraint))
+		}
+		checkout := git.NewWatcher(repo.URL, repo.Branch, ctrlCfg.WorkDir, 0, logger, watcherOpts...)
+		if err := checkout.Open(); err != nil {
+			return nil, err
+		}
+		opts = append(opts, controller.WithWatcher(checkout))
+	}
+	return controller.NewStandalone(ctx, ctrlCfg, nil, logger, opts...)
+}
+
+func controllerConfig() controller.Config {
+	ctrlCfg := controller.Config{
+		RepoName:     repo.Name,
+		ProjectName:  cfg.Docker.ProjectName,
+		ComposeFiles: cfg.Docker.Files(),
+		Profiles:     cfg.Docker.Profiles,
+		WorkDir:      repoWorkDir(repo.Name),
+		StatePath:    cfg.State.Path,
+		ReconcileCfg: reconcile.Config{Mode: reconcile.ModeAuto},
+		KeyFile:      cfg.State.KeyFile,
+	}
+	if app != nil {
+		ctrlCfg.AppName, ctrlCfg.AppPath = app.Name, app.Path
+	}
+	return ctrlCfg
+}
+
+func parseDeploymentID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid deployment id: %s", s)
+	}
+	return id, nil
+}
+
+func reviewerIdentity() string {
+	if reviewFlags.as != "" {
+		return reviewFlags.as
+	}
+	return cliActor()
+}
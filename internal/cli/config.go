@@ -0,0 +1,13 @@
+package cli
+
+import "github.com/spf13/cobra"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect configuration",
+	Long:  `Commands for inspecting the configuration kedge resolves from defaults, config files, environment variables and flags.`,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
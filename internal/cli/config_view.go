@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+)
+
+var configViewFlags struct {
+	all bool
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the effective configuration",
+	Long: `Show every configuration value kedge resolved and where it came from.
+
+Precedence is flags, then KEDGE_* environment variables, then config files, then built-in defaults. With --repo, the repository's kedge.yaml is applied on top of the global config file.`,
+	RunE: runConfigView,
+}
+
+func init() {
+	configViewCmd.Flags().BoolVar(&configViewFlags.all, "all", false, "Include keys that are unset")
+	configCmd.AddCommand(configViewCmd)
+}
+
+func runConfigView(cmd *cobra.Command, args []string) error {
+	fmt.Printf("%-48s  %-30s  %s\n", "KEY", "VALUE", "SOURCE")
+	fmt.Println("------------------------------------------------  ------------------------------  ------")
+	for _, s := range cfgLoader.Settings() {
+		if s.Value == "" && s.Source == config.SourceDefault && !configViewFlags.all {
+			continue
+		}
+		source := string(s.Source)
+		if s.Origin != "" {
+			source = fmt.Sprintf("%s (%s)", s.Source, s.Origin)
+		}
+		fmt.Printf("%-48s  %-30s  %s\n", s.Key, s.Value, source)
+	}
+	return nil
+}
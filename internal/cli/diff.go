@@ -3,7 +3,6 @@ package cli
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 
 	"github.com/LoriKarikari/kedge/internal/docker"
 	"github.com/spf13/cobra"
@@ -21,8 +20,8 @@ func init() {
 }
 
 func runDiff(cmd *cobra.Command, args []string) error {
-	if repo == nil {
-		return fmt.Errorf("--repo is required")
+	if err := requireTarget(); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
@@ -33,8 +32,12 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	composePath := filepath.Join(repoWorkDir(repo.Name), cfg.Docker.ComposeFile)
-	project, err := docker.LoadProject(ctx, composePath, cfg.Docker.ProjectName)
+	env, err := repoEnvironment(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = docker.RemoveSecrets(cfg.Docker.ProjectName) }()
+	project, err := docker.LoadProject(ctx, composePaths(), cfg.Docker.ProjectName, docker.WithEnvironment(env), docker.WithProfiles(cfg.Docker.Profiles...))
 	if err != nil {
 		return fmt.Errorf("load compose: %w", err)
 	}
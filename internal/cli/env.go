@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/controller"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage repository variables",
+	Long: `Commands for managing variables that are interpolated into a repository's compose files.
+
+Variables belong to the repository given with --repo and take precedence over the .env file. Running controllers redeploy when a variable changes.`,
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+}
+
+func requireRepo() error {
+	if repo == nil {
+		return fmt.Errorf("--repo is required")
+	}
+	return nil
+}
+
+// repoEnvironment returns the target repository's variables for loading
+// its compose files outside a controller.
+func repoEnvironment(ctx context.Context) (map[string]string, error) {
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	env, _, err := controller.Environment(ctx, store, repo.Name, cfg.State.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load environment: %w", err)
+	}
+	return env, nil
+}
+
+func envEventPayload(key string, secret bool) json.RawMessage {
+	payload := map[string]any{"key": key}
+	if secret {
+		payload["secret"] = true
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return data
+}
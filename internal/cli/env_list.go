@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var envListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List repository variables",
+	Long:  `List the variables of the repository given with --repo. Secret values are not shown.`,
+	Args:  cobra.NoArgs,
+	RunE:  runEnvList,
+}
+
+func init() {
+	envCmd.AddCommand(envListCmd)
+}
+
+func runEnvList(cmd *cobra.Command, args []string) error {
+	if err := requireRepo(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	vars, err := store.ListEnv(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	if len(vars) == 0 {
+		fmt.Printf("No variables set for repository %s\n", repo.Name)
+		return nil
+	}
+
+	fmt.Printf("%-24s  %-19s  %s\n", "KEY", "UPDATED", "VALUE")
+	fmt.Println("------------------------  -------------------  -----")
+	for _, v := range vars {
+		value := v.Value
+		if v.Secret {
+			value = "(secret)"
+		}
+		fmt.Printf("%-24s  %-19s  %s\n", v.Key, v.UpdatedAt.Local().Format("2006-01-02 15:04:05"), value)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/secrets"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var envSetFlags struct {
+	secret bool
+	stdin  bool
+}
+
+var envSetCmd = &cobra.Command{
+	Use:   "set KEY=VALUE...",
+	Short: "Set repository variables",
+	Long: `Set one or more variables for the repository given with --repo.
+
+Secret values are stored encrypted with the key in state.key_file and hidden by kedge env list. Use --stdin to read a secret value instead of passing it on the command line.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runEnvSet,
+}
+
+func init() {
+	envSetCmd.Flags().BoolVar(&envSetFlags.secret, "secret", false, "Store the values encrypted and hide them in listings")
+	envSetCmd.Flags().BoolVar(&envSetFlags.stdin, "stdin", false, "Read the value of a single KEY from stdin")
+	envCmd.AddCommand(envSetCmd)
+}
+
+func runEnvSet(cmd *cobra.Command, args []string) error {
+	if err := requireRepo(); err != nil {
+		return err
+	}
+	vars, err := parseEnvArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if envSetFlags.secret {
+		keys, err := secrets.LoadOrCreate(cfg.State.KeyFile)
+		if err != nil {
+			return err
+		}
+		for i := range vars {
+			if vars[i][1], err = keys.Encrypt([]byte(vars[i][1])); err != nil {
+				return err
+			}
+		}
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	for _, v := range vars {
+		if err := store.SetEnv(ctx, repo.Name, v[0], v[1], envSetFlags.secret); err != nil {
+			return fmt.Errorf("set %s: %w", v[0], err)
+		}
+		recordEvent(ctx, store, &state.Event{Type: state.EventEnvSet, RepoName: repo.Name, Payload: envEventPayload(v[0], envSetFlags.secret)})
+		fmt.Printf("Set %s for repository %s\n", v[0], repo.Name)
+	}
+	return nil
+}
+
+// parseEnvArgs returns the key/value pairs to set, reading the value from
+// stdin when --stdin is given.
+func parseEnvArgs(args []string) ([][2]string, error) {
+	if envSetFlags.stdin {
+		if len(args) != 1 || strings.Contains(args[0], "=") {
+			return nil, fmt.Errorf("--stdin takes a single KEY")
+		}
+		value, err := io.ReadAll(io.LimitReader(os.Stdin, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("read stdin: %w", err)
+		}
+		args = []string{args[0] + "=" + strings.TrimRight(string(value), "\r\n")}
+	}
+
+	vars := make([][2]string, 0, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid argument %q: expected KEY=VALUE", arg)
+		}
+		if !state.ValidEnvKey(key) {
+			return nil, fmt.Errorf("invalid variable name %q", key)
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	return vars, nil
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var envUnsetCmd = &cobra.Command{
+	Use:   "unset KEY...",
+	Short: "Remove repository variables",
+	Long:  `Remove one or more variables from the repository given with --repo.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runEnvUnset,
+}
+
+func init() {
+	envCmd.AddCommand(envUnsetCmd)
+}
+
+func runEnvUnset(cmd *cobra.Command, args []string) error {
+	if err := requireRepo(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	for _, key := range args {
+		if err := store.UnsetEnv(ctx, repo.Name, key); err != nil {
+			if errors.Is(err, state.ErrNotFound) {
+				return fmt.Errorf("variable %q is not set for repository %s", key, repo.Name)
+			}
+			return err
+		}
+		recordEvent(ctx, store, &state.Event{Type: state.EventEnvUnset, RepoName: repo.Name, Payload: envEventPayload(key, false)})
+		fmt.Printf("Removed %s from repository %s\n", key, repo.Name)
+	}
+	return nil
+}
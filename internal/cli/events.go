@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/state"
+	"github.com/LoriKarikari/kedge/internal/telemetry"
+)
+
+const followRetry = 3 * time.Second
+
+var errStreamUnauthorized = errors.New("server rejected credentials, pass --token or set KEDGE_TOKEN")
+
+var eventsFlags struct {
+	since  string
+	types  []string
+	limit  int
+	follow bool
+	server string
+	token  string
+}
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the event log",
+	Long: `Display recorded events such as detected commits, drift, syncs, rollbacks and repository changes.
+
+With --follow, stream live controller activity from a running kedge server instead.`,
+	RunE: runEvents,
+}
+
+func init() {
+	eventsCmd.Flags().StringVar(&eventsFlags.since, "since", "", "Only show events newer than a duration (e.g. 1h) or RFC3339 timestamp")
+	eventsCmd.Flags().StringSliceVar(&eventsFlags.types, "type", nil, "Only show events of this type or type prefix (e.g. drift)")
+	eventsCmd.Flags().IntVar(&eventsFlags.limit, "limit", 50, "Maximum number of events to show")
+	eventsCmd.Flags().BoolVarP(&eventsFlags.follow, "follow", "f", false, "Stream live events from a running kedge server")
+	eventsCmd.Flags().StringVar(&eventsFlags.server, "server", "", "Server URL to follow (default: http://localhost:<server.port>)")
+	eventsCmd.Flags().StringVar(&eventsFlags.token, "token", "", "API token for --follow (default: $KEDGE_TOKEN)")
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func runEvents(cmd *cobra.Command, args []string) error {
+	if eventsFlags.follow {
+		return followEvents()
+	}
+
+	since, err := state.ParseSince(eventsFlags.since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	filter := state.EventFilter{
+		Types: eventsFlags.types,
+		Since: since,
+		Limit: eventsFlags.limit,
+	}
+	if repo != nil {
+		filter.RepoName = repo.Name
+		filter.App = appName()
+	}
+
+	events, err := store.ListEvents(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		fmt.Println("No events")
+		return nil
+	}
+
+	fmt.Printf("%-20s  %-20s  %-15s  %-12s  %-8s  %-12s  %s\n", "TIME", "TYPE", "REPO", "SERVICE", "COMMIT", "ACTOR", "DETAILS")
+	fmt.Println("--------------------  --------------------  ---------------  ------------  --------  ------------  -------")
+	for _, e := range events {
+		printEvent(e)
+	}
+
+	return nil
+}
+
+func printEvent(e *state.Event) {
+	fmt.Printf("%-20s  %-20s  %-15s  %-12s  %-8s  %-12s  %s\n",
+		e.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+		e.Type,
+		telemetry.RepoLabel(e.RepoName, e.App),
+		e.Service,
+		lo.Substring(e.Commit, 0, 8),
+		e.Actor,
+		string(e.Payload),
+	)
+}
+
+func followEvents() error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	streamURL, err := eventStreamURL()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-20s  %-24s  %-15s  %-12s  %-8s  %s\n", "TIME", "TYPE", "REPO", "SERVICE", "COMMIT", "DETAILS")
+	fmt.Println("--------------------  ------------------------  ---------------  ------------  --------  -------")
+
+	var lastID uint64
+	for {
+		err := streamEvents(ctx, streamURL, &lastID)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, errStreamUnauthorized) {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "stream interrupted: %v, reconnecting in %s\n", err, followRetry)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(followRetry):
+		}
+	}
+}
+
+func eventStreamURL() (string, error) {
+	base := eventsFlags.server
+	if base == "" {
+		base = fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	u = u.JoinPath("events", "stream")
+
+	query := url.Values{}
+	if repo != nil {
+		query.Set("repo", repo.Name)
+	}
+	if app != nil {
+		query.Set("app", app.Name)
+	}
+	for _, t := range eventsFlags.types {
+		query.Add("type", t)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func streamEvents(ctx context.Context, streamURL string, lastID *uint64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if token := lo.CoalesceOrEmpty(eventsFlags.token, os.Getenv("KEDGE_TOKEN")); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if *lastID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(*lastID, 10))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: status %d", errStreamUnauthorized, resp.StatusCode)
+	default:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				printStreamEvent(data.String(), lastID)
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("server closed the stream")
+}
+
+func printStreamEvent(raw string, lastID *uint64) {
+	var e bus.Event
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid event: %v\n", err)
+		return
+	}
+	*lastID = e.ID
+
+	details := ""
+	if len(e.Data) > 0 {
+		if data, err := json.Marshal(e.Data); err == nil {
+			details = string(data)
+		}
+	}
+	fmt.Printf("%-20s  %-24s  %-15s  %-12s  %-8s  %s\n",
+		e.Time.Local().Format("2006-01-02 15:04:05"),
+		e.Type,
+		telemetry.RepoLabel(e.Repo, e.App),
+		e.Service,
+		lo.Substring(e.Commit, 0, 8),
+		details,
+	)
+}
@@ -2,26 +2,49 @@ package cli
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var healthcheckFlags struct {
-	port int
+	port       int
+	address    string
+	socket     string
+	tls        bool
+	caCert     string
+	cert       string
+	key        string
+	serverName string
+	insecure   bool
 }
 
 var healthcheckCmd = &cobra.Command{
 	Use:   "healthcheck",
 	Short: "Check if kedge server is healthy",
-	Long:  `Check if the kedge server is running and ready. Useful for container HEALTHCHECK.`,
-	RunE:  runHealthcheck,
+	Long: `Check if the kedge server is running and ready. Useful for container HEALTHCHECK.
+
+Connection settings default to the server section of the configuration, so the check follows the server onto TLS or a unix socket.`,
+	RunE: runHealthcheck,
 }
 
 func init() {
 	healthcheckCmd.Flags().IntVar(&healthcheckFlags.port, "port", 8080, "Server port to check")
+	healthcheckCmd.Flags().StringVar(&healthcheckFlags.address, "address", "", "Server address to check (default: server.address or localhost)")
+	healthcheckCmd.Flags().StringVar(&healthcheckFlags.socket, "socket", "", "Unix socket to check instead of a TCP port (default: server.socket)")
+	healthcheckCmd.Flags().BoolVar(&healthcheckFlags.tls, "tls", false, "Connect over TLS (default: enabled when server.tls is configured)")
+	healthcheckCmd.Flags().StringVar(&healthcheckFlags.caCert, "ca-cert", "", "CA certificate used to verify the server")
+	healthcheckCmd.Flags().StringVar(&healthcheckFlags.cert, "cert", "", "Client certificate for mutual TLS")
+	healthcheckCmd.Flags().StringVar(&healthcheckFlags.key, "key", "", "Client key for mutual TLS")
+	healthcheckCmd.Flags().StringVar(&healthcheckFlags.serverName, "server-name", "", "Expected server name in the certificate")
+	healthcheckCmd.Flags().BoolVar(&healthcheckFlags.insecure, "insecure", false, "Skip server certificate verification")
 	rootCmd.AddCommand(healthcheckCmd)
 }
 
@@ -33,13 +56,45 @@ func runHealthcheck(cmd *cobra.Command, args []string) error {
 	if cmd.Flags().Changed("port") {
 		port = healthcheckFlags.port
 	}
+	useTLS := cfg.Server.TLS.Enabled()
+	if cmd.Flags().Changed("tls") {
+		useTLS = healthcheckFlags.tls
+	}
+	socket := cfg.Server.Socket
+	if cmd.Flags().Changed("socket") {
+		socket = healthcheckFlags.socket
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%d/health", port), nil)
+	transport := &http.Transport{}
+	if useTLS {
+		tlsCfg, err := healthcheckTLSConfig()
+		if err != nil {
+			return err
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+
+	host := net.JoinHostPort(healthcheckAddress(), strconv.Itoa(port))
+	if socket != "" {
+		host = "localhost"
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/health", scheme, host), nil)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	client := &http.Client{Transport: transport}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("health check failed: %w", err)
 	}
@@ -52,3 +107,45 @@ func runHealthcheck(cmd *cobra.Command, args []string) error {
 	fmt.Println("healthy")
 	return nil
 }
+
+func healthcheckAddress() string {
+	if healthcheckFlags.address != "" {
+		return healthcheckFlags.address
+	}
+	switch cfg.Server.Address {
+	case "", "0.0.0.0", "::":
+		return "localhost"
+	default:
+		return cfg.Server.Address
+	}
+}
+
+func healthcheckTLSConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         healthcheckFlags.serverName,
+		InsecureSkipVerify: healthcheckFlags.insecure, //nolint:gosec // opt-in for self-signed certificates
+	}
+
+	if healthcheckFlags.caCert != "" {
+		pem, err := os.ReadFile(healthcheckFlags.caCert)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", healthcheckFlags.caCert)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if healthcheckFlags.cert != "" || healthcheckFlags.key != "" {
+		cert, err := tls.LoadX509KeyPair(healthcheckFlags.cert, healthcheckFlags.key)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
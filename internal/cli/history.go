@@ -26,8 +26,8 @@ func init() {
 }
 
 func runHistory(cmd *cobra.Command, args []string) error {
-	if repo == nil {
-		return fmt.Errorf("--repo is required")
+	if err := requireTarget(); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
@@ -38,31 +38,35 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	}
 	defer store.Close()
 
-	deployments, err := store.ListDeployments(ctx, repo.Name, historyFlags.limit)
+	deployments, err := store.ListDeployments(ctx, repo.Name, appName(), historyFlags.limit)
 	if err != nil {
 		return err
 	}
 
+	printDeployments(deployments)
+	return nil
+}
+
+func printDeployments(deployments []*state.Deployment) {
 	if len(deployments) == 0 {
 		fmt.Println("No deployments yet")
-		return nil
+		return
 	}
 
-	fmt.Printf("%-8s  %-10s  %-20s  %s\n", "COMMIT", "STATUS", "TIME", "MESSAGE")
-	fmt.Println("--------  ----------  --------------------  -------")
+	fmt.Printf("%-6s  %-8s  %-17s  %-20s  %s\n", "ID", "COMMIT", "STATUS", "TIME", "MESSAGE")
+	fmt.Println("------  --------  -----------------  --------------------  -------")
 
 	for _, d := range deployments {
 		msg := d.Message
 		if len(msg) > 40 {
 			msg = msg[:37] + "..."
 		}
-		fmt.Printf("%-8s  %-10s  %-20s  %s\n",
+		fmt.Printf("%-6d  %-8s  %-17s  %-20s  %s\n",
+			d.ID,
 			lo.Substring(d.CommitHash, 0, 8),
 			d.Status,
 			d.DeployedAt.Format("2006-01-02 15:04:05"),
 			msg,
 		)
 	}
-
-	return nil
 }
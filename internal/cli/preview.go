@@ -0,0 +1,17 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Inspect preview environments",
+	Long: `Commands for inspecting the preview environments of the repository given with --repo.
+
+A repository added with --preview deploys every branch matching the pattern as its own compose project. kedge serve creates previews when branches appear and removes them when the branch is deleted or goes stale.`,
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+}
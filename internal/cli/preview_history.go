@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var previewHistoryFlags struct {
+	limit int
+}
+
+var previewHistoryCmd = &cobra.Command{
+	Use:   "history <branch>",
+	Short: "Show the deployment history of a preview",
+	Long:  `Display the past deployments of the preview environment for a branch.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPreviewHistory,
+}
+
+func init() {
+	previewHistoryCmd.Flags().IntVar(&previewHistoryFlags.limit, "limit", 10, "Maximum number of entries to show")
+	previewCmd.AddCommand(previewHistoryCmd)
+}
+
+func runPreviewHistory(cmd *cobra.Command, args []string) error {
+	if err := requireRepo(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	deployments, err := store.ListDeployments(ctx, repo.Name, state.PreviewApp(args[0]), previewHistoryFlags.limit)
+	if err != nil {
+		return err
+	}
+	printDeployments(deployments)
+	return nil
+}
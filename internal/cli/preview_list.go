@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var previewListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List preview environments",
+	Long:  `List the preview environments of the repository given with --repo.`,
+	Args:  cobra.NoArgs,
+	RunE:  runPreviewList,
+}
+
+func init() {
+	previewCmd.AddCommand(previewListCmd)
+}
+
+func runPreviewList(cmd *cobra.Command, args []string) error {
+	if err := requireRepo(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	previews, err := store.ListPreviews(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	if len(previews) == 0 {
+		fmt.Printf("No previews for repository %s\n", repo.Name)
+		return nil
+	}
+
+	fmt.Printf("%-32s  %-8s  %-8s  %-7s  %-19s  %s\n", "BRANCH", "COMMIT", "STATUS", "PORTS", "UPDATED", "PROJECT")
+	fmt.Println("--------------------------------  --------  --------  -------  -------------------  -------")
+	for _, p := range previews {
+		ports := "none"
+		if repo.PreviewPortOffset > 0 {
+			ports = fmt.Sprintf("+%d", p.Slot*repo.PreviewPortOffset)
+		}
+		fmt.Printf("%-32s  %-8s  %-8s  %-7s  %-19s  %s\n",
+			p.Branch,
+			lo.Substring(p.Commit, 0, 8),
+			lo.Ternary(p.Retired(), "retired", "active"),
+			ports,
+			p.UpdatedAt.Local().Format("2006-01-02 15:04:05"),
+			p.ProjectName,
+		)
+	}
+	return nil
+}
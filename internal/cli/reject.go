@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var rejectCmd = &cobra.Command{
+	Use:   "reject <id>",
+	Short: "Reject a deployment awaiting approval",
+	Long:  `Discard a deployment that is waiting for approval and record who rejected it.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runReject,
+}
+
+func init() {
+	rejectCmd.Flags().StringVar(&reviewFlags.as, "as", "", "Identity recorded as the reviewer (defaults to the current user)")
+	rootCmd.AddCommand(rejectCmd)
+}
+
+func runReject(cmd *cobra.Command, args []string) error {
+	id, err := parseDeploymentID(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	ctrl, err := newStandaloneController(ctx)
+	if err != nil {
+		return err
+	}
+	defer ctrl.Close()
+
+	deployment, err := ctrl.Reject(ctx, id, reviewerIdentity())
+	if err != nil {
+		return fmt.Errorf("reject deployment %d: %w", id, err)
+	}
+
+	fmt.Printf("Rejected deployment %d (commit %s)\n", deployment.ID, lo.Substring(deployment.CommitHash, 0, 8))
+	return nil
+}
@@ -1,17 +1,27 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/huh"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
 
+	"github.com/LoriKarikari/kedge/internal/git"
 	"github.com/LoriKarikari/kedge/internal/git/auth"
+	"github.com/LoriKarikari/kedge/internal/secrets"
 	"github.com/LoriKarikari/kedge/internal/state"
 )
 
@@ -21,6 +31,35 @@ var repoAddFlags struct {
 	sshKeyPath  string
 	username    string
 	passwordEnv string
+	tagPattern  string
+	semver      string
+	trustKeys   string
+	signedTags  bool
+	submodules  bool
+	depth       int
+	sparse      []string
+
+	preview           string
+	previewTTL        time.Duration
+	previewPortOffset int
+
+	sshUser        string
+	sshAgent       bool
+	passphraseEnv  string
+	passphraseFile string
+	knownHosts     string
+	hostKeys       []string
+	acceptHostKey  bool
+
+	tokenFile        string
+	appID            int64
+	installationID   int64
+	appKey           string
+	gitHubAPIURL     string
+	credentialHelper string
+
+	tokenStdin  bool
+	sshKeyStdin bool
 }
 
 var repoAddCmd = &cobra.Command{
@@ -37,6 +76,38 @@ func init() {
 	repoAddCmd.Flags().StringVar(&repoAddFlags.sshKeyPath, "ssh-private-key-path", "", "Path to SSH private key for authentication")
 	repoAddCmd.Flags().StringVar(&repoAddFlags.username, "username", "", "Username for HTTPS authentication (defaults to x-access-token)")
 	repoAddCmd.Flags().StringVar(&repoAddFlags.passwordEnv, "password-env", "", "Environment variable name containing the password/token")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.sshUser, "ssh-user", "", "SSH user (defaults to the user in the URL, or git)")
+	repoAddCmd.Flags().BoolVar(&repoAddFlags.sshAgent, "ssh-agent", false, "Authenticate with the keys held by ssh-agent")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.passphraseEnv, "ssh-passphrase-env", "", "Environment variable name containing the SSH key passphrase")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.passphraseFile, "ssh-passphrase-file", "", "File containing the SSH key passphrase")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.knownHosts, "known-hosts", "", "known_hosts file to verify the server's host key against")
+	repoAddCmd.Flags().StringArrayVar(&repoAddFlags.hostKeys, "host-key", nil, "Pin this server host key, e.g. 'ssh-ed25519 AAAA...' (repeatable)")
+	repoAddCmd.Flags().BoolVar(&repoAddFlags.acceptHostKey, "accept-host-key", false, "Pin the server's current host key without asking")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.tokenFile, "token-file", "", "File containing the HTTPS token; re-read whenever it changes")
+	repoAddCmd.Flags().Int64Var(&repoAddFlags.appID, "github-app-id", 0, "GitHub App ID to authenticate as")
+	repoAddCmd.Flags().Int64Var(&repoAddFlags.installationID, "github-installation-id", 0, "GitHub App installation ID")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.appKey, "github-app-key", "", "Path to the GitHub App private key (PEM)")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.gitHubAPIURL, "github-api-url", "", "GitHub API URL for GitHub Enterprise Server (defaults to https://api.github.com)")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.credentialHelper, "credential-helper", "", "Git credential helper to ask for the HTTPS password (e.g. 'store' or '!my-script')")
+	repoAddCmd.Flags().BoolVar(&repoAddFlags.tokenStdin, "token-stdin", false, "Read the HTTPS token from stdin and store it encrypted")
+	repoAddCmd.Flags().BoolVar(&repoAddFlags.sshKeyStdin, "ssh-key-stdin", false, "Read the SSH private key from stdin and store it encrypted")
+	repoAddCmd.MarkFlagsMutuallyExclusive("ssh-private-key-path", "ssh-agent", "ssh-key-stdin", "token-stdin")
+	repoAddCmd.MarkFlagsMutuallyExclusive("password-env", "token-file", "github-app-id", "credential-helper", "token-stdin")
+	repoAddCmd.MarkFlagsRequiredTogether("github-app-id", "github-installation-id", "github-app-key")
+	repoAddCmd.MarkFlagsMutuallyExclusive("ssh-passphrase-env", "ssh-passphrase-file")
+	repoAddCmd.MarkFlagsMutuallyExclusive("known-hosts", "host-key", "accept-host-key")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.tagPattern, "tag-pattern", "", "Track the highest semver tag matching this glob instead of a branch (e.g. 'v*')")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.semver, "semver", "", "Semver constraint for tracked tags (e.g. '>=1.2.0, <2.0.0')")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.trustKeys, "trust-keys", "", "File of trusted PGP/SSH public keys; only commits signed by one of them are deployed")
+	repoAddCmd.Flags().BoolVar(&repoAddFlags.signedTags, "require-signed-tags", false, "Verify the signature of the tracked tag instead of the commit (requires --trust-keys and tag tracking)")
+	repoAddCmd.Flags().BoolVar(&repoAddFlags.submodules, "submodules", false, "Initialize and update submodules recursively on clone and pull")
+	repoAddCmd.Flags().IntVar(&repoAddFlags.depth, "depth", 0, "Shallow clone with this many commits of history (0 for full history)")
+	repoAddCmd.Flags().StringSliceVar(&repoAddFlags.sparse, "sparse", nil, "Only check out these directories (repeatable)")
+	repoAddCmd.Flags().StringVar(&repoAddFlags.preview, "preview", "", "Deploy every branch matching this pattern as its own preview environment (e.g. 'preview/*')")
+	repoAddCmd.Flags().DurationVar(&repoAddFlags.previewTTL, "preview-ttl", 7*24*time.Hour, "Remove previews whose branch has not changed for this long (0 keeps them until the branch is deleted)")
+	repoAddCmd.Flags().IntVar(&repoAddFlags.previewPortOffset, "preview-port-offset", 0, "Shift published ports by this much per preview (0 publishes no ports)")
+	repoAddCmd.MarkFlagsMutuallyExclusive("branch", "tag-pattern")
+	repoAddCmd.MarkFlagsMutuallyExclusive("branch", "semver")
 	repoCmd.AddCommand(repoAddCmd)
 }
 
@@ -69,6 +140,30 @@ func runRepoAdd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	tags, err := buildTagConfig()
+	if err != nil {
+		return err
+	}
+	branch := repoAddFlags.branch
+	if tags != nil {
+		branch = ""
+	}
+
+	trust, err := buildTrustConfig(tags != nil)
+	if err != nil {
+		return err
+	}
+
+	checkout, err := buildCheckoutConfig()
+	if err != nil {
+		return err
+	}
+
+	preview, err := buildPreviewConfig()
+	if err != nil {
+		return err
+	}
+
 	ctx := context.Background()
 	store, err := state.New(ctx, cfg.State.Path)
 	if err != nil {
@@ -76,19 +171,199 @@ func runRepoAdd(cmd *cobra.Command, args []string) error {
 	}
 	defer store.Close()
 
-	repo, err := store.SaveRepo(ctx, name, repoURL, repoAddFlags.branch, repoAuth)
+	repo, err := store.SaveRepo(ctx, state.RepoSpec{
+		Name:     name,
+		URL:      repoURL,
+		Branch:   branch,
+		Auth:     repoAuth,
+		Tags:     tags,
+		Trust:    trust,
+		Checkout: checkout,
+		Preview:  preview,
+	})
 	if err != nil {
 		return fmt.Errorf("save repo: %w", err)
 	}
+	recordEvent(ctx, store, &state.Event{
+		Type:     state.EventRepoAdded,
+		RepoName: repo.Name,
+		Payload:  repoEventPayload(repo),
+	})
 
 	fmt.Printf("Added repository %q (%s)\n", repo.Name, repo.URL)
 	if repoAuth != nil {
 		fmt.Printf("  Auth: %s\n", repoAuth.Type)
 	}
+	if tags != nil {
+		fmt.Printf("  Tracking: %s\n", repoRef(repo))
+	}
+	if checkout != nil {
+		fmt.Printf("  Checkout: %s\n", repoCheckout(repo))
+	}
+	if trust != nil {
+		fmt.Printf("  Trust: %s%s\n", trust.KeysPath, lo.Ternary(trust.SignedTags, " (signed tags)", ""))
+	}
+	if preview != nil {
+		fmt.Printf("  Previews: %s\n", repoPreview(repo))
+	}
 	return nil
 }
 
+func buildTagConfig() (*state.RepoTags, error) {
+	if repoAddFlags.tagPattern == "" && repoAddFlags.semver == "" {
+		return nil, nil
+	}
+	tags := &state.RepoTags{Pattern: lo.CoalesceOrEmpty(repoAddFlags.tagPattern, "*"), Constraint: repoAddFlags.semver}
+	if err := git.ValidTagPattern(tags.Pattern); err != nil {
+		return nil, fmt.Errorf("invalid --tag-pattern %q: %w", tags.Pattern, err)
+	}
+	if tags.Constraint != "" {
+		if _, err := git.ParseConstraint(tags.Constraint); err != nil {
+			return nil, fmt.Errorf("invalid --semver: %w", err)
+		}
+	}
+	return tags, nil
+}
+
+func buildTrustConfig(tagged bool) (*state.RepoTrust, error) {
+	if repoAddFlags.trustKeys == "" {
+		if repoAddFlags.signedTags {
+			return nil, fmt.Errorf("--require-signed-tags requires --trust-keys")
+		}
+		return nil, nil
+	}
+	if repoAddFlags.signedTags && !tagged {
+		return nil, fmt.Errorf("--require-signed-tags requires --tag-pattern or --semver")
+	}
+	keysPath, err := filepath.Abs(expandPath(repoAddFlags.trustKeys))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := git.LoadKeyring(keysPath); err != nil {
+		return nil, fmt.Errorf("invalid --trust-keys: %w", err)
+	}
+	return &state.RepoTrust{KeysPath: keysPath, SignedTags: repoAddFlags.signedTags}, nil
+}
+
+func buildCheckoutConfig() (*state.RepoCheckout, error) {
+	if repoAddFlags.depth < 0 {
+		return nil, fmt.Errorf("--depth must not be negative")
+	}
+	for _, dir := range repoAddFlags.sparse {
+		if !filepath.IsLocal(dir) {
+			return nil, fmt.Errorf("invalid --sparse %q: must be a relative path inside the repository", dir)
+		}
+	}
+	if !repoAddFlags.submodules && repoAddFlags.depth == 0 && len(repoAddFlags.sparse) == 0 {
+		return nil, nil
+	}
+	return &state.RepoCheckout{
+		Submodules:  repoAddFlags.submodules,
+		Depth:       repoAddFlags.depth,
+		SparsePaths: lo.Map(repoAddFlags.sparse, func(dir string, _ int) string { return filepath.ToSlash(filepath.Clean(dir)) }),
+	}, nil
+}
+
+func buildPreviewConfig() (*state.RepoPreview, error) {
+	if repoAddFlags.preview == "" {
+		return nil, nil
+	}
+	if err := git.ValidatePattern(repoAddFlags.preview); err != nil {
+		return nil, fmt.Errorf("invalid --preview %q: %w", repoAddFlags.preview, err)
+	}
+	if repoAddFlags.previewTTL < 0 {
+		return nil, fmt.Errorf("--preview-ttl must not be negative")
+	}
+	if repoAddFlags.previewPortOffset < 0 {
+		return nil, fmt.Errorf("--preview-port-offset must not be negative")
+	}
+	return &state.RepoPreview{
+		Pattern:    repoAddFlags.preview,
+		TTL:        repoAddFlags.previewTTL.Truncate(time.Second),
+		PortOffset: repoAddFlags.previewPortOffset,
+	}, nil
+}
+
 func buildAuthConfig(repoURL string) (*state.RepoAuth, error) {
+	if repoAddFlags.tokenStdin || repoAddFlags.sshKeyStdin {
+		return buildStoredCredentials(repoURL)
+	}
+
+	repoAuth, err := buildCredentials(repoURL)
+	if err != nil || repoAuth == nil || repoAuth.Type == string(auth.TypeToken) {
+		return repoAuth, err
+	}
+	if isHTTPSource(repoAuth.Type) {
+		if err := validateSource(repoURL, repoAuth); err != nil {
+			return nil, err
+		}
+		return repoAuth, nil
+	}
+	return configureSSH(repoURL, repoAuth, nil)
+}
+
+// buildStoredCredentials reads a token or SSH key from stdin and encrypts it
+// with the key file, so kedge serve needs no environment for it.
+func buildStoredCredentials(repoURL string) (*state.RepoAuth, error) {
+	secret, err := io.ReadAll(io.LimitReader(os.Stdin, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read stdin: %w", err)
+	}
+
+	repoAuth := &state.RepoAuth{Type: string(auth.TypeSSHKey)}
+	if repoAddFlags.tokenStdin {
+		secret = bytes.TrimSpace(secret)
+		repoAuth = &state.RepoAuth{Type: string(auth.TypeToken), Username: repoAddFlags.username}
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("no credential on stdin")
+	}
+
+	if repoAddFlags.sshKeyStdin {
+		if repoAuth, err = configureSSH(repoURL, repoAuth, secret); err != nil {
+			return nil, err
+		}
+	}
+
+	keys, err := secrets.LoadOrCreate(cfg.State.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if repoAuth.Secret, err = keys.Encrypt(secret); err != nil {
+		return nil, err
+	}
+	return repoAuth, nil
+}
+
+func configureSSH(repoURL string, repoAuth *state.RepoAuth, sshKey []byte) (*state.RepoAuth, error) {
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	repoAuth.Username = lo.CoalesceOrEmpty(repoAddFlags.sshUser, ep.User)
+	repoAuth.PassphraseEnv = repoAddFlags.passphraseEnv
+	if repoAddFlags.passphraseFile != "" {
+		if repoAuth.PassphraseFile, err = filepath.Abs(expandPath(repoAddFlags.passphraseFile)); err != nil {
+			return nil, err
+		}
+	}
+	if repoAuth.Type == string(auth.TypeSSHKey) {
+		authCfg := repoAuthConfig(repoURL, repoAuth)
+		authCfg.SSHKey = sshKey
+		if _, err := authCfg.Resolve(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if ep.Protocol == "ssh" {
+		if err := configureHostKeys(repoURL, repoAuth); err != nil {
+			return nil, err
+		}
+	}
+	return repoAuth, nil
+}
+
+func buildCredentials(repoURL string) (*state.RepoAuth, error) {
 	if repoAddFlags.sshKeyPath != "" {
 		if _, err := os.Stat(repoAddFlags.sshKeyPath); err != nil {
 			return nil, fmt.Errorf("SSH key not found: %s", repoAddFlags.sshKeyPath)
@@ -99,6 +374,14 @@ func buildAuthConfig(repoURL string) (*state.RepoAuth, error) {
 		}, nil
 	}
 
+	if repoAddFlags.sshAgent {
+		return &state.RepoAuth{Type: string(auth.TypeSSHAgent)}, nil
+	}
+
+	if source, err := buildCredentialSource(); source != nil || err != nil {
+		return source, err
+	}
+
 	if repoAddFlags.passwordEnv != "" {
 		return &state.RepoAuth{
 			Type:        string(auth.TypeToken),
@@ -111,7 +394,7 @@ func buildAuthConfig(repoURL string) (*state.RepoAuth, error) {
 		var sshKeyPath string
 		err := huh.NewInput().
 			Title("SSH private key path").
-			Description("Path to the SSH private key for authentication").
+			Description("Path to the SSH private key for authentication, or empty to use ssh-agent").
 			Placeholder("~/.ssh/id_ed25519").
 			Value(&sshKeyPath).
 			Validate(func(s string) error {
@@ -134,11 +417,134 @@ func buildAuthConfig(repoURL string) (*state.RepoAuth, error) {
 				SSHKeyPath: expandPath(sshKeyPath),
 			}, nil
 		}
+		return &state.RepoAuth{Type: string(auth.TypeSSHAgent)}, nil
 	}
 
 	return nil, nil
 }
 
+func buildCredentialSource() (*state.RepoAuth, error) {
+	switch {
+	case repoAddFlags.tokenFile != "":
+		path, err := filepath.Abs(expandPath(repoAddFlags.tokenFile))
+		if err != nil {
+			return nil, err
+		}
+		return &state.RepoAuth{Type: string(auth.TypeTokenFile), Username: repoAddFlags.username, TokenFile: path}, nil
+
+	case repoAddFlags.appID != 0:
+		keyPath, err := filepath.Abs(expandPath(repoAddFlags.appKey))
+		if err != nil {
+			return nil, err
+		}
+		return &state.RepoAuth{
+			Type:           string(auth.TypeGitHubApp),
+			AppID:          repoAddFlags.appID,
+			InstallationID: repoAddFlags.installationID,
+			AppKeyPath:     keyPath,
+			GitHubAPIURL:   repoAddFlags.gitHubAPIURL,
+		}, nil
+
+	case repoAddFlags.credentialHelper != "":
+		return &state.RepoAuth{Type: string(auth.TypeCredentialHelper), Username: repoAddFlags.username, CredentialHelper: repoAddFlags.credentialHelper}, nil
+	}
+	return nil, nil
+}
+
+func isHTTPSource(authType string) bool {
+	switch auth.Type(authType) {
+	case auth.TypeTokenFile, auth.TypeGitHubApp, auth.TypeCredentialHelper:
+		return true
+	}
+	return false
+}
+
+// validateSource checks what can be checked offline: the token file is read,
+// the App key is parsed, and the helper is left for the first fetch.
+func validateSource(repoURL string, repoAuth *state.RepoAuth) error {
+	source, err := repoAuthConfig(repoURL, repoAuth).NewSource(nil)
+	if err != nil {
+		return err
+	}
+	if repoAuth.Type == string(auth.TypeTokenFile) {
+		if _, err := source.AuthMethod(context.Background()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func configureHostKeys(repoURL string, repoAuth *state.RepoAuth) error {
+	switch {
+	case repoAddFlags.knownHosts != "":
+		path, err := filepath.Abs(expandPath(repoAddFlags.knownHosts))
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("known_hosts file not found: %s", path)
+		}
+		repoAuth.KnownHostsPath = path
+		return nil
+
+	case len(repoAddFlags.hostKeys) > 0:
+		for _, line := range repoAddFlags.hostKeys {
+			key, err := auth.ParseHostKey(line)
+			if err != nil {
+				return fmt.Errorf("invalid --host-key: %w", err)
+			}
+			repoAuth.HostKeys = append(repoAuth.HostKeys, auth.FormatHostKey(key))
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	key, err := auth.ScanHostKey(ctx, repoURL)
+	if err != nil {
+		return fmt.Errorf("%w (use --host-key or --known-hosts to configure it manually)", err)
+	}
+
+	fmt.Printf("Host key: %s %s\n", key.Type(), ssh.FingerprintSHA256(key))
+	accepted := repoAddFlags.acceptHostKey
+	if !accepted {
+		err := huh.NewConfirm().
+			Title("Trust this host key?").
+			Description("Compare the fingerprint with the one published by your Git host.").
+			Value(&accepted).
+			Run()
+		if err != nil {
+			return err
+		}
+	}
+	if !accepted {
+		return fmt.Errorf("host key not accepted")
+	}
+	repoAuth.HostKeys = []string{auth.FormatHostKey(key)}
+	return nil
+}
+
+func repoAuthConfig(repoURL string, r *state.RepoAuth) *auth.Config {
+	return &auth.Config{
+		Type:           auth.Type(r.Type),
+		URL:            repoURL,
+		SSHKeyPath:     r.SSHKeyPath,
+		Username:       r.Username,
+		PasswordEnv:    r.PasswordEnv,
+		PassphraseEnv:  r.PassphraseEnv,
+		PassphraseFile: r.PassphraseFile,
+		KnownHostsPath: r.KnownHostsPath,
+		HostKeys:       r.HostKeys,
+
+		TokenFile:        r.TokenFile,
+		AppID:            r.AppID,
+		InstallationID:   r.InstallationID,
+		AppKeyPath:       r.AppKeyPath,
+		GitHubAPIURL:     r.GitHubAPIURL,
+		CredentialHelper: r.CredentialHelper,
+	}
+}
+
 func isSSHURL(repoURL string) bool {
 	return strings.HasPrefix(repoURL, "git@") ||
 		strings.HasPrefix(repoURL, "ssh://")
@@ -164,3 +570,51 @@ func repoNameFromURL(repoURL string) string {
 	name = strings.TrimSuffix(name, ".git")
 	return name
 }
+
+func repoEventPayload(r *state.Repo) json.RawMessage {
+	payload := map[string]string{"url": r.URL, "branch": r.Branch, "auth": r.AuthType}
+	if r.TagPattern != "" {
+		payload = map[string]string{"url": r.URL, "tag_pattern": r.TagPattern, "semver": r.TagConstraint, "auth": r.AuthType}
+	}
+	if r.TrustKeysPath != "" {
+		payload["trust_keys"] = r.TrustKeysPath
+	}
+	if checkout := repoCheckout(r); checkout != "" {
+		payload["checkout"] = checkout
+	}
+	if r.PreviewPattern != "" {
+		payload["preview"] = repoPreview(r)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func repoCheckout(r *state.Repo) string {
+	var parts []string
+	if r.Submodules {
+		parts = append(parts, "submodules")
+	}
+	if r.Depth > 0 {
+		parts = append(parts, fmt.Sprintf("depth %d", r.Depth))
+	}
+	if len(r.SparsePaths) > 0 {
+		parts = append(parts, "sparse "+strings.Join(r.SparsePaths, ","))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func repoPreview(r *state.Repo) string {
+	parts := []string{r.PreviewPattern}
+	if r.PreviewTTL > 0 {
+		parts = append(parts, "ttl "+r.PreviewTTL.String())
+	}
+	if r.PreviewPortOffset > 0 {
+		parts = append(parts, fmt.Sprintf("port offset %d", r.PreviewPortOffset))
+	} else {
+		parts = append(parts, "no published ports")
+	}
+	return strings.Join(parts, ", ")
+}
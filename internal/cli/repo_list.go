@@ -37,11 +37,22 @@ func runRepoList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Printf("%-20s  %-10s  %s\n", "NAME", "BRANCH", "URL")
-	fmt.Println("--------------------  ----------  ---")
+	fmt.Printf("%-20s  %-24s  %s\n", "NAME", "REF", "URL")
+	fmt.Println("--------------------  ------------------------  ---")
 	for _, r := range repos {
-		fmt.Printf("%-20s  %-10s  %s\n", r.Name, r.Branch, r.URL)
+		fmt.Printf("%-20s  %-24s  %s\n", r.Name, repoRef(r), r.URL)
 	}
 
 	return nil
 }
+
+func repoRef(r *state.Repo) string {
+	switch {
+	case r.TagPattern == "":
+		return r.Branch
+	case r.TagConstraint == "":
+		return "tags " + r.TagPattern
+	default:
+		return fmt.Sprintf("tags %s (%s)", r.TagPattern, r.TagConstraint)
+	}
+}
@@ -36,6 +36,7 @@ func runRepoRemove(cmd *cobra.Command, args []string) error {
 		}
 		return err
 	}
+	recordEvent(ctx, store, &state.Event{Type: state.EventRepoRemoved, RepoName: name})
 
 	fmt.Printf("Removed repository %q\n", name)
 	return nil
@@ -8,7 +8,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/LoriKarikari/kedge/internal/controller"
 	"github.com/LoriKarikari/kedge/internal/docker"
+	"github.com/LoriKarikari/kedge/internal/notify"
 	"github.com/LoriKarikari/kedge/internal/state"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
@@ -27,8 +29,8 @@ func init() {
 }
 
 func runRollback(cmd *cobra.Command, args []string) error {
-	if repo == nil {
-		return fmt.Errorf("--repo is required")
+	if err := requireTarget(); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
@@ -40,10 +42,13 @@ func runRollback(cmd *cobra.Command, args []string) error {
 	}
 	defer store.Close()
 
-	deployment, err := findDeployment(ctx, store, repo.Name, commitPrefix)
+	deployment, err := findDeployment(ctx, store, repo.Name, appName(), commitPrefix)
 	if err != nil {
 		return err
 	}
+	if deployment.Status == state.StatusRejected {
+		return fmt.Errorf("commit %s was rejected: %s", lo.Substring(deployment.CommitHash, 0, 8), deployment.Message)
+	}
 
 	tmpDir, err := os.MkdirTemp("", "kedge-rollback-*")
 	if err != nil {
@@ -56,7 +61,12 @@ func runRollback(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("write compose file: %w", err)
 	}
 
-	project, err := docker.LoadProject(ctx, composePath, cfg.Docker.ProjectName)
+	env, _, err := controller.Environment(ctx, store, repo.Name, cfg.State.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load environment: %w", err)
+	}
+	defer func() { _ = docker.RemoveSecrets(cfg.Docker.ProjectName) }()
+	project, err := docker.LoadProject(ctx, []string{composePath}, cfg.Docker.ProjectName, docker.WithEnvironment(env), docker.WithProfiles(cfg.Docker.Profiles...))
 	if err != nil {
 		return fmt.Errorf("load compose: %w", err)
 	}
@@ -73,17 +83,25 @@ func runRollback(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("deploy: %w", err)
 	}
 
-	_, err = store.SaveDeployment(ctx, repo.Name, deployment.CommitHash, deployment.ComposeContent, state.StatusRolledBack, "rollback")
+	_, err = store.SaveDeployment(ctx, repo.Name, appName(), deployment.CommitHash, deployment.ComposeContent, state.StatusRolledBack, "rollback")
 	if err != nil {
 		logger.Warn("failed to record rollback", slog.Any("error", err))
 	}
+	recordEvent(ctx, store, &state.Event{Type: state.EventRollback, RepoName: repo.Name, App: appName(), Commit: deployment.CommitHash})
+
+	notifier, err := notify.New(cfg.Notifications, logger)
+	if err != nil {
+		logger.Warn("failed to configure notifications", slog.Any("error", err))
+	}
+	notifier.Notify(ctx, notify.Event{Type: notify.EventRollback, Repo: repo.Name, App: appName(), Commit: deployment.CommitHash})
+	notifier.Close()
 
 	fmt.Println("Rollback completed successfully")
 	return nil
 }
 
-func findDeployment(ctx context.Context, store *state.Store, repoName, prefix string) (*state.Deployment, error) {
-	deployment, err := store.GetDeploymentByCommit(ctx, repoName, prefix)
+func findDeployment(ctx context.Context, store *state.Store, repoName, app, prefix string) (*state.Deployment, error) {
+	deployment, err := store.GetDeploymentByCommit(ctx, repoName, app, prefix)
 	if err == nil {
 		return deployment, nil
 	}
@@ -91,7 +109,7 @@ func findDeployment(ctx context.Context, store *state.Store, repoName, prefix st
 		return nil, err
 	}
 
-	deployments, err := store.ListDeployments(ctx, repoName, 100)
+	deployments, err := store.ListDeployments(ctx, repoName, app, 100)
 	if err != nil {
 		return nil, err
 	}
@@ -5,20 +5,31 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strings"
 
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/LoriKarikari/kedge/internal/config"
 	"github.com/LoriKarikari/kedge/internal/logging"
 	"github.com/LoriKarikari/kedge/internal/state"
 )
 
+const configKeyAnnotation = "kedge_config_key"
+
 var (
-	cfg      *config.Config
-	logger   *slog.Logger
-	repoFlag string
-	repo     *state.Repo
+	cfg        *config.Config
+	cfgLoader  *config.Loader
+	logger     *slog.Logger
+	repoFlag   string
+	appFlag    string
+	configFlag string
+	repo       *state.Repo
+	app        *config.App
+	repoApps   []config.App
 )
 
 var rootCmd = &cobra.Command{
@@ -26,15 +37,25 @@ var rootCmd = &cobra.Command{
 	Short: "GitOps controller for Docker Compose",
 	Long:  `Kedge watches a Git repository and automatically deploys Docker Compose applications when changes are detected.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if cmd.Name() == "version" {
+		if cmd.Name() == "version" || cmd.Name() == "schema" {
 			return nil
 		}
 
-		cfg = config.Default()
+		if appFlag != "" && repoFlag == "" {
+			return fmt.Errorf("--app requires --repo")
+		}
+
+		loader, err := loadConfig(cmd, "", "")
+		if err != nil {
+			return err
+		}
+		if err := config.ExportEnvironment(loader.Config().Environment); err != nil {
+			return fmt.Errorf("set environment: %w", err)
+		}
 
 		if repoFlag != "" {
 			ctx := context.Background()
-			store, err := state.New(ctx, cfg.State.Path)
+			store, err := state.New(ctx, loader.Config().State.Path)
 			if err != nil {
 				return fmt.Errorf("open state: %w", err)
 			}
@@ -48,12 +69,37 @@ var rootCmd = &cobra.Command{
 				return err
 			}
 
-			cfg, err = loadRepoConfig(repo.Name)
+			workDir := repoWorkDir(repo.Name)
+			repoConfig, err := config.FindRepo(workDir)
 			if err != nil {
 				return fmt.Errorf("load repo config: %w", err)
 			}
+			repoApps, err = config.LoadApps(workDir)
+			if err != nil {
+				return fmt.Errorf("load apps: %w", err)
+			}
+
+			var appConfig string
+			if appFlag != "" {
+				found, err := config.FindApp(repoApps, appFlag)
+				if err != nil {
+					return fmt.Errorf("%w in repository %q", err, repo.Name)
+				}
+				app = &found
+				if appConfig, err = config.FindRepo(filepath.Join(workDir, app.Path)); err != nil {
+					return fmt.Errorf("load app config: %w", err)
+				}
+			}
+
+			loader, err = loadConfig(cmd, repoConfig, appConfig)
+			if err != nil {
+				return err
+			}
 		}
 
+		cfgLoader = loader
+		cfg = loader.Config()
+
 		logger = logging.New(logging.Config{
 			Level:  cfg.Logging.Level,
 			Format: cfg.Logging.Format,
@@ -70,21 +116,137 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&repoFlag, "repo", "", "Repository name to operate on")
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&repoFlag, "repo", "", "Repository name to operate on")
+	flags.StringVar(&appFlag, "app", "", "App within the repository to operate on")
+	flags.StringVar(&configFlag, "config", "", "Global config file (default: $KEDGE_CONFIG or $XDG_CONFIG_HOME/kedge/config.yaml)")
+	flags.String("environment", "", "Environment name exposed as $KEDGE_ENV to select compose overlays")
+	flags.String("state-path", "", "SQLite state database path")
+	flags.String("log-level", "", "Log level: debug, info, warn, error")
+	flags.String("log-format", "", "Log format: text or json")
+	bindConfigFlag(flags, "environment", "environment")
+	bindConfigFlag(flags, "state-path", "state.path")
+	bindConfigFlag(flags, "log-level", "logging.level")
+	bindConfigFlag(flags, "log-format", "logging.format")
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 }
 
+func bindConfigFlag(flags *pflag.FlagSet, name, key string) {
+	_ = flags.SetAnnotation(name, configKeyAnnotation, []string{key})
+}
+
+func loadConfig(cmd *cobra.Command, repoConfig, appConfig string) (*config.Loader, error) {
+	loader := config.NewLoader()
+
+	global, err := config.FindGlobal(configFlag)
+	if err != nil {
+		return nil, fmt.Errorf("find config: %w", err)
+	}
+	if global != "" {
+		if err := loader.LoadFile(global); err != nil {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+	}
+
+	if repoConfig != "" {
+		if err := loader.LoadFile(repoConfig); err != nil {
+			return nil, fmt.Errorf("load repo config: %w", err)
+		}
+	}
+
+	if appConfig != "" {
+		if err := loadAppConfig(loader, appConfig); err != nil {
+			return nil, fmt.Errorf("load app config: %w", err)
+		}
+	}
+
+	if err := loader.LoadEnv(os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("load environment: %w", err)
+	}
+
+	var flagErr error
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		keys := f.Annotations[configKeyAnnotation]
+		if len(keys) == 0 || flagErr != nil {
+			return
+		}
+		flagErr = loader.Set(keys[0], f.Value.String(), config.SourceFlag, "--"+f.Name)
+	})
+	return loader, flagErr
+}
+
+func loadAppConfig(loader *config.Loader, path string) error {
+	own := config.NewLoader()
+	if err := own.LoadFile(path); err != nil {
+		return err
+	}
+	if err := loader.LoadFile(path); err != nil {
+		return err
+	}
+
+	origin := "app " + app.Name
+	if !own.IsSet("docker.project_name") {
+		if err := loader.Set("docker.project_name", app.Name, config.SourceDefault, origin); err != nil {
+			return err
+		}
+	}
+	if !own.IsSet("docker.compose_files") {
+		loader.Config().Docker.ComposeFiles = nil
+	}
+	if !own.IsSet("docker.compose_file") {
+		return loader.Set("docker.compose_file", config.Default().Docker.ComposeFile, config.SourceDefault, origin)
+	}
+	return nil
+}
+
 func repoWorkDir(name string) string {
 	return filepath.Join(".kedge", "repos", name)
 }
 
-func loadRepoConfig(name string) (*config.Config, error) {
-	workDir := repoWorkDir(name)
-	configPath := filepath.Join(workDir, "kedge.yaml")
-	cfg, err := config.Load(configPath)
-	if err != nil {
-		configPath = filepath.Join(workDir, "kedge.yml")
-		cfg, err = config.Load(configPath)
+func targetDir() string {
+	dir := repoWorkDir(repo.Name)
+	if app != nil {
+		dir = filepath.Join(dir, app.Path)
+	}
+	return dir
+}
+
+// composePaths returns the target's compose files in merge order.
+func composePaths() []string {
+	dir := targetDir()
+	return lo.Map(cfg.Docker.Files(), func(file string, _ int) string { return filepath.Join(dir, file) })
+}
+
+func appName() string {
+	if app == nil {
+		return ""
+	}
+	return app.Name
+}
+
+func requireTarget() error {
+	if repo == nil {
+		return fmt.Errorf("--repo is required")
+	}
+	if app == nil && len(repoApps) > 0 {
+		names := lo.Map(repoApps, func(a config.App, _ int) string { return a.Name })
+		return fmt.Errorf("repository %q has multiple apps; pass --app (one of: %s)", repo.Name, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func cliActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "cli"
+}
+
+func recordEvent(ctx context.Context, store *state.Store, e *state.Event) {
+	if e.Actor == "" {
+		e.Actor = cliActor()
+	}
+	if _, err := store.RecordEvent(ctx, e); err != nil {
+		logger.Warn("failed to record event", slog.String("type", string(e.Type)), slog.Any("error", err))
 	}
-	return cfg, err
 }
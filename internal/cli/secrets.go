@@ -0,0 +1,13 @@
+package cli
+
+import "github.com/spf13/cobra"
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage stored credentials",
+	Long:  `Commands for managing the key that encrypts credentials stored with kedge repo add --token-stdin or --ssh-key-stdin.`,
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/secrets"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var secretsRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Rotate the credential encryption key",
+	Long: `Generate a new key, re-encrypt every stored credential with it and remove the old key from the key file.
+
+The new key is written to the key file before anything is re-encrypted, so an interrupted rotation can be run again.`,
+	Args: cobra.NoArgs,
+	RunE: runSecretsRotateKey,
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsRotateKeyCmd)
+}
+
+func runSecretsRotateKey(cmd *cobra.Command, args []string) error {
+	keyFile := cfg.State.KeyFile
+	keys, err := secrets.Load(keyFile)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	oldID := keys.KeyID()
+	if err := keys.Rotate(); err != nil {
+		return err
+	}
+	if err := keys.Save(keyFile); err != nil {
+		return fmt.Errorf("save key file: %w", err)
+	}
+
+	n, err := store.RewriteSecrets(ctx, func(secret string) (string, error) {
+		plaintext, err := keys.Decrypt(secret)
+		if err != nil {
+			return "", err
+		}
+		return keys.Encrypt(plaintext)
+	})
+	if err != nil {
+		return fmt.Errorf("re-encrypt credentials: %w", err)
+	}
+
+	keys.Prune()
+	if err := keys.Save(keyFile); err != nil {
+		return fmt.Errorf("save key file: %w", err)
+	}
+
+	payload, _ := json.Marshal(map[string]any{"old_key": oldID, "new_key": keys.KeyID(), "credentials": n})
+	recordEvent(ctx, store, &state.Event{Type: state.EventSecretsKeyRotated, Payload: payload})
+
+	fmt.Printf("Rotated key %s to %s and re-encrypted %d credential(s)\n", oldID, keys.KeyID(), n)
+	return nil
+}
@@ -12,7 +12,9 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/LoriKarikari/kedge/internal/bus"
 	"github.com/LoriKarikari/kedge/internal/manager"
+	"github.com/LoriKarikari/kedge/internal/reconcile"
 	"github.com/LoriKarikari/kedge/internal/server"
 	"github.com/LoriKarikari/kedge/internal/state"
 	"github.com/LoriKarikari/kedge/internal/telemetry"
@@ -26,12 +28,21 @@ var serveCmd = &cobra.Command{
 }
 
 func init() {
+	serveCmd.Flags().Int("port", 0, "HTTP server port (default: server.port)")
+	serveCmd.Flags().String("address", "", "Address to bind (default: server.address)")
+	bindConfigFlag(serveCmd.Flags(), "port", "server.port")
+	bindConfigFlag(serveCmd.Flags(), "address", "server.address")
 	rootCmd.AddCommand(serveCmd)
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
 	statePath := cfg.State.Path
 
+	mode, err := reconcile.ParseMode(cfg.Reconciliation.Mode)
+	if err != nil {
+		return fmt.Errorf("reconciliation.mode: %w", err)
+	}
+
 	if err := os.MkdirAll(filepath.Dir(statePath), 0o750); err != nil {
 		return err
 	}
@@ -47,7 +58,7 @@ func runServe(cmd *cobra.Command, args []string) error {
 
 	var tp *telemetry.Provider
 	if cfg.Telemetry.Metrics.Enabled {
-		tp, err = telemetry.New()
+		tp, err = telemetry.New(metricsOptions()...)
 		if err != nil {
 			return fmt.Errorf("init telemetry: %w", err)
 		}
@@ -59,13 +70,47 @@ func runServe(cmd *cobra.Command, args []string) error {
 				logger.Error("telemetry shutdown error", slog.Any("error", err))
 			}
 		}()
-		logger.Info("telemetry enabled", slog.String("endpoint", "/metrics"))
+		logger.Info("telemetry enabled",
+			slog.Bool("prometheus", tp.ServesPrometheus()),
+			slog.Bool("otlp", cfg.Telemetry.Metrics.OTLP.Enabled),
+		)
 	}
 
-	mgr := manager.New(store, tp, logger)
+	if cfg.Telemetry.Tracing.Enabled {
+		traces, err := telemetry.NewTracerProvider(ctx, telemetry.TracingConfig{
+			Endpoint:           cfg.Telemetry.Tracing.Endpoint,
+			Headers:            cfg.Telemetry.Tracing.Headers,
+			SampleRatio:        cfg.Telemetry.Tracing.SampleRatio,
+			ResourceAttributes: cfg.Telemetry.Resource,
+		})
+		if err != nil {
+			return fmt.Errorf("init tracing: %w", err)
+		}
+		traces.SetGlobal()
+		defer func() {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+			if err := traces.Shutdown(shutdownCtx); err != nil {
+				logger.Error("tracing shutdown error", slog.Any("error", err))
+			}
+		}()
+		logger.Info("tracing enabled", slog.String("endpoint", cfg.Telemetry.Tracing.Endpoint))
+	}
+
+	events := bus.New(bus.DefaultHistorySize)
+
+	mgr := manager.New(store, tp, logger, manager.WithEventBus(events))
 	defer mgr.Close()
 
-	srv := server.New(cfg.Server.Port, mgr, tp, logger)
+	srv := server.New(cfg.Server.Port, mgr, tp, logger,
+		server.WithReviewer(mgr),
+		server.WithEventLog(store),
+		server.WithEventBus(events),
+		server.WithAuthenticator(store),
+		server.WithAddress(cfg.Server.Address),
+		server.WithUnixSocket(cfg.Server.Socket),
+		server.WithTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, cfg.Server.TLS.ClientCAFile),
+	)
 	if err := srv.Start(ctx); err != nil {
 		return fmt.Errorf("start server: %w", err)
 	}
@@ -77,10 +122,41 @@ func runServe(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	logger.Info("server started", slog.Int("port", cfg.Server.Port))
+	logger.Info("server started", slog.String("address", srv.Addr()), slog.Bool("tls", cfg.Server.TLS.Enabled()))
 	logger.Info("starting kedge manager")
 
 	return mgr.Start(ctx, manager.Config{
-		StatePath: statePath,
+		StatePath:    statePath,
+		PollInterval: cfg.Git.PollInterval,
+		Reconciliation: reconcile.Config{
+			Mode:     mode,
+			Interval: cfg.Reconciliation.Interval,
+		},
+		LogLevel:       cfg.Logging.Level,
+		EventRetention: cfg.State.EventRetention,
+		KeyFile:        cfg.State.KeyFile,
 	})
 }
+
+func metricsOptions() []telemetry.Option {
+	metrics := cfg.Telemetry.Metrics
+	opts := []telemetry.Option{telemetry.WithResourceAttributes(cfg.Telemetry.Resource)}
+	if !metrics.Prometheus {
+		opts = append(opts, telemetry.WithoutPrometheus())
+	}
+	if metrics.OTLP.Enabled {
+		opts = append(opts, telemetry.WithOTLP(telemetry.OTLPConfig{
+			Protocol: telemetry.Protocol(metrics.OTLP.Protocol),
+			Endpoint: metrics.OTLP.Endpoint,
+			Headers:  metrics.OTLP.Headers,
+			Interval: metrics.OTLP.Interval,
+			TLS: telemetry.TLSConfig{
+				CAFile:             metrics.OTLP.TLS.CAFile,
+				CertFile:           metrics.OTLP.TLS.CertFile,
+				KeyFile:            metrics.OTLP.TLS.KeyFile,
+				InsecureSkipVerify: metrics.OTLP.TLS.InsecureSkipVerify,
+			},
+		}))
+	}
+	return opts
+}
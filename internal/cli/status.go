@@ -3,10 +3,11 @@ package cli
 import (
 	"context"
 	"fmt"
-	"path/filepath"
+	"strings"
 
 	"github.com/LoriKarikari/kedge/internal/docker"
 	"github.com/LoriKarikari/kedge/internal/state"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 )
 
@@ -22,8 +23,8 @@ func init() {
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
-	if repo == nil {
-		return fmt.Errorf("--repo is required")
+	if err := requireTarget(); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
@@ -34,8 +35,12 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	composePath := filepath.Join(repoWorkDir(repo.Name), cfg.Docker.ComposeFile)
-	project, err := docker.LoadProject(ctx, composePath, cfg.Docker.ProjectName)
+	env, err := repoEnvironment(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = docker.RemoveSecrets(cfg.Docker.ProjectName) }()
+	project, err := docker.LoadProject(ctx, composePaths(), cfg.Docker.ProjectName, docker.WithEnvironment(env), docker.WithProfiles(cfg.Docker.Profiles...))
 	if err != nil {
 		return fmt.Errorf("load compose: %w", err)
 	}
@@ -61,7 +66,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	defer store.Close()
-	deployment, err := store.GetLastDeployment(ctx, repo.Name)
+	deployment, err := store.GetLastDeployment(ctx, repo.Name, appName())
 	switch {
 	case err == state.ErrNotFound:
 		fmt.Println("No deployments yet")
@@ -76,5 +81,22 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	pending, err := store.ListAwaitingApproval(ctx, repo.Name, appName())
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		fmt.Println("\n=== Awaiting Approval ===")
+		for _, d := range pending {
+			fmt.Printf("ID %d  commit %s  (%s)\n", d.ID, lo.Substring(d.CommitHash, 0, 8), d.DeployedAt.Format("2006-01-02 15:04:05"))
+			for line := range strings.SplitSeq(d.Diff, "\n") {
+				if line != "" {
+					fmt.Printf("  %s\n", line)
+				}
+			}
+		}
+		fmt.Printf("\nApprove with: kedge approve --repo %s%s <id>\n", repo.Name, lo.Ternary(app != nil, " --app "+appName(), ""))
+	}
+
 	return nil
 }
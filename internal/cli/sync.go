@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/LoriKarikari/kedge/internal/controller"
 	"github.com/LoriKarikari/kedge/internal/reconcile"
 	"github.com/spf13/cobra"
 )
@@ -26,22 +25,9 @@ func init() {
 }
 
 func runSync(cmd *cobra.Command, args []string) error {
-	if repo == nil {
-		return fmt.Errorf("--repo is required")
-	}
-
 	ctx := context.Background()
 
-	ctrlCfg := controller.Config{
-		RepoName:     repo.Name,
-		ProjectName:  cfg.Docker.ProjectName,
-		ComposePath:  cfg.Docker.ComposeFile,
-		WorkDir:      repoWorkDir(repo.Name),
-		StatePath:    cfg.State.Path,
-		ReconcileCfg: reconcile.Config{Mode: reconcile.ModeAuto},
-	}
-
-	ctrl, err := controller.NewStandalone(ctx, ctrlCfg, nil, logger)
+	ctrl, err := newStandaloneController(ctx)
 	if err != nil {
 		return err
 	}
@@ -49,9 +35,9 @@ func runSync(cmd *cobra.Command, args []string) error {
 
 	var result *reconcile.Result
 	if syncFlags.force {
-		result, err = ctrl.Sync(ctx)
+		result, err = ctrl.Sync(ctx, cliActor())
 	} else {
-		result, err = ctrl.Reconcile(ctx)
+		result, err = ctrl.Reconcile(ctx, cliActor())
 	}
 	if err != nil {
 		return err
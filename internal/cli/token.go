@@ -0,0 +1,13 @@
+package cli
+
+import "github.com/spf13/cobra"
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens",
+	Long:  `Commands for creating, listing, and revoking tokens used to authenticate against the HTTP API.`,
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var tokenCreateFlags struct {
+	role string
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API token",
+	Long: `Create an API token with a role. Use --repo to restrict the token to a single repository.
+
+The token is printed once and only its hash is stored.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTokenCreate,
+}
+
+func init() {
+	tokenCreateCmd.Flags().StringVar(&tokenCreateFlags.role, "role", string(state.RoleViewer), "Token role: viewer, operator or admin")
+	tokenCmd.AddCommand(tokenCreateCmd)
+}
+
+func runTokenCreate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	role := state.Role(tokenCreateFlags.role)
+	if !role.IsValid() {
+		return fmt.Errorf("invalid role %q: must be viewer, operator or admin", tokenCreateFlags.role)
+	}
+
+	var repoName string
+	if repo != nil {
+		repoName = repo.Name
+	}
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	token, secret, err := store.CreateToken(ctx, name, role, repoName, cliActor())
+	if err != nil {
+		if errors.Is(err, state.ErrTokenExists) {
+			return fmt.Errorf("token %q already exists", name)
+		}
+		return err
+	}
+	recordEvent(ctx, store, &state.Event{
+		Type:     state.EventTokenCreated,
+		RepoName: repoName,
+		Payload:  tokenEventPayload(token.Name, token.Role),
+	})
+
+	scope := "all repositories"
+	if token.RepoName != "" {
+		scope = "repository " + token.RepoName
+	}
+	fmt.Printf("Created %s token %q for %s\n\n", token.Role, token.Name, scope)
+	fmt.Println(secret)
+	fmt.Println("\nStore this token now, it cannot be shown again.")
+	return nil
+}
+
+func tokenEventPayload(name string, role state.Role) json.RawMessage {
+	payload := map[string]string{"name": name}
+	if role != "" {
+		payload["role"] = string(role)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return data
+}
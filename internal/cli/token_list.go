@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Long:  `List all API tokens with their role, repository scope and last use.`,
+	RunE:  runTokenList,
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenListCmd)
+}
+
+func runTokenList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tokens, err := store.ListTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens")
+		return nil
+	}
+
+	fmt.Printf("%-20s  %-8s  %-15s  %-12s  %-19s  %s\n", "NAME", "ROLE", "REPO", "CREATED BY", "CREATED", "LAST USED")
+	fmt.Println("--------------------  --------  ---------------  ------------  -------------------  ---------")
+	for _, t := range tokens {
+		lastUsed := lo.Ternary(t.LastUsedAt.IsZero(), "never", t.LastUsedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("%-20s  %-8s  %-15s  %-12s  %-19s  %s\n",
+			t.Name,
+			t.Role,
+			lo.CoalesceOrEmpty(t.RepoName, "*"),
+			t.CreatedBy,
+			t.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			lastUsed,
+		)
+	}
+
+	return nil
+}
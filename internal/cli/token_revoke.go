@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <name>",
+	Short: "Revoke an API token",
+	Long:  `Revoke an API token. Requests using it are rejected immediately.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenRevoke,
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRevokeCmd)
+}
+
+func runTokenRevoke(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	ctx := context.Background()
+	store, err := state.New(ctx, cfg.State.Path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.RevokeToken(ctx, name); err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			return fmt.Errorf("token %q not found", name)
+		}
+		return err
+	}
+	recordEvent(ctx, store, &state.Event{Type: state.EventTokenRevoked, Payload: tokenEventPayload(name, "")})
+
+	fmt.Printf("Revoked token %q\n", name)
+	return nil
+}
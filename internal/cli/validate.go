@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/validate"
+)
+
+var validateFlags struct {
+	strict bool
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [path]",
+	Short: "Check kedge.yaml and the compose file for errors",
+	Long: `Validate a repository's kedge.yaml and the compose file it points to.
+
+The path can be a kedge.yaml file or a directory containing one, and defaults to the current directory (or the repository selected with --repo). For a repository with apps, every app is checked; pass --app to check a single one. Unknown keys and invalid values are errors. Compose keys that kedge does not implement are reported as warnings.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runValidate,
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateFlags.strict, "strict", false, "Treat warnings as errors")
+	rootCmd.AddCommand(validateCmd)
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	path := "."
+	switch {
+	case len(args) == 1:
+		path = args[0]
+	case repo != nil:
+		path = repoWorkDir(repo.Name)
+	}
+
+	configPath, err := resolveRepoConfig(path)
+	if err != nil {
+		return err
+	}
+
+	var diags []validate.Diagnostic
+	if len(args) == 0 && app != nil {
+		configPath, err = config.FindRepo(repoWorkDir(repo.Name))
+		if err == nil {
+			diags, err = validate.App(context.Background(), configPath, *app)
+		}
+	} else {
+		diags, err = validate.Repo(context.Background(), configPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+
+	errs, warnings := len(validate.Errors(diags)), len(validate.Warnings(diags))
+	if errs > 0 || (validateFlags.strict && warnings > 0) {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("validation failed: %d error(s), %d warning(s)", errs, warnings)
+	}
+
+	if warnings > 0 {
+		fmt.Printf("%s is valid with %d warning(s)\n", configPath, warnings)
+		return nil
+	}
+	fmt.Printf("%s is valid\n", configPath)
+	return nil
+}
+
+func resolveRepoConfig(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return path, nil
+	}
+	return config.FindRepo(path)
+}
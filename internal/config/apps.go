@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrAppNotFound = errors.New("app not found")
+	ErrInvalidApp  = errors.New("invalid app")
+)
+
+var appNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type App struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+func ResolveApps(repoDir string, apps []App) ([]App, error) {
+	var resolved []App
+	for _, app := range apps {
+		path := filepath.Clean(app.Path)
+		if app.Path == "" || filepath.IsAbs(path) || path == "." || strings.HasPrefix(path, "..") {
+			return nil, fmt.Errorf("%w: path must be a subdirectory of the repository: %q", ErrInvalidApp, app.Path)
+		}
+
+		matches, err := filepath.Glob(filepath.Join(repoDir, path))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidApp, app.Path, err)
+		}
+		glob := strings.ContainsAny(path, "*?[")
+		if glob && app.Name != "" {
+			return nil, fmt.Errorf("%w: %q: name cannot be set for a glob", ErrInvalidApp, app.Path)
+		}
+
+		for _, match := range matches {
+			_, err := FindRepo(match)
+			if info, statErr := os.Stat(match); statErr != nil || !info.IsDir() {
+				err = errors.New("not a directory")
+			}
+			if err != nil {
+				if glob {
+					continue
+				}
+				return nil, fmt.Errorf("%w: %q: %w", ErrInvalidApp, app.Path, err)
+			}
+			rel, err := filepath.Rel(repoDir, match)
+			if err != nil {
+				return nil, err
+			}
+			name := app.Name
+			if name == "" {
+				name = filepath.Base(rel)
+			}
+			resolved = append(resolved, App{Name: name, Path: filepath.ToSlash(rel)})
+		}
+		if !glob && len(matches) == 0 {
+			return nil, fmt.Errorf("%w: %q: directory not found", ErrInvalidApp, app.Path)
+		}
+	}
+
+	seen := make(map[string]bool, len(resolved))
+	for _, app := range resolved {
+		if !appNamePattern.MatchString(app.Name) {
+			return nil, fmt.Errorf("%w: name %q must contain only lowercase letters, digits, dashes and underscores", ErrInvalidApp, app.Name)
+		}
+		if seen[app.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrInvalidApp, app.Name)
+		}
+		seen[app.Name] = true
+	}
+	slices.SortFunc(resolved, func(a, b App) int { return strings.Compare(a.Name, b.Name) })
+	return resolved, nil
+}
+
+func FindApp(apps []App, name string) (App, error) {
+	i := slices.IndexFunc(apps, func(a App) bool { return a.Name == name })
+	if i < 0 {
+		return App{}, fmt.Errorf("%w: %q", ErrAppNotFound, name)
+	}
+	return apps[i], nil
+}
+
+func LoadApps(repoDir string) ([]App, error) {
+	path, err := FindRepo(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveApps(repoDir, cfg.Apps)
+}
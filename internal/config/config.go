@@ -10,7 +10,12 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// EnvEnvironment carries the instance's environment name so kedge.yaml and
+// compose files can select overlays with ${KEDGE_ENV}.
+const EnvEnvironment = "KEDGE_ENV"
+
 type Config struct {
+	Environment    string         `yaml:"environment"`
 	Git            Git            `yaml:"git"`
 	Docker         Docker         `yaml:"docker"`
 	Reconciliation Reconciliation `yaml:"reconciliation"`
@@ -18,6 +23,8 @@ type Config struct {
 	Logging        Logging        `yaml:"logging"`
 	Server         Server         `yaml:"server"`
 	Telemetry      Telemetry      `yaml:"telemetry"`
+	Notifications  []Notification `yaml:"notifications"`
+	Apps           []App          `yaml:"apps"`
 }
 
 type Git struct {
@@ -25,11 +32,28 @@ type Git struct {
 	Branch       string        `yaml:"branch"`
 	PollInterval time.Duration `yaml:"poll_interval"`
 	WorkDir      string        `yaml:"work_dir"`
+	Paths        Paths         `yaml:"paths"`
+}
+
+type Paths struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
 }
 
 type Docker struct {
-	ProjectName string `yaml:"project_name"`
-	ComposeFile string `yaml:"compose_file"`
+	ProjectName  string   `yaml:"project_name"`
+	ComposeFile  string   `yaml:"compose_file"`
+	ComposeFiles []string `yaml:"compose_files"`
+	Profiles     []string `yaml:"profiles"`
+}
+
+// Files returns the compose files to merge in order: compose_files when set,
+// otherwise compose_file alone.
+func (d Docker) Files() []string {
+	if len(d.ComposeFiles) > 0 {
+		return d.ComposeFiles
+	}
+	return []string{d.ComposeFile}
 }
 
 type Reconciliation struct {
@@ -38,7 +62,9 @@ type Reconciliation struct {
 }
 
 type State struct {
-	Path string `yaml:"path"`
+	Path           string        `yaml:"path"`
+	KeyFile        string        `yaml:"key_file"`
+	EventRetention time.Duration `yaml:"event_retention"`
 }
 
 type Logging struct {
@@ -47,15 +73,79 @@ type Logging struct {
 }
 
 type Server struct {
-	Port int `yaml:"port"`
+	Port    int       `yaml:"port"`
+	Address string    `yaml:"address"`
+	Socket  string    `yaml:"socket"`
+	TLS     ServerTLS `yaml:"tls"`
+}
+
+type ServerTLS struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+func (t ServerTLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
 }
 
 type Telemetry struct {
-	Metrics MetricsConfig `yaml:"metrics"`
+	Metrics  MetricsConfig     `yaml:"metrics"`
+	Tracing  TracingConfig     `yaml:"tracing"`
+	Resource map[string]string `yaml:"resource"`
 }
 
 type MetricsConfig struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled    bool        `yaml:"enabled"`
+	Prometheus bool        `yaml:"prometheus"`
+	OTLP       OTLPMetrics `yaml:"otlp"`
+}
+
+type OTLPMetrics struct {
+	Enabled  bool              `yaml:"enabled"`
+	Protocol string            `yaml:"protocol"`
+	Endpoint string            `yaml:"endpoint"`
+	Headers  map[string]string `yaml:"headers"`
+	Interval time.Duration     `yaml:"interval"`
+	TLS      ClientTLS         `yaml:"tls"`
+}
+
+type ClientTLS struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Endpoint    string            `yaml:"endpoint"`
+	Headers     map[string]string `yaml:"headers"`
+	SampleRatio float64           `yaml:"sample_ratio"`
+}
+
+type Notification struct {
+	Name         string            `yaml:"name"`
+	Type         string            `yaml:"type"`
+	URL          string            `yaml:"url"`
+	Topic        string            `yaml:"topic"`
+	Headers      map[string]string `yaml:"headers"`
+	Events       []string          `yaml:"events"`
+	Template     string            `yaml:"template"`
+	DedupeWindow time.Duration     `yaml:"dedupe_window"`
+	DedupeEvents []string          `yaml:"dedupe_events"`
+	RateLimit    int               `yaml:"rate_limit"`
+	Retries      int               `yaml:"retries"`
+	SMTP         SMTP              `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host        string   `yaml:"host"`
+	Port        int      `yaml:"port"`
+	Username    string   `yaml:"username"`
+	PasswordEnv string   `yaml:"password_env"`
+	From        string   `yaml:"from"`
+	To          []string `yaml:"to"`
 }
 
 func Default() *Config {
@@ -74,7 +164,9 @@ func Default() *Config {
 			Interval: time.Minute,
 		},
 		State: State{
-			Path: ".kedge/state.db",
+			Path:           ".kedge/state.db",
+			KeyFile:        ".kedge/secret.key",
+			EventRetention: 30 * 24 * time.Hour,
 		},
 		Logging: Logging{
 			Level:  "info",
@@ -85,7 +177,15 @@ func Default() *Config {
 		},
 		Telemetry: Telemetry{
 			Metrics: MetricsConfig{
-				Enabled: true,
+				Enabled:    true,
+				Prometheus: true,
+				OTLP: OTLPMetrics{
+					Protocol: "http",
+					Interval: time.Minute,
+				},
+			},
+			Tracing: TracingConfig{
+				SampleRatio: 1,
 			},
 		},
 	}
@@ -94,6 +194,36 @@ func Default() *Config {
 func Load(path string) (*Config, error) {
 	cfg := Default()
 
+	data, err := ReadExpanded(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// ExportEnvironment sets KEDGE_ENV to name for the rest of the process. An
+// empty name leaves any KEDGE_ENV already in the environment alone.
+func ExportEnvironment(name string) error {
+	if name == "" {
+		return nil
+	}
+	return os.Setenv(EnvEnvironment, name)
+}
+
+func ReadExpanded(path string) ([]byte, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(expandEnv(string(data))), nil
+}
+
+func readFile(path string) ([]byte, error) {
 	dir := filepath.Dir(path)
 	if dir == "" {
 		dir = "."
@@ -108,14 +238,7 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
-
-	expanded := expandEnv(string(data))
-
-	if err := yaml.Unmarshal([]byte(expanded), cfg); err != nil {
-		return nil, fmt.Errorf("parse config: %w", err)
-	}
-
-	return cfg, nil
+	return data, nil
 }
 
 var envPattern = regexp.MustCompile(`\$\{([^}:]+)(?::-([^}]*))?\}`)
@@ -0,0 +1,336 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+const (
+	EnvConfig  = "KEDGE_CONFIG"
+	envPrefix  = "KEDGE_"
+	globalName = "config.yaml"
+)
+
+var (
+	ErrUnknownKey         = errors.New("unknown config key")
+	ErrUnsupportedKey     = errors.New("config key cannot be set from a string")
+	ErrRepoConfigNotFound = errors.New("kedge.yaml not found")
+)
+
+var RepoFiles = []string{"kedge.yaml", "kedge.yml"}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+type Setting struct {
+	Key    string
+	Value  string
+	Source Source
+	Origin string
+}
+
+type origin struct {
+	source Source
+	detail string
+}
+
+type Loader struct {
+	cfg     *Config
+	origins map[string]origin
+}
+
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+func NewLoader() *Loader {
+	return &Loader{
+		cfg:     Default(),
+		origins: make(map[string]origin),
+	}
+}
+
+func (l *Loader) Config() *Config {
+	return l.cfg
+}
+
+func (l *Loader) LoadFile(path string) error {
+	expanded, err := ReadExpanded(path)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(expanded, l.cfg); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(expanded, &raw); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+	known := l.keys()
+	l.markFile(raw, "", known, path)
+	return nil
+}
+
+func (l *Loader) markFile(raw map[string]any, prefix string, known map[string]bool, path string) {
+	for name, value := range raw {
+		key := joinKey(prefix, name)
+		if known[key] {
+			l.origins[key] = origin{source: SourceFile, detail: path}
+			continue
+		}
+		if nested, ok := value.(map[string]any); ok {
+			l.markFile(nested, key, known, path)
+		}
+	}
+}
+
+func (l *Loader) LoadEnv(lookup func(string) (string, bool)) error {
+	for _, f := range fields(reflect.ValueOf(l.cfg).Elem(), "") {
+		name := EnvName(f.key)
+		raw, ok := lookup(name)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		l.origins[f.key] = origin{source: SourceEnv, detail: name}
+	}
+	return nil
+}
+
+func (l *Loader) Set(key, raw string, source Source, detail string) error {
+	for _, f := range fields(reflect.ValueOf(l.cfg).Elem(), "") {
+		if f.key != key {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		l.origins[key] = origin{source: source, detail: detail}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+}
+
+func (l *Loader) Settings() []Setting {
+	all := fields(reflect.ValueOf(l.cfg).Elem(), "")
+	settings := make([]Setting, 0, len(all))
+	for _, f := range all {
+		o, ok := l.origins[f.key]
+		if !ok {
+			o = origin{source: SourceDefault}
+		}
+		settings = append(settings, Setting{
+			Key:    f.key,
+			Value:  formatValue(f.key, f.value),
+			Source: o.source,
+			Origin: o.detail,
+		})
+	}
+	return settings
+}
+
+func (l *Loader) IsSet(key string) bool {
+	o, ok := l.origins[key]
+	return ok && o.source != SourceDefault
+}
+
+func (l *Loader) keys() map[string]bool {
+	known := make(map[string]bool)
+	for _, f := range fields(reflect.ValueOf(l.cfg).Elem(), "") {
+		known[f.key] = true
+	}
+	return known
+}
+
+func EnvName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func FindGlobal(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+	for _, dir := range configDirs() {
+		path := filepath.Join(dir, "kedge", globalName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func FindRepo(dir string) (string, error) {
+	for _, file := range RepoFiles {
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", ErrRepoConfigNotFound
+}
+
+func configDirs() []string {
+	var dirs []string
+	if home := os.Getenv("XDG_CONFIG_HOME"); home != "" {
+		dirs = append(dirs, home)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+
+	system := os.Getenv("XDG_CONFIG_DIRS")
+	if system == "" {
+		system = "/etc/xdg"
+	}
+	return append(dirs, filepath.SplitList(system)...)
+}
+
+func fields(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := joinKey(prefix, name)
+		if sf.Type.Kind() == reflect.Struct {
+			out = append(out, fields(v.Field(i), key)...)
+			continue
+		}
+		out = append(out, field{key: key, value: v.Field(i)})
+	}
+	return out
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return ErrUnsupportedKey
+		}
+		m := make(map[string]string)
+		for pair := range strings.SplitSeq(raw, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got %q", pair)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(val)
+		}
+		v.Set(reflect.ValueOf(m))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return ErrUnsupportedKey
+		}
+		v.Set(reflect.ValueOf(strings.Split(raw, ",")))
+	default:
+		return ErrUnsupportedKey
+	}
+	return nil
+}
+
+func formatValue(key string, v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		slices.Sort(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			value := fmt.Sprint(v.MapIndex(reflect.ValueOf(k)).Interface())
+			if strings.HasSuffix(key, "headers") {
+				value = "<redacted>"
+			}
+			pairs = append(pairs, k+"="+value)
+		}
+		return strings.Join(pairs, ",")
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return ""
+		}
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Sprintf("[%d entries]", v.Len())
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).String()
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func settingsByKey(l *Loader) map[string]Setting {
+	out := make(map[string]Setting)
+	for _, s := range l.Settings() {
+		out[s.Key] = s
+	}
+	return out
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `
+git:
+  branch: develop
+  poll_interval: 30s
+server:
+  port: 9000
+  address: 127.0.0.1
+logging:
+  level: debug
+`)
+
+	l := NewLoader()
+	if err := l.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if err := l.LoadEnv(envLookup(map[string]string{
+		"KEDGE_SERVER_PORT":   "9100",
+		"KEDGE_LOGGING_LEVEL": "warn",
+		"KEDGE_STATE_PATH":    "/var/lib/kedge/state.db",
+		"KEDGE_GIT_BRANCH":    "",
+	})); err != nil {
+		t.Fatalf("LoadEnv() error = %v", err)
+	}
+	if err := l.Set("server.port", "9200", SourceFlag, "--port"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		source Source
+		origin string
+	}{
+		{"reconciliation.mode", "auto", SourceDefault, ""},
+		{"git.branch", "develop", SourceFile, path},
+		{"git.poll_interval", "30s", SourceFile, path},
+		{"server.address", "127.0.0.1", SourceFile, path},
+		{"state.path", "/var/lib/kedge/state.db", SourceEnv, "KEDGE_STATE_PATH"},
+		{"logging.level", "warn", SourceEnv, "KEDGE_LOGGING_LEVEL"},
+		{"server.port", "9200", SourceFlag, "--port"},
+	}
+	settings := settingsByKey(l)
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := settings[tt.key]
+			if !ok {
+				t.Fatalf("Settings() has no %s", tt.key)
+			}
+			want := Setting{Key: tt.key, Value: tt.value, Source: tt.source, Origin: tt.origin}
+			if got != want {
+				t.Errorf("setting = %+v, want %+v", got, want)
+			}
+			if l.IsSet(tt.key) != (tt.source != SourceDefault) {
+				t.Errorf("IsSet() = %v for a %s value", l.IsSet(tt.key), tt.source)
+			}
+		})
+	}
+
+	cfg := l.Config()
+	if cfg.Server.Port != 9200 || cfg.Git.PollInterval != 30*time.Second || cfg.Logging.Level != "warn" {
+		t.Errorf("config = port %d, poll %s, level %s", cfg.Server.Port, cfg.Git.PollInterval, cfg.Logging.Level)
+	}
+}
+
+func TestLoaderFileOrigins(t *testing.T) {
+	dir := t.TempDir()
+	global := writeConfig(t, dir, `
+git:
+  branch: develop
+telemetry:
+  resource:
+    team: platform
+unknown:
+  nested: true
+`)
+	repo := filepath.Join(dir, "kedge.yaml")
+	if err := os.WriteFile(repo, []byte("git:\n  url: https://example.com/repo.git\n  branch: release\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLoader()
+	for _, path := range []string{global, repo} {
+		if err := l.LoadFile(path); err != nil {
+			t.Fatalf("LoadFile(%s) error = %v", path, err)
+		}
+	}
+
+	settings := settingsByKey(l)
+	tests := []struct {
+		key    string
+		value  string
+		origin string
+	}{
+		{"git.branch", "release", repo},
+		{"git.url", "https://example.com/repo.git", repo},
+		{"telemetry.resource", "team=platform", global},
+	}
+	for _, tt := range tests {
+		if got := settings[tt.key]; got.Value != tt.value || got.Source != SourceFile || got.Origin != tt.origin {
+			t.Errorf("%s = %+v, want %q from %s", tt.key, got, tt.value, tt.origin)
+		}
+	}
+	if _, ok := settings["unknown.nested"]; ok {
+		t.Error("Settings() lists an unknown file key")
+	}
+	if _, ok := settings["telemetry.resource.team"]; ok {
+		t.Error("Settings() lists a map entry as its own key")
+	}
+}
+
+func TestLoadEnvValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   string
+		raw   string
+		get   func(*Config) any
+		want  any
+		shown string
+	}{
+		{"string", "KEDGE_DOCKER_PROJECT_NAME", "shop", func(c *Config) any { return c.Docker.ProjectName }, "shop", "shop"},
+		{"duration", "KEDGE_RECONCILIATION_INTERVAL", "90s", func(c *Config) any { return c.Reconciliation.Interval }, 90 * time.Second, "1m30s"},
+		{"bool", "KEDGE_TELEMETRY_METRICS_ENABLED", "false", func(c *Config) any { return c.Telemetry.Metrics.Enabled }, false, "false"},
+		{"int", "KEDGE_SERVER_PORT", "9090", func(c *Config) any { return c.Server.Port }, 9090, "9090"},
+		{"float", "KEDGE_TELEMETRY_TRACING_SAMPLE_RATIO", "0.25", func(c *Config) any { return c.Telemetry.Tracing.SampleRatio }, 0.25, "0.25"},
+		{"map", "KEDGE_TELEMETRY_RESOURCE", "team = platform, ,region=eu", func(c *Config) any { return c.Telemetry.Resource }, map[string]string{"team": "platform", "region": "eu"}, "region=eu,team=platform"},
+		{"redacted map", "KEDGE_TELEMETRY_TRACING_HEADERS", "authorization=secret", func(c *Config) any { return c.Telemetry.Tracing.Headers }, map[string]string{"authorization": "secret"}, "authorization=<redacted>"},
+		{"slice", "KEDGE_DOCKER_COMPOSE_FILES", "base.yaml,prod.yaml", func(c *Config) any { return c.Docker.ComposeFiles }, []string{"base.yaml", "prod.yaml"}, "base.yaml,prod.yaml"},
+		{"nested slice", "KEDGE_GIT_PATHS_INCLUDE", "web/**", func(c *Config) any { return c.Git.Paths.Include }, []string{"web/**"}, "web/**"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader()
+			if err := l.LoadEnv(envLookup(map[string]string{tt.env: tt.raw})); err != nil {
+				t.Fatalf("LoadEnv() error = %v", err)
+			}
+			if got := tt.get(l.Config()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+			for _, s := range l.Settings() {
+				if EnvName(s.Key) != tt.env {
+					continue
+				}
+				if s.Value != tt.shown || s.Source != SourceEnv || s.Origin != tt.env {
+					t.Errorf("setting = %+v, want %q from %s", s, tt.shown, tt.env)
+				}
+			}
+		})
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(*Loader) error
+		want error
+	}{
+		{"invalid env duration", func(l *Loader) error {
+			return l.LoadEnv(envLookup(map[string]string{"KEDGE_GIT_POLL_INTERVAL": "soon"}))
+		}, nil},
+		{"invalid env map", func(l *Loader) error {
+			return l.LoadEnv(envLookup(map[string]string{"KEDGE_TELEMETRY_RESOURCE": "team"}))
+		}, nil},
+		{"invalid flag int", func(l *Loader) error {
+			return l.Set("server.port", "http", SourceFlag, "--port")
+		}, nil},
+		{"unknown key", func(l *Loader) error {
+			return l.Set("server.prot", "80", SourceFlag, "--prot")
+		}, ErrUnknownKey},
+		{"struct slice", func(l *Loader) error {
+			return l.Set("notifications", "slack", SourceFlag, "--notifications")
+		}, ErrUnsupportedKey},
+		{"missing file", func(l *Loader) error {
+			return l.LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		}, os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(NewLoader())
+			if err == nil {
+				t.Fatal("error = nil")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindGlobal(t *testing.T) {
+	home := t.TempDir()
+	system1 := t.TempDir()
+	system2 := t.TempDir()
+	for _, dir := range []string{home, system2} {
+		if err := os.MkdirAll(filepath.Join(dir, "kedge"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeConfig(t, filepath.Join(dir, "kedge"), "")
+	}
+	homeFile := filepath.Join(home, "kedge", "config.yaml")
+	systemFile := filepath.Join(system2, "kedge", "config.yaml")
+	empty := t.TempDir()
+
+	tests := []struct {
+		name       string
+		explicit   string
+		env        string
+		configHome string
+		configDirs string
+		want       string
+	}{
+		{"flag wins", "/etc/kedge.yaml", "/tmp/env.yaml", home, "", "/etc/kedge.yaml"},
+		{"KEDGE_CONFIG", "", "/tmp/env.yaml", home, "", "/tmp/env.yaml"},
+		{"XDG_CONFIG_HOME", "", "", home, system2, homeFile},
+		{"XDG_CONFIG_DIRS in order", "", "", empty, system1 + string(os.PathListSeparator) + system2, systemFile},
+		{"nothing found", "", "", empty, system1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvConfig, tt.env)
+			t.Setenv("XDG_CONFIG_HOME", tt.configHome)
+			t.Setenv("XDG_CONFIG_DIRS", tt.configDirs)
+			got, err := FindGlobal(tt.explicit)
+			if err != nil {
+				t.Fatalf("FindGlobal() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FindGlobal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("home fallback", func(t *testing.T) {
+		t.Setenv(EnvConfig, "")
+		t.Setenv("XDG_CONFIG_HOME", "")
+		t.Setenv("XDG_CONFIG_DIRS", empty)
+		t.Setenv("HOME", t.TempDir())
+		dir := filepath.Join(os.Getenv("HOME"), ".config", "kedge")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		want := writeConfig(t, dir, "")
+		if got, err := FindGlobal(""); err != nil || got != want {
+			t.Errorf("FindGlobal() = %q, %v, want %q", got, err, want)
+		}
+	})
+}
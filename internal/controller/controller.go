@@ -1,29 +1,60 @@
 package controller
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/config"
 	"github.com/LoriKarikari/kedge/internal/docker"
 	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/logging"
+	"github.com/LoriKarikari/kedge/internal/notify"
 	"github.com/LoriKarikari/kedge/internal/reconcile"
 	"github.com/LoriKarikari/kedge/internal/state"
 	"github.com/LoriKarikari/kedge/internal/telemetry"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var ErrStaleApproval = errors.New("working tree no longer matches the deployment awaiting approval")
+
+const systemActor = "kedge"
+
 type Config struct {
-	RepoName     string
-	ProjectName  string
-	ComposePath  string
-	WorkDir      string
-	StatePath    string
-	ReconcileCfg reconcile.Config
+	RepoName      string
+	AppName       string
+	AppPath       string
+	ProjectName   string
+	ComposeFiles  []string
+	Profiles      []string
+	WorkDir       string
+	StatePath     string
+	PollInterval  time.Duration
+	Paths         config.Paths
+	ReconcileCfg  reconcile.Config
+	LogLevel      string
+	Notifications []config.Notification
+
+	TrustKeys         *git.Keyring
+	RequireSignedTags bool
+
+	KeyFile string
+
+	// Preview is the branch a preview environment deploys. Its AppName is
+	// state.PreviewApp(Preview) so its history stays apart from the repo's.
+	Preview           string
+	PreviewPortOffset int
 }
 
 type Controller struct {
@@ -31,15 +62,40 @@ type Controller struct {
 	client     *docker.Client
 	reconciler *reconcile.Reconciler
 	store      *state.Store
+	bus        *bus.Bus
 	metrics    *telemetry.Metrics
-	config     Config
+	defaults   *Config
 	workDir    string
 	logger     *slog.Logger
+	logLevel   slog.LevelVar
 	ready      atomic.Bool
+	deployMu   sync.Mutex
+	lastDrift  string
+
+	mu             sync.RWMutex
+	config         Config
+	notifier       *notify.Notifier
+	envFingerprint string
+}
+
+type Option func(*Controller)
+
+// WithWatcher gives a standalone controller the checkout it deploys, so that
+// its head can be verified against the trusted keys.
+func WithWatcher(w *git.Watcher) Option {
+	return func(c *Controller) {
+		c.watcher = w
+	}
 }
 
-func New(ctx context.Context, watcher *git.Watcher, cfg Config, metrics *telemetry.Metrics, logger *slog.Logger) (*Controller, error) {
-	ctrl, err := newController(ctx, cfg, metrics, logger)
+func WithEventBus(b *bus.Bus) Option {
+	return func(c *Controller) {
+		c.bus = b
+	}
+}
+
+func New(ctx context.Context, watcher *git.Watcher, cfg Config, metrics *telemetry.Metrics, logger *slog.Logger, opts ...Option) (*Controller, error) {
+	ctrl, err := newController(ctx, cfg, metrics, logger, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -48,11 +104,11 @@ func New(ctx context.Context, watcher *git.Watcher, cfg Config, metrics *telemet
 	return ctrl, nil
 }
 
-func NewStandalone(ctx context.Context, cfg Config, metrics *telemetry.Metrics, logger *slog.Logger) (*Controller, error) {
+func NewStandalone(ctx context.Context, cfg Config, metrics *telemetry.Metrics, logger *slog.Logger, opts ...Option) (*Controller, error) {
 	if cfg.WorkDir == "" {
 		return nil, fmt.Errorf("workdir is required")
 	}
-	ctrl, err := newController(ctx, cfg, metrics, logger)
+	ctrl, err := newController(ctx, cfg, metrics, logger, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -60,59 +116,119 @@ func NewStandalone(ctx context.Context, cfg Config, metrics *telemetry.Metrics,
 	return ctrl, nil
 }
 
-func newController(ctx context.Context, cfg Config, metrics *telemetry.Metrics, logger *slog.Logger) (*Controller, error) {
-	if filepath.IsAbs(cfg.ComposePath) {
-		return nil, fmt.Errorf("compose path must be relative: %s", cfg.ComposePath)
+func newController(ctx context.Context, cfg Config, metrics *telemetry.Metrics, logger *slog.Logger, opts []Option) (*Controller, error) {
+	if path, ok := lo.Find(cfg.ComposeFiles, filepath.IsAbs); ok {
+		return nil, fmt.Errorf("compose path must be relative: %s", path)
 	}
 
 	if logger == nil {
 		logger = slog.Default()
 	}
+
+	ctrl := &Controller{
+		metrics: metrics,
+		config:  cfg,
+	}
+	for _, opt := range opts {
+		opt(ctrl)
+	}
+
+	if cfg.LogLevel != "" {
+		ctrl.setLogLevel(cfg.LogLevel)
+		logger = logging.WithLevel(logger, &ctrl.logLevel)
+	}
 	logger = logger.With(slog.String("component", "controller"))
+	if cfg.AppName != "" {
+		logger = logger.With(slog.String("app", cfg.AppName))
+	}
+	ctrl.logger = logger
 
-	client, err := docker.NewClient(cfg.ProjectName, logger)
+	notifier, err := notify.New(cfg.Notifications, logger)
 	if err != nil {
 		return nil, err
 	}
+	ctrl.notifier = notifier
+
+	client, err := docker.NewClient(cfg.ProjectName, logger, docker.WithRepoName(cfg.RepoName), docker.WithAppName(cfg.AppName), docker.WithEventBus(ctrl.bus), docker.WithMetrics(metrics))
+	if err != nil {
+		return nil, err
+	}
+	ctrl.client = client
 
 	store, err := state.New(ctx, cfg.StatePath)
 	if err != nil {
 		_ = client.Close()
 		return nil, err
 	}
+	ctrl.store = store
 
-	reconciler := reconcile.New(client, nil, cfg.ReconcileCfg, logger)
+	ctrl.reconciler = reconcile.New(client, nil, cfg.ReconcileCfg, logger, reconcile.WithEventBus(ctrl.bus, cfg.RepoName), reconcile.WithAppName(cfg.AppName))
+	return ctrl, nil
+}
 
-	return &Controller{
-		client:     client,
-		reconciler: reconciler,
-		store:      store,
-		metrics:    metrics,
-		config:     cfg,
-		logger:     logger,
-	}, nil
+func (c *Controller) publish(eventType, service, commit string, data map[string]any) {
+	c.bus.Publish(bus.Event{
+		Type:    eventType,
+		Repo:    c.config.RepoName,
+		App:     c.config.AppName,
+		Service: service,
+		Commit:  commit,
+		Data:    data,
+	})
 }
 
 func (c *Controller) Run(ctx context.Context) error {
 	if err := c.watcher.Clone(ctx); err != nil {
+		c.stopped(ctx, err)
 		return err
 	}
 
-	if err := c.loadAndReconcile(ctx, c.watcher.LastCommit()); err != nil {
-		return fmt.Errorf("initial reconcile: %w", err)
+	if err := c.Start(ctx); err != nil {
+		return err
 	}
 
-	c.ready.Store(true)
-
-	go c.watchDrift(ctx)
-
 	c.watcher.Watch(ctx, func(event git.ChangeEvent) {
-		c.handleChange(ctx, event)
+		c.HandleChange(ctx, event)
 	})
 
 	return nil
 }
 
+func (c *Controller) Start(ctx context.Context) error {
+	commit := c.watcher.LastCommit()
+	if err := c.verify(ctx, commit, c.watcher.CurrentTag()); err != nil {
+		c.logger.Warn("not ready until a trusted commit is deployed", slog.Any("error", err))
+	} else {
+		c.deployMu.Lock()
+		err := c.loadAndReconcile(ctx, commit)
+		c.deployMu.Unlock()
+		if err != nil {
+			err = fmt.Errorf("initial reconcile: %w", err)
+			c.stopped(ctx, err)
+			return err
+		}
+		c.ready.Store(true)
+	}
+
+	go c.watchDrift(ctx)
+	go c.watchEnv(ctx)
+	return nil
+}
+
+func (c *Controller) stopped(ctx context.Context, err error) {
+	if ctx.Err() == nil {
+		c.notify(ctx, notify.EventControllerStopped, "", err.Error(), nil)
+	}
+}
+
+func (c *Controller) Name() string {
+	return telemetry.RepoLabel(c.config.RepoName, c.config.AppName)
+}
+
+func (c *Controller) ProjectName() string {
+	return c.currentConfig().ProjectName
+}
+
 func (c *Controller) IsReady() bool {
 	return c.ready.Load()
 }
@@ -136,48 +252,160 @@ func (c *Controller) handleDriftResult(ctx context.Context, result *reconcile.Re
 		c.logger.Error("drift check failed", slog.Any("error", result.Error))
 		return
 	}
+	if len(result.Changes) > 0 {
+		services := lo.Map(result.Changes, func(change docker.ServiceDiff, _ int) string { return change.Service })
+		message := lo.Ternary(result.Reconciled, "remediated", "not remediated")
+		c.notify(ctx, notify.EventDriftDetected, "", message, services)
+	}
+	c.recordDrift(ctx, result)
 	if result.Reconciled {
 		c.logger.Info("drift reconciled", slog.Int("changes", len(result.Changes)))
 		if c.metrics != nil {
 			for _, change := range result.Changes {
-				c.metrics.RecordDrift(ctx, c.config.RepoName, change.Service)
+				c.metrics.RecordDrift(ctx, c.Name(), change.Service)
 			}
 		}
 	}
+	inSync := result.Reconciled || len(result.Changes) == 0
+	if inSync && c.metrics != nil {
+		c.metrics.RecordSuccessfulReconcile(ctx, c.Name())
+	}
+	c.collectMetrics(ctx, lo.Ternary(inSync, 0, len(result.Changes)))
+}
+
+func (c *Controller) collectMetrics(ctx context.Context, outOfSync int) {
+	if c.metrics == nil {
+		return
+	}
+	repo := c.Name()
+	c.metrics.SetOutOfSync(repo, outOfSync)
+
+	statuses, err := c.client.Status(ctx)
+	if err != nil {
+		c.logger.Warn("failed to collect service metrics", slog.Any("error", err))
+	} else {
+		c.metrics.SetServiceStates(repo, lo.Map(statuses, func(s docker.ServiceStatus, _ int) telemetry.ServiceState {
+			return telemetry.ServiceState{Service: s.Service, State: s.State, Restarts: s.RestartCount}
+		}))
+	}
+
+	if c.watcher == nil {
+		return
+	}
+	deployment, err := c.store.GetLastSuccessfulDeployment(ctx, c.config.RepoName, c.config.AppName)
+	if err != nil {
+		return
+	}
+	if committed, err := c.watcher.CommitTime(deployment.CommitHash); err == nil {
+		c.metrics.SetDeployedCommit(repo, committed)
+	}
+	if pending, err := c.watcher.CommitsSince(deployment.CommitHash); err == nil {
+		c.metrics.SetPendingCommits(repo, pending)
+	}
+}
+
+func (c *Controller) recordDrift(ctx context.Context, result *reconcile.Result) {
+	drift := formatChanges(result.Changes)
+	if drift == c.lastDrift && !result.Reconciled {
+		return
+	}
+	c.lastDrift = lo.Ternary(result.Reconciled, "", drift)
+
+	for _, change := range result.Changes {
+		payload := map[string]string{"action": string(change.Action), "reason": change.Reason}
+		c.recordEvent(ctx, state.EventDriftDetected, change.Service, "", systemActor, payload)
+		if result.Reconciled {
+			c.recordEvent(ctx, state.EventDriftRemediated, change.Service, "", systemActor, payload)
+			c.publish(bus.TypeDriftRemediated, change.Service, "", map[string]any{"action": string(change.Action), "reason": change.Reason})
+		}
+	}
 }
 
-func (c *Controller) handleChange(ctx context.Context, event git.ChangeEvent) {
-	c.logger.Info("git change detected", slog.String("commit", lo.Substring(event.Commit, 0, 8)), slog.String("message", event.Message))
+func (c *Controller) recordEvent(ctx context.Context, eventType state.EventType, service, commit, actor string, payload any) {
+	e := &state.Event{
+		Type:     eventType,
+		RepoName: c.config.RepoName,
+		App:      c.config.AppName,
+		Service:  service,
+		Commit:   commit,
+		Actor:    actor,
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			c.logger.Warn("failed to encode event payload", slog.Any("error", err))
+		}
+		e.Payload = data
+	}
+	if _, err := c.store.RecordEvent(ctx, e); err != nil {
+		c.logger.Warn("failed to record event", slog.String("type", string(eventType)), slog.Any("error", err))
+	}
+}
 
-	if err := c.loadAndReconcile(ctx, event.Commit); err != nil {
+func (c *Controller) HandleChange(ctx context.Context, event git.ChangeEvent) {
+	payload := map[string]string{"message": event.Message}
+	logger := c.logger
+	if event.Tag != "" {
+		payload["tag"] = event.Tag
+		logger = logger.With(slog.String("tag", event.Tag))
+	}
+	logger.Info("git change detected", slog.String("commit", lo.Substring(event.Commit, 0, 8)), slog.String("message", event.Message))
+	c.recordEvent(ctx, state.EventCommitDetected, "", event.Commit, systemActor, payload)
+
+	if err := c.verify(ctx, event.Commit, event.Tag); err != nil {
+		return
+	}
+	if reason := c.skipReason(ctx, event.Paths); reason != "" {
+		c.skipCommit(ctx, event.Commit, reason)
+		return
+	}
+
+	c.deployMu.Lock()
+	err := c.loadAndReconcile(ctx, event.Commit)
+	c.deployMu.Unlock()
+	if err != nil {
 		c.logger.Error("reconcile failed", slog.Any("error", err))
+		return
 	}
+	c.ready.Store(true)
 }
 
-func (c *Controller) loadAndReconcile(ctx context.Context, commit string) error {
-	if err := c.loadProject(ctx, commit); err != nil {
-		return err
+// loadAndReconcile deploys commit. The caller holds deployMu.
+func (c *Controller) loadAndReconcile(ctx context.Context, commit string) (err error) {
+	ctx, span := telemetry.StartSpan(ctx, "Controller.loadAndReconcile", telemetry.AttrRepo.String(c.Name()), telemetry.AttrCommit.String(commit))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if c.defaults != nil {
+		if err := c.reloadConfig(ctx, commit); err != nil {
+			return err
+		}
 	}
 
-	root, err := os.OpenRoot(c.workDir)
+	fingerprint, err := c.loadProject(ctx, commit)
 	if err != nil {
-		return fmt.Errorf("open work directory: %w", err)
+		if errors.Is(err, docker.ErrDecrypt) {
+			c.failDeployment(ctx, commit, err.Error())
+		}
+		return err
 	}
-	defer root.Close()
 
-	composeContent, err := root.ReadFile(c.config.ComposePath)
+	composeContent, err := c.readComposeFile()
 	if err != nil {
-		return fmt.Errorf("read compose file: %w", err)
+		return err
 	}
 
-	deployment, err := c.store.SaveDeployment(ctx, c.config.RepoName, commit, string(composeContent), state.StatusPending, "")
+	deployment, err := c.store.SaveDeployment(ctx, c.config.RepoName, c.config.AppName, commit, composeContent, state.StatusPending, "")
 	if err != nil {
 		c.logger.Warn("failed to save deployment", slog.Any("error", err))
 	}
 
+	c.publish(bus.TypeReconcileStarted, "", commit, nil)
 	start := time.Now()
 	result := c.reconciler.Reconcile(ctx)
 	duration := time.Since(start)
+	if result.Error == nil {
+		c.setEnvFingerprint(fingerprint)
+	}
 
 	var status state.DeploymentStatus
 	var message string
@@ -186,50 +414,311 @@ func (c *Controller) loadAndReconcile(ctx context.Context, commit string) error
 		status, message = state.StatusFailed, result.Error.Error()
 	case result.Reconciled:
 		status = state.StatusSuccess
+	case result.NeedsApproval:
+		status = state.StatusAwaiting
 	default:
 		status, message = state.StatusSkipped, "no changes applied"
 	}
 
 	if c.metrics != nil {
-		c.metrics.RecordDeployment(ctx, c.config.RepoName, string(status))
-		c.metrics.RecordReconciliation(ctx, c.config.RepoName, duration, result.Error == nil)
+		c.metrics.RecordDeployment(ctx, c.Name(), string(status))
+		c.metrics.RecordReconciliation(ctx, c.Name(), duration, result.Error == nil)
+		if status == state.StatusSuccess {
+			c.metrics.RecordSuccessfulReconcile(ctx, c.Name())
+		}
 	}
 
 	if deployment != nil {
-		if err := c.store.UpdateDeploymentStatus(ctx, deployment.ID, status, message); err != nil {
-			c.logger.Warn("failed to update deployment status", slog.Any("error", err))
-		}
+		c.recordResult(ctx, deployment.ID, status, message, result.Changes)
+	}
+	c.collectMetrics(ctx, lo.Ternary(status == state.StatusSuccess, 0, len(result.Changes)))
+
+	span.SetAttributes(attribute.String("kedge.status", string(status)), attribute.Int("kedge.changes", len(result.Changes)))
+
+	finished := map[string]any{"status": string(status), "changes": len(result.Changes), "duration_ms": duration.Milliseconds()}
+	if message != "" {
+		finished["message"] = message
+	}
+	c.publish(bus.TypeReconcileFinished, "", commit, finished)
+
+	switch status {
+	case state.StatusSuccess:
+		c.notify(ctx, notify.EventDeploymentSucceeded, commit, "", nil)
+	case state.StatusFailed:
+		c.notify(ctx, notify.EventDeploymentFailed, commit, message, nil)
+	case state.StatusAwaiting:
+		c.notify(ctx, notify.EventAwaitingApproval, commit, formatChanges(result.Changes), nil)
 	}
 
 	return result.Error
 }
 
-func (c *Controller) loadProject(ctx context.Context, commit string) error {
-	composePath := filepath.Join(c.workDir, c.config.ComposePath)
-	project, err := docker.LoadProject(ctx, composePath, c.config.ProjectName)
+// failDeployment records a commit that could not be deployed before
+// reconciling started. Only the raw compose file is stored.
+func (c *Controller) failDeployment(ctx context.Context, commit, message string) {
+	composeContent, err := c.readComposeFile()
 	if err != nil {
-		return err
+		c.logger.Warn("failed to read compose file", slog.Any("error", err))
+	}
+	if _, err := c.store.SaveDeployment(ctx, c.config.RepoName, c.config.AppName, commit, composeContent, state.StatusFailed, message); err != nil {
+		c.logger.Warn("failed to save deployment", slog.Any("error", err))
+	}
+	if c.metrics != nil {
+		c.metrics.RecordDeployment(ctx, c.Name(), string(state.StatusFailed))
+	}
+	c.publish(bus.TypeReconcileFinished, "", commit, map[string]any{"status": string(state.StatusFailed), "message": message})
+	c.notify(ctx, notify.EventDeploymentFailed, commit, message, nil)
+}
+
+func (c *Controller) notify(ctx context.Context, eventType notify.EventType, commit, message string, services []string) {
+	c.mu.RLock()
+	notifier := c.notifier
+	c.mu.RUnlock()
+	notifier.Notify(ctx, notify.Event{
+		Type:     eventType,
+		Repo:     c.config.RepoName,
+		App:      c.config.AppName,
+		Commit:   commit,
+		Message:  message,
+		Services: services,
+	})
+}
+
+func (c *Controller) recordResult(ctx context.Context, id int64, status state.DeploymentStatus, message string, changes []docker.ServiceDiff) {
+	if status == state.StatusAwaiting {
+		if err := c.store.RequestApproval(ctx, id, formatChanges(changes)); err != nil {
+			c.logger.Warn("failed to queue deployment for approval", slog.Any("error", err))
+			return
+		}
+		c.logger.Info("deployment awaiting approval", slog.Int64("id", id), slog.Int("changes", len(changes)))
+		c.publish(bus.TypeApprovalRequested, "", "", map[string]any{"deployment_id": id, "diff": formatChanges(changes)})
+		return
+	}
+	if err := c.store.UpdateDeploymentStatus(ctx, id, status, message); err != nil {
+		c.logger.Warn("failed to update deployment status", slog.Any("error", err))
+	}
+}
+
+func formatChanges(changes []docker.ServiceDiff) string {
+	lines := lo.Map(changes, func(change docker.ServiceDiff, _ int) string {
+		return fmt.Sprintf("%s: %s (%s)", change.Service, change.Action, change.Reason)
+	})
+	return strings.Join(lines, "\n")
+}
+
+// readComposeFile returns the compose snapshot stored with a deployment. A
+// single file is stored as is; several files are stored as one multi-document
+// YAML file, which compose merges in the same order on rollback.
+func (c *Controller) readComposeFile() (string, error) {
+	root, err := os.OpenRoot(c.workDir)
+	if err != nil {
+		return "", fmt.Errorf("open work directory: %w", err)
+	}
+	defer root.Close()
+
+	cfg := c.currentConfig()
+	if len(cfg.ComposeFiles) == 1 {
+		content, err := root.ReadFile(filepath.Join(cfg.AppPath, cfg.ComposeFiles[0]))
+		if err != nil {
+			return "", fmt.Errorf("read compose file: %w", err)
+		}
+		return string(content), nil
+	}
+
+	var b strings.Builder
+	for _, file := range cfg.ComposeFiles {
+		content, err := root.ReadFile(filepath.Join(cfg.AppPath, file))
+		if err != nil {
+			return "", fmt.Errorf("read compose file: %w", err)
+		}
+		fmt.Fprintf(&b, "---\n# %s\n%s", file, content)
+		if !bytes.HasSuffix(content, []byte("\n")) {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String(), nil
+}
+
+func (c *Controller) composePaths(cfg Config) []string {
+	return lo.Map(cfg.ComposeFiles, func(file string, _ int) string {
+		return filepath.Join(c.workDir, cfg.AppPath, file)
+	})
+}
+
+func (c *Controller) PendingApprovals(ctx context.Context) ([]*state.Deployment, error) {
+	return c.store.ListAwaitingApproval(ctx, c.config.RepoName, c.config.AppName)
+}
+
+func (c *Controller) Approve(ctx context.Context, id int64, approver string) (*state.Deployment, error) {
+	c.deployMu.Lock()
+	defer c.deployMu.Unlock()
+
+	deployment, err := c.awaitingDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyHead(); err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := c.loadProject(ctx, deployment.CommitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	composeContent, err := c.readComposeFile()
+	if err != nil {
+		return nil, err
+	}
+	if composeContent != deployment.ComposeContent {
+		return nil, ErrStaleApproval
+	}
+
+	c.logger.Info("deployment approved", slog.Int64("id", id), slog.String("approver", approver))
+
+	start := time.Now()
+	result := c.reconciler.Sync(ctx)
+	duration := time.Since(start)
+
+	status, message := state.StatusSuccess, "approved by "+approver
+	if result.Error != nil {
+		status, message = state.StatusFailed, result.Error.Error()
+	}
+
+	if c.metrics != nil {
+		c.metrics.RecordDeployment(ctx, c.Name(), string(status))
+		c.metrics.RecordReconciliation(ctx, c.Name(), duration, result.Error == nil)
+		if result.Error == nil {
+			c.metrics.RecordSuccessfulReconcile(ctx, c.Name())
+		}
+	}
+
+	if err := c.store.ReviewDeployment(ctx, id, status, message, approver); err != nil {
+		return nil, err
+	}
+	c.collectMetrics(ctx, lo.Ternary(result.Error == nil, 0, len(result.Changes)))
+	c.recordEvent(ctx, state.EventDeploymentApproved, "", deployment.CommitHash, approver, map[string]any{"deployment_id": id, "status": status})
+
+	if result.Error != nil {
+		c.notify(ctx, notify.EventDeploymentFailed, deployment.CommitHash, message, nil)
+		return nil, result.Error
+	}
+	c.setEnvFingerprint(fingerprint)
+	c.notify(ctx, notify.EventDeploymentSucceeded, deployment.CommitHash, message, nil)
+	return c.store.GetDeployment(ctx, id)
+}
+
+func (c *Controller) Reject(ctx context.Context, id int64, reviewer string) (*state.Deployment, error) {
+	c.deployMu.Lock()
+	defer c.deployMu.Unlock()
+
+	deployment, err := c.awaitingDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyHead(); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("deployment rejected", slog.Int64("id", id), slog.String("reviewer", reviewer))
+
+	if err := c.store.ReviewDeployment(ctx, id, state.StatusSkipped, "rejected by "+reviewer, reviewer); err != nil {
+		return nil, err
+	}
+	c.recordEvent(ctx, state.EventDeploymentRejected, "", deployment.CommitHash, reviewer, map[string]any{"deployment_id": id})
+	return c.store.GetDeployment(ctx, id)
+}
+
+func (c *Controller) awaitingDeployment(ctx context.Context, id int64) (*state.Deployment, error) {
+	deployment, err := c.store.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.RepoName != c.config.RepoName || deployment.App != c.config.AppName {
+		return nil, state.ErrNotFound
+	}
+	if deployment.Status != state.StatusAwaiting {
+		return nil, state.ErrNotAwaiting
+	}
+	return deployment, nil
+}
+
+// loadProject hands the project to the reconciler and returns the
+// fingerprint of the environment it was loaded with.
+func (c *Controller) loadProject(ctx context.Context, commit string) (string, error) {
+	cfg := c.currentConfig()
+	env, fingerprint, err := Environment(ctx, c.store, cfg.RepoName, cfg.KeyFile)
+	if err != nil {
+		return "", fmt.Errorf("load environment: %w", err)
+	}
+	opts := []docker.LoadOption{docker.WithEnvironment(env), docker.WithProfiles(cfg.Profiles...)}
+	if cfg.Preview != "" {
+		opts = append(opts, docker.WithPreview(cfg.PreviewPortOffset))
+	}
+	project, err := docker.LoadProject(ctx, c.composePaths(cfg), cfg.ProjectName, opts...)
+	if err != nil {
+		return "", err
 	}
 	c.reconciler.SetProject(project)
 	c.reconciler.SetCommit(commit)
-	return nil
+	return fingerprint, nil
 }
 
-func (c *Controller) Sync(ctx context.Context) (*reconcile.Result, error) {
-	if err := c.loadProject(ctx, ""); err != nil {
+func (c *Controller) Sync(ctx context.Context, actor string) (*reconcile.Result, error) {
+	c.deployMu.Lock()
+	defer c.deployMu.Unlock()
+
+	if err := c.verifyHead(); err != nil {
+		return nil, err
+	}
+	fingerprint, err := c.loadProject(ctx, "")
+	if err != nil {
 		return nil, err
 	}
-	return c.reconciler.Sync(ctx), nil
+	result := c.reconciler.Sync(ctx)
+	if result.Error == nil {
+		c.setEnvFingerprint(fingerprint)
+	}
+	c.recordSync(ctx, actor, true, result)
+	return result, nil
 }
 
-func (c *Controller) Reconcile(ctx context.Context) (*reconcile.Result, error) {
-	if err := c.loadProject(ctx, ""); err != nil {
+func (c *Controller) Reconcile(ctx context.Context, actor string) (*reconcile.Result, error) {
+	c.deployMu.Lock()
+	defer c.deployMu.Unlock()
+
+	if err := c.verifyHead(); err != nil {
 		return nil, err
 	}
-	return c.reconciler.Reconcile(ctx), nil
+	fingerprint, err := c.loadProject(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	result := c.reconciler.Reconcile(ctx)
+	if result.Error == nil {
+		c.setEnvFingerprint(fingerprint)
+	}
+	c.recordSync(ctx, actor, false, result)
+	return result, nil
+}
+
+func (c *Controller) recordSync(ctx context.Context, actor string, force bool, result *reconcile.Result) {
+	payload := map[string]any{"force": force, "reconciled": result.Reconciled, "changes": len(result.Changes)}
+	if result.Error != nil {
+		payload["error"] = result.Error.Error()
+	}
+	c.recordEvent(ctx, state.EventSync, "", "", actor, payload)
 }
 
 func (c *Controller) Close() error {
+	c.mu.RLock()
+	notifier := c.notifier
+	c.mu.RUnlock()
+	notifier.Close()
+	if c.metrics != nil {
+		c.metrics.ForgetRepo(c.Name())
+	}
+
 	var err error
 	if c.store != nil {
 		err = c.store.Close()
@@ -237,5 +726,8 @@ func (c *Controller) Close() error {
 	if c.client != nil {
 		_ = c.client.Close()
 	}
+	if rmErr := docker.RemoveSecrets(c.ProjectName()); rmErr != nil {
+		c.logger.Warn("failed to remove decrypted files", slog.Any("error", rmErr))
+	}
 	return err
 }
@@ -3,11 +3,13 @@ package controller
 import (
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/LoriKarikari/kedge/internal/git"
 	"github.com/LoriKarikari/kedge/internal/reconcile"
+	"github.com/LoriKarikari/kedge/internal/state"
 )
 
 func TestNew(t *testing.T) {
@@ -27,9 +29,9 @@ func TestNew(t *testing.T) {
 	)
 
 	cfg := Config{
-		ProjectName: "test-project",
-		ComposePath: "docker-compose.yaml",
-		StatePath:   statePath,
+		ProjectName:  "test-project",
+		ComposeFiles: []string{"docker-compose.yaml"},
+		StatePath:    statePath,
 		ReconcileCfg: reconcile.Config{
 			Mode: reconcile.ModeManual,
 		},
@@ -54,3 +56,47 @@ func TestNew(t *testing.T) {
 		t.Error("store not initialized")
 	}
 }
+
+func TestSyncSerializedWithApprove(t *testing.T) {
+	c := newReloadController(t)
+	ctx := t.Context()
+	if _, err := c.store.SaveRepo(ctx, state.RepoSpec{Name: c.config.RepoName, URL: "https://example.com/webapp.git", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	deployment, err := c.store.SaveDeployment(ctx, c.config.RepoName, c.config.AppName, testCommit, "services: [", state.StatusAwaiting, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.workDir, "docker-compose.yaml"), []byte("services: ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			if _, err := c.Sync(ctx, "alice"); err == nil {
+				t.Error("Sync() error = nil with a broken compose file")
+			}
+		})
+		wg.Go(func() {
+			if _, err := c.Approve(ctx, deployment.ID, "bob"); err == nil {
+				t.Error("Approve() error = nil with a broken compose file")
+			}
+		})
+	}
+	wg.Wait()
+
+	c.deployMu.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.Sync(ctx, "alice")
+	}()
+	select {
+	case <-done:
+		t.Fatal("Sync() ran while a deploy was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.deployMu.Unlock()
+	<-done
+}
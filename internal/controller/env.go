@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/secrets"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+// Environment returns a repository's variables with secret values decrypted,
+// and a fingerprint that changes whenever one of them does. The instance's
+// KEDGE_ENV is included unless the repository sets its own.
+func Environment(ctx context.Context, store *state.Store, repoName, keyFile string) (map[string]string, string, error) {
+	vars, err := store.ListEnv(ctx, repoName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	env := make(map[string]string, len(vars)+1)
+	if name := os.Getenv(config.EnvEnvironment); name != "" {
+		env[config.EnvEnvironment] = name
+	}
+	if len(vars) == 0 {
+		return env, "", nil
+	}
+	h := sha256.New()
+	var keys *secrets.Keyring
+	for _, v := range vars {
+		value := v.Value
+		if v.Secret {
+			if keys == nil {
+				if keys, err = secrets.Load(keyFile); err != nil {
+					return nil, "", err
+				}
+			}
+			plaintext, err := keys.Decrypt(v.Value)
+			if err != nil {
+				return nil, "", fmt.Errorf("decrypt %s: %w", v.Key, err)
+			}
+			value = string(plaintext)
+		}
+		env[v.Key] = value
+		fmt.Fprintf(h, "%s=%q\n", v.Key, value)
+	}
+	return env, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// watchEnv redeploys the current commit when the repository's variables
+// change.
+func (c *Controller) watchEnv(ctx context.Context) {
+	for {
+		interval := c.currentConfig().PollInterval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+			c.checkEnv(ctx)
+		}
+	}
+}
+
+// checkEnv redeploys when the variables differ from the last successful
+// deploy, so a failed redeploy is retried on the next check. The check runs
+// under deployMu, so variables applied by a concurrent deploy are not
+// deployed again.
+func (c *Controller) checkEnv(ctx context.Context) {
+	c.deployMu.Lock()
+	defer c.deployMu.Unlock()
+
+	cfg := c.currentConfig()
+	_, fingerprint, err := Environment(ctx, c.store, cfg.RepoName, cfg.KeyFile)
+	if err != nil {
+		c.logger.Warn("failed to load environment", slog.Any("error", err))
+		return
+	}
+
+	c.mu.RLock()
+	changed := fingerprint != c.envFingerprint
+	c.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	c.logger.Info("environment changed, redeploying")
+	if err := c.verifyHead(); err != nil {
+		c.logger.Warn("not redeploying an untrusted commit", slog.Any("error", err))
+		return
+	}
+	if err := c.loadAndReconcile(ctx, c.watcher.LastCommit()); err != nil {
+		c.logger.Error("reconcile failed", slog.Any("error", err))
+	}
+}
+
+func (c *Controller) setEnvFingerprint(fingerprint string) {
+	c.mu.Lock()
+	c.envFingerprint = fingerprint
+	c.mu.Unlock()
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/secrets"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+func TestEnvironment(t *testing.T) {
+	c := newReloadController(t)
+	ctx := t.Context()
+	if _, err := c.store.SaveRepo(ctx, state.RepoSpec{Name: c.config.RepoName, URL: "https://example.com/webapp.git", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "secret.key")
+	keys, err := secrets.LoadOrCreate(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, empty, err := Environment(ctx, c.store, c.config.RepoName, keyFile)
+	if err != nil || len(env) != 0 || empty != "" {
+		t.Fatalf("Environment() = %v, %q, %v", env, empty, err)
+	}
+
+	sealed, err := keys.Encrypt([]byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.store.SetEnv(ctx, c.config.RepoName, "DB_PASSWORD", sealed, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.store.SetEnv(ctx, c.config.RepoName, "TAG", "1.0", false); err != nil {
+		t.Fatal(err)
+	}
+	env, first, err := Environment(ctx, c.store, c.config.RepoName, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["DB_PASSWORD"] != "hunter2" || env["TAG"] != "1.0" {
+		t.Errorf("Environment() = %v", env)
+	}
+
+	if err := c.store.SetEnv(ctx, c.config.RepoName, "TAG", "1.1", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, second, err := Environment(ctx, c.store, c.config.RepoName, keyFile); err != nil || second == first {
+		t.Errorf("fingerprint did not change: %q, %v", second, err)
+	}
+}
+
+func TestCheckEnvRetriesFailedRedeploy(t *testing.T) {
+	c := newReloadController(t)
+	ctx := t.Context()
+	c.watcher = git.NewWatcher(c.workDir, "main", c.workDir, time.Minute, nil)
+	if _, err := c.store.SaveRepo(ctx, state.RepoSpec{Name: c.config.RepoName, URL: "https://example.com/webapp.git", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.store.SetEnv(ctx, c.config.RepoName, "TAG", "1.0", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.workDir, "docker-compose.yaml"), []byte("services: ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c.checkEnv(ctx)
+	if c.envFingerprint != "" {
+		t.Fatal("fingerprint stored although the redeploy failed")
+	}
+
+	if _, err := c.Sync(ctx, "alice"); err == nil {
+		t.Fatal("Sync() error = nil with a broken compose file")
+	}
+	if c.envFingerprint != "" {
+		t.Error("fingerprint stored although the sync failed")
+	}
+
+	if err := os.WriteFile(filepath.Join(c.workDir, "docker-compose.yaml"), []byte("services:\n  web:\n    image: nginx:alpine\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, want, err := Environment(ctx, c.store, c.config.RepoName, c.config.KeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.loadProject(ctx, testCommit); err != nil || got != want {
+		t.Errorf("loadProject() = %q, %v, want the current fingerprint %q", got, err, want)
+	}
+	if c.envFingerprint != "" {
+		t.Error("loadProject() stored the fingerprint before deploying")
+	}
+}
+
+func TestCheckEnvSkipsAppliedEnvironment(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join(t.TempDir(), "keys.txt"))
+	c := newReloadController(t)
+	c.defaults = nil
+	ctx := t.Context()
+	c.watcher = git.NewWatcher(c.workDir, "main", c.workDir, time.Minute, nil)
+	if _, err := c.store.SaveRepo(ctx, state.RepoSpec{Name: c.config.RepoName, URL: "https://example.com/webapp.git", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.store.SetEnv(ctx, c.config.RepoName, "TAG", "1.0", false); err != nil {
+		t.Fatal(err)
+	}
+	// Every redeploy attempt fails to decrypt and records a failed deployment.
+	compose := "services:\n  web:\n    image: nginx:alpine\n    env_file: secrets.env\n"
+	encrypted := "DB_PASSWORD=ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]\n" +
+		"sops_mac=ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]\n"
+	for name, content := range map[string]string{"docker-compose.yaml": compose, "secrets.env": encrypted} {
+		if err := os.WriteFile(filepath.Join(c.workDir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, fingerprint, err := Environment(ctx, c.store, c.config.RepoName, c.config.KeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.deployMu.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.checkEnv(ctx)
+	}()
+	select {
+	case <-done:
+		t.Fatal("checkEnv() ran while a deploy was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.setEnvFingerprint(fingerprint)
+	c.deployMu.Unlock()
+	<-done
+
+	if _, err := c.store.GetLastDeployment(ctx, c.config.RepoName, ""); !errors.Is(err, state.ErrNotFound) {
+		t.Fatalf("GetLastDeployment() error = %v, want no redeploy after a deploy applied the variables", err)
+	}
+
+	c.setEnvFingerprint("")
+	c.checkEnv(ctx)
+	if _, err := c.store.GetLastDeployment(ctx, c.config.RepoName, ""); err != nil {
+		t.Errorf("GetLastDeployment() error = %v, want a redeploy for changed variables", err)
+	}
+}
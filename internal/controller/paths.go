@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/docker"
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/state"
+	"github.com/samber/lo"
+)
+
+func (c *Controller) skipReason(ctx context.Context, changed []string) string {
+	if changed == nil {
+		return ""
+	}
+	cfg := c.currentConfig()
+
+	var watched []string
+	if len(cfg.Paths.Include) == 0 {
+		files, err := c.watchedFiles(ctx, cfg)
+		if err != nil {
+			c.logger.Debug("cannot determine watched files", slog.Any("error", err))
+			return ""
+		}
+		watched = files
+	}
+
+	for _, name := range changed {
+		if c.isRelevant(cfg, name, watched) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("no watched paths changed (%d files changed)", len(changed))
+}
+
+func (c *Controller) isRelevant(cfg Config, name string, watched []string) bool {
+	dir, base := path.Split(name)
+	if slices.Contains(config.RepoFiles, base) && (dir == "" || dir == filepath.ToSlash(cfg.AppPath)+"/") {
+		return true
+	}
+
+	rel := name
+	if cfg.AppPath != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(name, filepath.ToSlash(cfg.AppPath)+"/"); !ok {
+			return slices.Contains(watched, name)
+		}
+	}
+	if matchAny(cfg.Paths.Exclude, rel) {
+		return false
+	}
+	if len(cfg.Paths.Include) > 0 {
+		return matchAny(cfg.Paths.Include, rel)
+	}
+	return slices.Contains(watched, name)
+}
+
+func (c *Controller) watchedFiles(ctx context.Context, cfg Config) ([]string, error) {
+	project, err := docker.LoadProject(ctx, c.composePaths(cfg), cfg.ProjectName, docker.WithProfiles(cfg.Profiles...), docker.WithoutDecryption())
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(c.workDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range docker.ReferencedFiles(project) {
+		rel, err := filepath.Rel(root, file)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		files = append(files, filepath.ToSlash(rel))
+	}
+	return files, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	return lo.SomeBy(patterns, func(pattern string) bool {
+		ok, _ := git.MatchPath(pattern, name)
+		return ok
+	})
+}
+
+func (c *Controller) skipCommit(ctx context.Context, commit, reason string) {
+	c.deployMu.Lock()
+	defer c.deployMu.Unlock()
+
+	c.logger.Info("commit skipped", slog.String("commit", lo.Substring(commit, 0, 8)), slog.String("reason", reason))
+
+	composeContent, err := c.readComposeFile()
+	if err != nil {
+		c.logger.Warn("failed to read compose file", slog.Any("error", err))
+	}
+	if _, err := c.store.SaveDeployment(ctx, c.config.RepoName, c.config.AppName, commit, composeContent, state.StatusSkipped, reason); err != nil {
+		c.logger.Warn("failed to save deployment", slog.Any("error", err))
+	}
+	c.publish(bus.TypeCommitSkipped, "", commit, map[string]any{"reason": reason})
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/docker"
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+func TestSkipReason(t *testing.T) {
+	c := newReloadController(t)
+	c.defaults = nil
+	if _, err := c.store.SaveRepo(t.Context(), state.RepoSpec{Name: c.config.RepoName, URL: "https://example.com/webapp.git", Branch: "main"}); err != nil {
+		t.Fatalf("SaveRepo() error = %v", err)
+	}
+	compose := "services:\n  web:\n    image: nginx:alpine\n    env_file: web.env\n"
+	if err := os.WriteFile(filepath.Join(c.workDir, "docker-compose.yaml"), []byte(compose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.workDir, "web.env"), []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.workDir, ".env"), []byte("TAG=1.27\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		paths   config.Paths
+		changed []string
+		skip    bool
+	}{
+		{"unknown", config.Paths{}, nil, false},
+		{"docs only", config.Paths{}, []string{"README.md", "docs/intro.md"}, true},
+		{"compose file", config.Paths{}, []string{"README.md", "docker-compose.yaml"}, false},
+		{"env file", config.Paths{}, []string{"web.env"}, false},
+		{"dotenv", config.Paths{}, []string{".env"}, false},
+		{"nested dotenv", config.Paths{}, []string{"docs/.env"}, true},
+		{"kedge.yaml", config.Paths{}, []string{"kedge.yaml"}, false},
+		{"include", config.Paths{Include: []string{"config/**"}}, []string{"config/nginx/app.conf"}, false},
+		{"include replaces defaults", config.Paths{Include: []string{"config/**"}}, []string{"docker-compose.yaml"}, true},
+		{"exclude", config.Paths{Exclude: []string{"*.env"}}, []string{"web.env"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.config.Paths = tt.paths
+			if got := c.skipReason(t.Context(), tt.changed); (got != "") != tt.skip {
+				t.Errorf("skipReason(%v) = %q, want skip %v", tt.changed, got, tt.skip)
+			}
+		})
+	}
+}
+
+func TestSkipReasonApp(t *testing.T) {
+	c := newReloadController(t)
+	appDir := filepath.Join(c.workDir, "stacks", "web")
+	if err := os.MkdirAll(appDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(c.workDir, "docker-compose.yaml"), filepath.Join(appDir, "docker-compose.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	c.config.AppName, c.config.AppPath = "web", filepath.Join("stacks", "web")
+
+	if c.skipReason(t.Context(), []string{"stacks/api/docker-compose.yaml"}) == "" {
+		t.Error("expected change to another app to be skipped")
+	}
+	if c.skipReason(t.Context(), []string{"stacks/web/docker-compose.yaml"}) != "" {
+		t.Error("expected change to the app's compose file to deploy")
+	}
+	if c.skipReason(t.Context(), []string{"kedge.yaml"}) != "" {
+		t.Error("expected change to the root kedge.yaml to deploy")
+	}
+}
+
+func TestHandleChangeSkipped(t *testing.T) {
+	c := newReloadController(t)
+	if _, err := c.store.SaveRepo(t.Context(), state.RepoSpec{Name: c.config.RepoName, URL: "https://example.com/webapp.git", Branch: "main"}); err != nil {
+		t.Fatalf("SaveRepo() error = %v", err)
+	}
+
+	c.HandleChange(t.Context(), git.ChangeEvent{Commit: testCommit, Paths: []string{"README.md"}})
+
+	deployment, err := c.store.GetLastDeployment(t.Context(), c.config.RepoName, "")
+	if err != nil {
+		t.Fatalf("GetLastDeployment() error = %v", err)
+	}
+	if deployment.Status != state.StatusSkipped || deployment.CommitHash != testCommit || deployment.Message == "" {
+		t.Errorf("unexpected deployment %+v", deployment)
+	}
+}
+
+func TestLoadAndReconcileDecryptFailure(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join(t.TempDir(), "keys.txt"))
+	c := newReloadController(t)
+	c.defaults = nil
+	if _, err := c.store.SaveRepo(t.Context(), state.RepoSpec{Name: c.config.RepoName, URL: "https://example.com/webapp.git", Branch: "main"}); err != nil {
+		t.Fatalf("SaveRepo() error = %v", err)
+	}
+	compose := "services:\n  web:\n    image: nginx:alpine\n    env_file: secrets.env\n"
+	encrypted := "DB_PASSWORD=ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]\n" +
+		"sops_mac=ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]\n"
+	for name, content := range map[string]string{"docker-compose.yaml": compose, "secrets.env": encrypted} {
+		if err := os.WriteFile(filepath.Join(c.workDir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.deployMu.Lock()
+	err := c.loadAndReconcile(t.Context(), testCommit)
+	c.deployMu.Unlock()
+	if !errors.Is(err, docker.ErrDecrypt) {
+		t.Fatalf("loadAndReconcile() error = %v, want ErrDecrypt", err)
+	}
+
+	deployment, err := c.store.GetLastDeployment(t.Context(), c.config.RepoName, "")
+	if err != nil {
+		t.Fatalf("GetLastDeployment() error = %v", err)
+	}
+	if deployment.Status != state.StatusFailed || !strings.Contains(deployment.Message, "secrets.env") {
+		t.Errorf("unexpected deployment %+v", deployment)
+	}
+	if deployment.ComposeContent != compose {
+		t.Errorf("ComposeContent = %q, want the raw compose file", deployment.ComposeContent)
+	}
+}
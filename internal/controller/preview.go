@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"strings"
+)
+
+const maxPreviewSlug = 40
+
+// PreviewProjectName derives the compose project of a preview environment
+// from the repository's project and the branch, e.g. "web" and
+// "preview/Login-Form" become "web-preview-login-form".
+func PreviewProjectName(project, branch string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(branch) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > maxPreviewSlug {
+		slug = strings.TrimRight(slug[:maxPreviewSlug], "-")
+	}
+	return project + "-" + slug
+}
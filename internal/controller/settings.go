@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/logging"
+	"github.com/LoriKarikari/kedge/internal/notify"
+	"github.com/LoriKarikari/kedge/internal/reconcile"
+	"github.com/LoriKarikari/kedge/internal/state"
+	"github.com/LoriKarikari/kedge/internal/validate"
+	"github.com/samber/lo"
+)
+
+var ErrInvalidRepoConfig = errors.New("invalid repository config")
+
+func WithReload(defaults Config) Option {
+	return func(c *Controller) {
+		c.defaults = &defaults
+	}
+}
+
+func LoadRepoConfig(workDir string, defaults Config) (Config, error) {
+	path, err := config.FindRepo(workDir)
+	if err != nil {
+		return defaults, err
+	}
+
+	var skip []string
+	if defaults.isApp() {
+		skip = []string{"docker.project_name", "docker.compose_file", "docker.compose_files", "git.paths.include", "git.paths.exclude"}
+	}
+	cfg, manifest, err := applyRepoFile(path, defaults, skip)
+	if err != nil {
+		return defaults, err
+	}
+
+	if !defaults.isApp() {
+		if len(manifest.Apps) > 0 {
+			return defaults, fmt.Errorf("%w: apps cannot be added without a restart", ErrInvalidRepoConfig)
+		}
+	} else {
+		apps, err := config.ResolveApps(workDir, manifest.Apps)
+		if err != nil {
+			return defaults, fmt.Errorf("%w: %w", ErrInvalidRepoConfig, err)
+		}
+		app, err := config.FindApp(apps, defaults.AppName)
+		if err != nil || app.Path != defaults.AppPath {
+			return defaults, fmt.Errorf("%w: app %q was removed or moved; restart to apply", ErrInvalidRepoConfig, defaults.AppName)
+		}
+		appPath, err := config.FindRepo(filepath.Join(workDir, app.Path))
+		if err != nil {
+			return defaults, fmt.Errorf("app %s: %w", app.Name, err)
+		}
+		if cfg, _, err = applyRepoFile(appPath, cfg, []string{"git.poll_interval"}); err != nil {
+			return defaults, err
+		}
+	}
+
+	if cfg.Preview != "" {
+		cfg.ProjectName = PreviewProjectName(cfg.ProjectName, cfg.Preview)
+	}
+
+	if err := validateRepoConfig(cfg); err != nil {
+		return defaults, fmt.Errorf("%w: %w", ErrInvalidRepoConfig, err)
+	}
+	return cfg, nil
+}
+
+func (cfg Config) isApp() bool {
+	return cfg.AppName != "" && cfg.Preview == ""
+}
+
+func applyRepoFile(path string, cfg Config, skip []string) (Config, *config.Config, error) {
+	loader := config.NewLoader()
+	if err := loader.LoadFile(path); err != nil {
+		return cfg, nil, fmt.Errorf("%w: %w", ErrInvalidRepoConfig, err)
+	}
+	repoCfg := loader.Config()
+	isSet := func(key string) bool {
+		return loader.IsSet(key) && !lo.Contains(skip, key)
+	}
+
+	if isSet("docker.project_name") {
+		cfg.ProjectName = repoCfg.Docker.ProjectName
+	}
+	if isSet("docker.compose_file") || isSet("docker.compose_files") {
+		cfg.ComposeFiles = repoCfg.Docker.Files()
+	}
+	if isSet("docker.profiles") {
+		cfg.Profiles = repoCfg.Docker.Profiles
+	}
+	if isSet("git.poll_interval") {
+		cfg.PollInterval = repoCfg.Git.PollInterval
+	}
+	if isSet("git.paths.include") {
+		cfg.Paths.Include = repoCfg.Git.Paths.Include
+	}
+	if isSet("git.paths.exclude") {
+		cfg.Paths.Exclude = repoCfg.Git.Paths.Exclude
+	}
+	if isSet("reconciliation.interval") {
+		cfg.ReconcileCfg.Interval = repoCfg.Reconciliation.Interval
+	}
+	if isSet("reconciliation.mode") {
+		mode, err := reconcile.ParseMode(repoCfg.Reconciliation.Mode)
+		if err != nil {
+			return cfg, nil, fmt.Errorf("%w: reconciliation.mode: %w", ErrInvalidRepoConfig, err)
+		}
+		cfg.ReconcileCfg.Mode = mode
+	}
+	if isSet("logging.level") {
+		cfg.LogLevel = repoCfg.Logging.Level
+	}
+	if isSet("notifications") {
+		cfg.Notifications = repoCfg.Notifications
+	}
+	return cfg, repoCfg, nil
+}
+
+func validateRepoConfig(cfg Config) error {
+	switch {
+	case cfg.ProjectName == "":
+		return errors.New("docker.project_name must not be empty")
+	case len(cfg.ComposeFiles) == 0:
+		return errors.New("docker.compose_file must not be empty")
+	case cfg.PollInterval < 0:
+		return fmt.Errorf("git.poll_interval must be positive: %s", cfg.PollInterval)
+	case cfg.ReconcileCfg.Interval < 0:
+		return fmt.Errorf("reconciliation.interval must be positive: %s", cfg.ReconcileCfg.Interval)
+	}
+	for _, file := range cfg.ComposeFiles {
+		switch {
+		case file == "":
+			return errors.New("docker.compose_file must not be empty")
+		case filepath.IsAbs(file):
+			return fmt.Errorf("docker.compose_file must be relative: %s", file)
+		}
+	}
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("logging.level: %w: %q", err, cfg.LogLevel)
+	}
+	for _, pattern := range slices.Concat(cfg.Paths.Include, cfg.Paths.Exclude) {
+		if err := git.ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("git.paths: %w: %q", err, pattern)
+		}
+	}
+	return nil
+}
+
+func changedKeys(current, next Config) []string {
+	var keys []string
+	if !slices.Equal(current.ComposeFiles, next.ComposeFiles) {
+		keys = append(keys, "docker.compose_files")
+	}
+	if !slices.Equal(current.Profiles, next.Profiles) {
+		keys = append(keys, "docker.profiles")
+	}
+	if current.PollInterval != next.PollInterval {
+		keys = append(keys, "git.poll_interval")
+	}
+	if !reflect.DeepEqual(current.Paths, next.Paths) {
+		keys = append(keys, "git.paths")
+	}
+	if current.ReconcileCfg.Mode != next.ReconcileCfg.Mode {
+		keys = append(keys, "reconciliation.mode")
+	}
+	if current.ReconcileCfg.Interval != next.ReconcileCfg.Interval {
+		keys = append(keys, "reconciliation.interval")
+	}
+	if current.LogLevel != next.LogLevel {
+		keys = append(keys, "logging.level")
+	}
+	if !reflect.DeepEqual(current.Notifications, next.Notifications) {
+		keys = append(keys, "notifications")
+	}
+	return keys
+}
+
+func (c *Controller) reloadConfig(ctx context.Context, commit string) error {
+	current := c.currentConfig()
+	next := current
+	err := c.checkRepo(ctx)
+	if err == nil {
+		next, err = LoadRepoConfig(c.workDir, *c.defaults)
+	}
+	if err == nil && next.ProjectName != current.ProjectName {
+		err = fmt.Errorf("%w: docker.project_name cannot change without a restart", ErrInvalidRepoConfig)
+	}
+
+	keys := changedKeys(current, next)
+	var notifier *notify.Notifier
+	if err == nil && lo.Contains(keys, "notifications") {
+		if notifier, err = notify.New(next.Notifications, c.logger); err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidRepoConfig, err)
+		}
+	}
+
+	if err != nil {
+		c.rejectConfig(ctx, commit, err)
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	c.applyConfig(next, notifier)
+	c.logger.Info("repository config reloaded", slog.String("commit", lo.Substring(commit, 0, 8)), slog.Any("changed", keys))
+	c.recordEvent(ctx, state.EventConfigReloaded, "", commit, systemActor, map[string]any{"changed": keys})
+	c.publish(bus.TypeConfigReloaded, "", commit, map[string]any{"changed": keys})
+	return nil
+}
+
+func (c *Controller) checkRepo(ctx context.Context) error {
+	path, err := config.FindRepo(c.workDir)
+	if err != nil {
+		return err
+	}
+	var diags []validate.Diagnostic
+	if cfg := c.currentConfig(); cfg.isApp() {
+		diags, err = validate.App(ctx, path, config.App{Name: cfg.AppName, Path: cfg.AppPath})
+	} else {
+		diags, err = validate.Repo(ctx, path)
+	}
+	if err != nil {
+		return err
+	}
+	for _, d := range validate.Warnings(diags) {
+		c.logger.Warn("repository config warning", slog.String("diagnostic", d.String()))
+	}
+	if errs := validate.Errors(diags); len(errs) > 0 {
+		messages := lo.Map(errs, func(d validate.Diagnostic, _ int) string { return d.String() })
+		return fmt.Errorf("%w: %s", ErrInvalidRepoConfig, strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+func (c *Controller) applyConfig(next Config, notifier *notify.Notifier) {
+	c.mu.Lock()
+	c.config.ComposeFiles = next.ComposeFiles
+	c.config.Profiles = next.Profiles
+	c.config.PollInterval = next.PollInterval
+	c.config.Paths = next.Paths
+	c.config.ReconcileCfg = next.ReconcileCfg
+	c.config.LogLevel = next.LogLevel
+	c.config.Notifications = next.Notifications
+	previous := c.notifier
+	if notifier != nil {
+		c.notifier = notifier
+	}
+	c.mu.Unlock()
+
+	if notifier != nil {
+		previous.Close()
+	}
+	c.setLogLevel(next.LogLevel)
+	c.reconciler.SetConfig(next.ReconcileCfg)
+	if c.watcher != nil {
+		c.watcher.SetPollInterval(next.PollInterval)
+	}
+}
+
+func (c *Controller) rejectConfig(ctx context.Context, commit string, err error) {
+	c.logger.Error("repository config rejected", slog.String("commit", lo.Substring(commit, 0, 8)), slog.Any("error", err))
+	c.recordEvent(ctx, state.EventConfigRejected, "", commit, systemActor, map[string]string{"error": err.Error()})
+	c.publish(bus.TypeConfigRejected, "", commit, map[string]any{"error": err.Error()})
+	c.notify(ctx, notify.EventDeploymentFailed, commit, err.Error(), nil)
+}
+
+func (c *Controller) setLogLevel(level string) {
+	parsed, err := logging.ParseLevel(level)
+	if err != nil {
+		return
+	}
+	c.logLevel.Set(parsed)
+}
+
+func (c *Controller) currentConfig() Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
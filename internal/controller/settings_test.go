@@ -0,0 +1,298 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/reconcile"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+const testCommit = "0123456789abcdef"
+
+func testDefaults() Config {
+	return Config{
+		RepoName:     "webapp",
+		ProjectName:  "kedge",
+		ComposeFiles: []string{"docker-compose.yaml"},
+		PollInterval: time.Minute,
+		ReconcileCfg: reconcile.Config{Mode: reconcile.ModeAuto, Interval: time.Minute},
+		LogLevel:     "info",
+	}
+}
+
+func writeRepoConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "kedge.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatalf("write kedge.yaml: %v", err)
+	}
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoConfig(t, dir, `
+git:
+  poll_interval: 15s
+docker:
+  project_name: webapp
+reconciliation:
+  mode: manual
+logging:
+  level: debug
+`)
+
+	cfg, err := LoadRepoConfig(dir, testDefaults())
+	if err != nil {
+		t.Fatalf("LoadRepoConfig() error = %v", err)
+	}
+
+	if cfg.PollInterval != 15*time.Second {
+		t.Errorf("PollInterval = %v, want 15s", cfg.PollInterval)
+	}
+	if cfg.ProjectName != "webapp" {
+		t.Errorf("ProjectName = %q, want webapp", cfg.ProjectName)
+	}
+	if cfg.ReconcileCfg.Mode != reconcile.ModeManual {
+		t.Errorf("Mode = %q, want manual", cfg.ReconcileCfg.Mode)
+	}
+	if cfg.ReconcileCfg.Interval != time.Minute {
+		t.Errorf("Interval = %v, want inherited 1m", cfg.ReconcileCfg.Interval)
+	}
+	if !slices.Equal(cfg.ComposeFiles, []string{"docker-compose.yaml"}) {
+		t.Errorf("ComposeFiles = %q, want inherited default", cfg.ComposeFiles)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want debug", cfg.LogLevel)
+	}
+}
+
+func TestLoadRepoConfigInvalid(t *testing.T) {
+	tests := map[string]string{
+		"mode":         "reconciliation:\n  mode: sometimes\n",
+		"compose file": "docker:\n  compose_file: /etc/compose.yaml\n",
+		"interval":     "reconciliation:\n  interval: -1s\n",
+		"log level":    "logging:\n  level: loud\n",
+		"yaml":         "git: [\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeRepoConfig(t, dir, content)
+
+			if _, err := LoadRepoConfig(dir, testDefaults()); !errors.Is(err, ErrInvalidRepoConfig) {
+				t.Errorf("expected ErrInvalidRepoConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadRepoConfigMissing(t *testing.T) {
+	if _, err := LoadRepoConfig(t.TempDir(), testDefaults()); !errors.Is(err, config.ErrRepoConfigNotFound) {
+		t.Errorf("expected ErrRepoConfigNotFound, got %v", err)
+	}
+}
+
+func newReloadController(t *testing.T) *Controller {
+	t.Helper()
+	store, err := state.New(t.Context(), filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	workDir := t.TempDir()
+	compose := "services:\n  web:\n    image: nginx:alpine\n"
+	if err := os.WriteFile(filepath.Join(workDir, "docker-compose.yaml"), []byte(compose), 0o600); err != nil {
+		t.Fatalf("write compose file: %v", err)
+	}
+
+	defaults := testDefaults()
+	return &Controller{
+		store:      store,
+		config:     defaults,
+		defaults:   &defaults,
+		workDir:    workDir,
+		logger:     slog.New(slog.DiscardHandler),
+		reconciler: reconcile.New(nil, nil, defaults.ReconcileCfg, nil),
+	}
+}
+
+func TestReloadConfigApplies(t *testing.T) {
+	c := newReloadController(t)
+	writeRepoConfig(t, c.workDir, "reconciliation:\n  mode: notify\n  interval: 5m\nlogging:\n  level: warn\n")
+
+	if err := c.reloadConfig(t.Context(), testCommit); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+
+	if got := c.reconciler.Config(); got.Mode != reconcile.ModeNotify || got.Interval != 5*time.Minute {
+		t.Errorf("reconciler config = %+v, want notify every 5m", got)
+	}
+	if got := c.currentConfig().LogLevel; got != "warn" {
+		t.Errorf("LogLevel = %q, want warn", got)
+	}
+	if got := c.logLevel.Level().String(); got != "WARN" {
+		t.Errorf("log level = %s, want WARN", got)
+	}
+
+	events, err := c.store.ListEvents(t.Context(), state.EventFilter{Types: []string{string(state.EventConfigReloaded)}})
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Commit != testCommit {
+		t.Errorf("expected one config_reloaded event for %s, got %d", testCommit, len(events))
+	}
+}
+
+func TestReloadConfigRejects(t *testing.T) {
+	c := newReloadController(t)
+	writeRepoConfig(t, c.workDir, "reconciliation:\n  mode: sometimes\n")
+
+	if err := c.reloadConfig(t.Context(), testCommit); !errors.Is(err, ErrInvalidRepoConfig) {
+		t.Fatalf("expected ErrInvalidRepoConfig, got %v", err)
+	}
+	if got := c.reconciler.Config().Mode; got != reconcile.ModeAuto {
+		t.Errorf("mode = %q, want previous auto", got)
+	}
+
+	writeRepoConfig(t, c.workDir, "docker:\n  project_name: renamed\n")
+	if err := c.reloadConfig(t.Context(), testCommit); !errors.Is(err, ErrInvalidRepoConfig) {
+		t.Errorf("expected project_name change to be rejected, got %v", err)
+	}
+
+	writeRepoConfig(t, c.workDir, "reconciliation:\n  mod: manual\n")
+	if err := c.reloadConfig(t.Context(), testCommit); !errors.Is(err, ErrInvalidRepoConfig) {
+		t.Errorf("expected unknown key to be rejected, got %v", err)
+	}
+
+	events, err := c.store.ListEvents(t.Context(), state.EventFilter{Types: []string{string(state.EventConfigRejected)}})
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("expected three config_rejected events, got %d", len(events))
+	}
+}
+
+func TestLoadAppConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoConfig(t, dir, `
+apps:
+  - path: stacks/*
+reconciliation:
+  mode: manual
+docker:
+  project_name: ignored
+`)
+	for _, name := range []string{"grafana", "loki"} {
+		appDir := filepath.Join(dir, "stacks", name)
+		if err := os.MkdirAll(appDir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		writeRepoConfig(t, appDir, "logging:\n  level: debug\n")
+	}
+	writeRepoConfig(t, filepath.Join(dir, "stacks", "loki"), "git:\n  poll_interval: 5s\ndocker:\n  compose_file: compose.yaml\n")
+
+	defaults := testDefaults()
+	defaults.AppName, defaults.AppPath, defaults.ProjectName = "grafana", "stacks/grafana", "grafana"
+	cfg, err := LoadRepoConfig(dir, defaults)
+	if err != nil {
+		t.Fatalf("LoadRepoConfig() error = %v", err)
+	}
+	if cfg.ProjectName != "grafana" {
+		t.Errorf("ProjectName = %q, want app name", cfg.ProjectName)
+	}
+	if cfg.ReconcileCfg.Mode != reconcile.ModeManual {
+		t.Errorf("Mode = %q, want manual from the root", cfg.ReconcileCfg.Mode)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want debug from the app", cfg.LogLevel)
+	}
+
+	defaults.AppName, defaults.AppPath, defaults.ProjectName = "loki", "stacks/loki", "loki"
+	cfg, err = LoadRepoConfig(dir, defaults)
+	if err != nil {
+		t.Fatalf("LoadRepoConfig() error = %v", err)
+	}
+	if !slices.Equal(cfg.ComposeFiles, []string{"compose.yaml"}) || cfg.PollInterval != time.Minute {
+		t.Errorf("loki config = %+v, want its own compose file and the shared poll interval", cfg)
+	}
+
+	defaults.AppName, defaults.AppPath = "tempo", "stacks/tempo"
+	if _, err := LoadRepoConfig(dir, defaults); !errors.Is(err, ErrInvalidRepoConfig) {
+		t.Errorf("expected removed app to be rejected, got %v", err)
+	}
+
+	if _, err := LoadRepoConfig(dir, testDefaults()); !errors.Is(err, ErrInvalidRepoConfig) {
+		t.Errorf("expected apps to be rejected for a single-app controller, got %v", err)
+	}
+}
+
+func TestLoadRepoConfigPreview(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoConfig(t, dir, `
+docker:
+  project_name: webapp
+`)
+	defaults := testDefaults()
+	defaults.AppName = state.PreviewApp("preview/Login-Form")
+	defaults.Preview = "preview/Login-Form"
+
+	cfg, err := LoadRepoConfig(dir, defaults)
+	if err != nil {
+		t.Fatalf("LoadRepoConfig() error = %v", err)
+	}
+	if cfg.ProjectName != "webapp-preview-login-form" {
+		t.Errorf("ProjectName = %q, want webapp-preview-login-form", cfg.ProjectName)
+	}
+
+	again, err := LoadRepoConfig(dir, defaults)
+	if err != nil || again.ProjectName != cfg.ProjectName {
+		t.Errorf("reloaded ProjectName = %q, %v; want it stable", again.ProjectName, err)
+	}
+}
+
+func TestLoadRepoConfigOverlays(t *testing.T) {
+	t.Setenv(config.EnvEnvironment, "staging")
+	c := newReloadController(t)
+	writeRepoConfig(t, c.workDir, `
+docker:
+  compose_files: [docker-compose.yaml, "overrides/${KEDGE_ENV}.yaml"]
+  profiles: [metrics]
+`)
+	if err := os.MkdirAll(filepath.Join(c.workDir, "overrides"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.workDir, "overrides", "staging.yaml"), []byte("services:\n  web:\n    image: nginx:${KEDGE_ENV}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadRepoConfig(c.workDir, *c.defaults)
+	if err != nil {
+		t.Fatalf("LoadRepoConfig() error = %v", err)
+	}
+	if !slices.Equal(cfg.ComposeFiles, []string{"docker-compose.yaml", "overrides/staging.yaml"}) || !slices.Equal(cfg.Profiles, []string{"metrics"}) {
+		t.Fatalf("ComposeFiles = %q, Profiles = %q", cfg.ComposeFiles, cfg.Profiles)
+	}
+
+	c.applyConfig(cfg, nil)
+	if _, err := c.loadProject(t.Context(), testCommit); err != nil {
+		t.Fatalf("loadProject() error = %v", err)
+	}
+	snapshot, err := c.readComposeFile()
+	if err != nil {
+		t.Fatalf("readComposeFile() error = %v", err)
+	}
+	want := "---\n# docker-compose.yaml\nservices:\n  web:\n    image: nginx:alpine\n" +
+		"---\n# overrides/staging.yaml\nservices:\n  web:\n    image: nginx:${KEDGE_ENV}\n"
+	if snapshot != want {
+		t.Errorf("snapshot = %q, want %q", snapshot, want)
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/notify"
+	"github.com/LoriKarikari/kedge/internal/state"
+	"github.com/samber/lo"
+)
+
+var ErrUntrustedCommit = errors.New("commit rejected")
+
+func (c *Controller) verify(ctx context.Context, commit, tag string) error {
+	if err := c.checkTrust(commit, tag); err != nil {
+		c.reject(ctx, commit, tag, err.Error())
+		return fmt.Errorf("%w: %w", ErrUntrustedCommit, err)
+	}
+	return nil
+}
+
+// verifyHead checks the commit checked out in the work directory, which is
+// what Sync, Reconcile and Approve deploy. The commit was already rejected
+// when it arrived, so a failure is only returned to the caller.
+func (c *Controller) verifyHead() error {
+	if c.currentConfig().TrustKeys == nil {
+		return nil
+	}
+	if c.watcher == nil {
+		return fmt.Errorf("%w: no checkout to verify", ErrUntrustedCommit)
+	}
+	if err := c.checkTrust(c.watcher.LastCommit(), c.watcher.CurrentTag()); err != nil {
+		return fmt.Errorf("%w: %w", ErrUntrustedCommit, err)
+	}
+	return nil
+}
+
+func (c *Controller) checkTrust(commit, tag string) error {
+	cfg := c.currentConfig()
+	if cfg.TrustKeys == nil || c.watcher == nil {
+		return nil
+	}
+
+	var signer string
+	var err error
+	if cfg.RequireSignedTags && tag != "" {
+		signer, err = c.watcher.VerifyTag(tag, cfg.TrustKeys)
+	} else {
+		signer, err = c.watcher.VerifyCommit(commit, cfg.TrustKeys)
+	}
+	if err != nil {
+		return err
+	}
+	c.logger.Debug("signature verified", slog.String("commit", lo.Substring(commit, 0, 8)), slog.String("signer", signer))
+	return nil
+}
+
+func (c *Controller) reject(ctx context.Context, commit, tag, reason string) {
+	c.deployMu.Lock()
+	defer c.deployMu.Unlock()
+
+	c.logger.Warn("commit rejected", slog.String("commit", lo.Substring(commit, 0, 8)), slog.String("reason", reason))
+
+	if _, err := c.store.SaveDeployment(ctx, c.config.RepoName, c.config.AppName, commit, "", state.StatusRejected, reason); err != nil {
+		c.logger.Warn("failed to save deployment", slog.Any("error", err))
+	}
+	data := map[string]any{"reason": reason}
+	if tag != "" {
+		data["tag"] = tag
+	}
+	c.publish(bus.TypeCommitRejected, "", commit, data)
+	c.notify(ctx, notify.EventCommitRejected, commit, reason, nil)
+}
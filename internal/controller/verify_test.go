@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+func TestHandleChangeRejected(t *testing.T) {
+	origin := t.TempDir()
+	repo, err := gogit.PlainInit(origin, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(origin, "docker-compose.yaml"), []byte("services: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("docker-compose.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := wt.Commit("unsigned", &gogit.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := git.ParseKeyring(ssh.MarshalAuthorizedKey(sshPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newReloadController(t)
+	c.watcher = git.NewWatcher(origin, "master", filepath.Join(t.TempDir(), "work"), time.Second, nil)
+	if err := c.watcher.Clone(t.Context()); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	c.workDir = c.watcher.WorkDir()
+	c.config.TrustKeys = keys
+	if _, err := c.store.SaveRepo(t.Context(), state.RepoSpec{Name: c.config.RepoName, URL: origin, Branch: "master"}); err != nil {
+		t.Fatalf("SaveRepo() error = %v", err)
+	}
+
+	c.HandleChange(t.Context(), git.ChangeEvent{Commit: hash.String()})
+
+	deployment, err := c.store.GetLastDeployment(t.Context(), c.config.RepoName, "")
+	if err != nil {
+		t.Fatalf("GetLastDeployment() error = %v", err)
+	}
+	if deployment.Status != state.StatusRejected || deployment.CommitHash != hash.String() || deployment.Message == "" {
+		t.Errorf("unexpected deployment %+v", deployment)
+	}
+
+	if _, err := c.Sync(t.Context(), "alice"); !errors.Is(err, ErrUntrustedCommit) {
+		t.Errorf("Sync() error = %v, want ErrUntrustedCommit", err)
+	}
+	if _, err := c.Reconcile(t.Context(), "alice"); !errors.Is(err, ErrUntrustedCommit) {
+		t.Errorf("Reconcile() error = %v, want ErrUntrustedCommit", err)
+	}
+	if err := c.Start(t.Context()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if c.IsReady() {
+		t.Error("controller is ready with a rejected commit checked out")
+	}
+}
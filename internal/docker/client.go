@@ -7,15 +7,51 @@ import (
 	"time"
 
 	"github.com/docker/docker/client"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
 type Client struct {
 	cli         *client.Client
 	logger      *slog.Logger
 	projectName string
+	bus         *bus.Bus
+	repoName    string
+	appName     string
+	metrics     *telemetry.Metrics
+}
+
+type ClientOption func(*Client)
+
+func WithRepoName(name string) ClientOption {
+	return func(c *Client) {
+		c.repoName = name
+	}
 }
 
-func NewClient(projectName string, logger *slog.Logger) (*Client, error) {
+func WithAppName(name string) ClientOption {
+	return func(c *Client) {
+		c.appName = name
+	}
+}
+
+func WithEventBus(b *bus.Bus) ClientOption {
+	return func(c *Client) {
+		c.bus = b
+	}
+}
+
+func WithMetrics(m *telemetry.Metrics) ClientOption {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+func NewClient(projectName string, logger *slog.Logger, opts ...ClientOption) (*Client, error) {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -35,11 +71,31 @@ func NewClient(projectName string, logger *slog.Logger) (*Client, error) {
 	}
 
 	logger.Info("docker client initialized")
-	return &Client{
+	c := &Client{
 		cli:         cli,
 		logger:      logger,
 		projectName: projectName,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func (c *Client) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, telemetry.AttrRepo.String(telemetry.RepoLabel(c.repoName, c.appName)), telemetry.AttrProject.String(c.projectName))
+	return telemetry.StartSpan(ctx, name, attrs...)
+}
+
+func (c *Client) publish(eventType, service, commit string, data map[string]any) {
+	c.bus.Publish(bus.Event{
+		Type:    eventType,
+		Repo:    c.repoName,
+		App:     c.appName,
+		Service: service,
+		Commit:  commit,
+		Data:    data,
+	})
 }
 
 func (c *Client) Close() error {
@@ -1,9 +1,12 @@
 package docker
 
 import (
+	"archive/tar"
 	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -17,6 +20,29 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestReadPullProgress(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"status":"Pulling from library/nginx","id":"latest"}`,
+		`{"status":"Downloading","progressDetail":{"current":10,"total":100},"id":"a"}`,
+		`{"status":"Downloading","progressDetail":{"current":90,"total":100},"id":"a"}`,
+		`{"status":"Downloading","progressDetail":{"current":5,"total":50},"id":"b"}`,
+		`{"status":"Download complete","id":"b"}`,
+	}, "\n")
+
+	pulled, err := readPullProgress(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("readPullProgress() error = %v", err)
+	}
+	if pulled != 150 {
+		t.Errorf("got %d bytes, want 150", pulled)
+	}
+
+	_, err = readPullProgress(strings.NewReader(`{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`))
+	if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("expected manifest unknown error, got %v", err)
+	}
+}
+
 func TestIntegrationDeployAndRemove(t *testing.T) {
 	if testing.Short() {
 		t.Skip(SkipIntegrationMsg)
@@ -39,7 +65,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(ctx, composePath, testProjectName)
+	project, err := LoadProject(ctx, []string{composePath}, testProjectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -109,7 +135,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(ctx, composePath, testProjectName)
+	project, err := LoadProject(ctx, []string{composePath}, testProjectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -162,7 +188,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(ctx, composePath, testProjectName)
+	project, err := LoadProject(ctx, []string{composePath}, testProjectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -201,3 +227,62 @@ services:
 		t.Log("container reused (same image)")
 	}
 }
+
+func TestIntegrationDeploySecrets(t *testing.T) {
+	if testing.Short() {
+		t.Skip(SkipIntegrationMsg)
+	}
+
+	client := NewTestClient(t, testProjectName)
+	ctx := t.Context()
+
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: `
+services:
+  web:
+    image: nginx:alpine
+    secrets:
+      - db_password
+secrets:
+  db_password:
+    file: db_password.txt
+`,
+		"db_password.txt": "s3cret",
+	})
+
+	project, err := LoadProject(ctx, []string{composePath}, testProjectName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = client.Remove(cleanupCtx)
+	})
+
+	if err := client.Deploy(ctx, project, "test-commit"); err != nil {
+		t.Fatalf("deploy failed: %v", err)
+	}
+	statuses, err := client.Status(ctx)
+	if err != nil || len(statuses) != 1 {
+		t.Fatalf("status = %v, %v, want one container", statuses, err)
+	}
+
+	reader, _, err := client.cli.CopyFromContainer(ctx, statuses[0].Container, "/run/secrets/db_password")
+	if err != nil {
+		t.Fatalf("copy secret from container: %v", err)
+	}
+	defer reader.Close()
+	tr := tar.NewReader(reader)
+	if _, err := tr.Next(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "s3cret" {
+		t.Errorf("secret in container = %q, want s3cret", data)
+	}
+}
@@ -2,27 +2,99 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/compose-spec/compose-go/v2/cli"
+	"github.com/compose-spec/compose-go/v2/loader"
 	"github.com/compose-spec/compose-go/v2/types"
 	"github.com/samber/lo"
 )
 
-func LoadProject(ctx context.Context, composePath, projectName string) (*types.Project, error) {
-	absPath, err := filepath.Abs(composePath)
-	if err != nil {
-		return nil, fmt.Errorf("resolve compose path: %w", err)
+var ErrNoComposeFiles = errors.New("no compose files")
+
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	noDecrypt bool
+	env       map[string]string
+	profiles  []string
+
+	preview    bool
+	portOffset int
+}
+
+// WithoutDecryption loads SOPS-encrypted files as they are in the checkout.
+// Use it when only the referenced paths matter.
+func WithoutDecryption() LoadOption {
+	return func(o *loadOptions) {
+		o.noDecrypt = true
 	}
+}
+
+// WithEnvironment interpolates the compose file with env. It takes
+// precedence over the .env file.
+func WithEnvironment(env map[string]string) LoadOption {
+	return func(o *loadOptions) {
+		o.env = env
+	}
+}
 
-	opts, err := cli.NewProjectOptions(
-		[]string{absPath},
-		cli.WithWorkingDirectory(filepath.Dir(absPath)),
+// WithProfiles enables services assigned to the given compose profiles.
+func WithProfiles(profiles ...string) LoadOption {
+	return func(o *loadOptions) {
+		o.profiles = profiles
+	}
+}
+
+// LoadProject merges composePaths in order, each overriding the ones before
+// it. Relative paths in every file resolve against the first file's
+// directory.
+func LoadProject(ctx context.Context, composePaths []string, projectName string, options ...LoadOption) (*types.Project, error) {
+	o := &loadOptions{}
+	for _, opt := range options {
+		opt(o)
+	}
+	if len(composePaths) == 0 {
+		return nil, ErrNoComposeFiles
+	}
+
+	absPaths := make([]string, len(composePaths))
+	for i, path := range composePaths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("resolve compose path: %w", err)
+		}
+		absPaths[i] = abs
+	}
+	workDir := filepath.Dir(absPaths[0])
+	d := &decrypter{projectName: projectName, workDir: workDir}
+
+	projectOpts := []cli.ProjectOptionsFn{
+		cli.WithWorkingDirectory(workDir),
 		cli.WithName(projectName),
 		cli.WithResolvedPaths(true),
 		cli.WithInterpolation(true),
-	)
+		cli.WithLoadOptions(func(l *loader.Options) { l.SkipResolveEnvironment = true }),
+	}
+	if len(o.profiles) > 0 {
+		projectOpts = append(projectOpts, cli.WithProfiles(o.profiles))
+	}
+	if len(o.env) > 0 {
+		projectOpts = append(projectOpts, cli.WithEnv(lo.MapToSlice(o.env, func(k, v string) string { return k + "=" + v })))
+	}
+	dotEnv, err := d.dotEnv(filepath.Join(workDir, ".env"), o.noDecrypt)
+	if err != nil {
+		return nil, err
+	}
+	if dotEnv != "" {
+		projectOpts = append(projectOpts, cli.WithEnvFiles(dotEnv), cli.WithDotEnv)
+	}
+
+	opts, err := cli.NewProjectOptions(absPaths, projectOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("create project options: %w", err)
 	}
@@ -32,9 +104,50 @@ func LoadProject(ctx context.Context, composePath, projectName string) (*types.P
 		return nil, fmt.Errorf("parse compose file: %w", err)
 	}
 
+	if !o.noDecrypt {
+		if err := d.decryptFiles(project); err != nil {
+			return nil, err
+		}
+	}
+	if o.preview {
+		if err := isolateProject(project, o.portOffset); err != nil {
+			return nil, err
+		}
+	}
+	project, err = project.WithServicesEnvironmentResolved(false)
+	if err != nil {
+		return nil, fmt.Errorf("resolve environment: %w", err)
+	}
+
 	return project, nil
 }
 
 func ServiceNames(project *types.Project) []string {
 	return lo.Keys(project.Services)
 }
+
+// ReferencedFiles lists the files a project is loaded from: its compose files,
+// the .env used for interpolation, and env_file, config and secret files.
+func ReferencedFiles(project *types.Project) []string {
+	files := slices.Clone(project.ComposeFiles)
+	if dotEnv := filepath.Join(project.WorkingDir, ".env"); fileExists(dotEnv) {
+		files = append(files, dotEnv)
+	}
+	for _, svc := range project.Services {
+		for _, envFile := range svc.EnvFiles {
+			files = append(files, envFile.Path)
+		}
+	}
+	for _, cfg := range project.Configs {
+		files = append(files, cfg.File)
+	}
+	for _, secret := range project.Secrets {
+		files = append(files, secret.File)
+	}
+	return lo.Uniq(lo.Compact(files))
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -30,7 +31,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(t.Context(), composePath, testProject)
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -55,7 +56,7 @@ services:
 }
 
 func TestLoadProjectInvalidFile(t *testing.T) {
-	_, err := LoadProject(t.Context(), "/nonexistent/compose.yaml", "test")
+	_, err := LoadProject(t.Context(), []string{"/nonexistent/compose.yaml"}, "test")
 	if err == nil {
 		t.Error("expected error for nonexistent file")
 	}
@@ -78,7 +79,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(t.Context(), composePath, "test")
+	project, err := LoadProject(t.Context(), []string{composePath}, "test")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -89,3 +90,67 @@ services:
 		t.Errorf("got %d names, want 3", len(names))
 	}
 }
+
+func TestLoadProjectWithEnvironment(t *testing.T) {
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: "services:\n  web:\n    image: nginx:${TAG}\n    environment:\n      - DB_PASSWORD\n",
+		".env":          "TAG=1.27\nDB_PASSWORD=from-dotenv\n",
+	})
+
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject, WithEnvironment(map[string]string{"DB_PASSWORD": "s3cret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := project.Services["web"]
+	if web.Image != "nginx:1.27" {
+		t.Errorf("image = %q, want nginx:1.27", web.Image)
+	}
+	if got := web.Environment["DB_PASSWORD"]; got == nil || *got != "s3cret" {
+		t.Errorf("DB_PASSWORD = %v, want s3cret", got)
+	}
+}
+
+func TestLoadProjectOverlays(t *testing.T) {
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: "services:\n  web:\n    image: nginx:1.27\n  debug:\n    image: busybox\n    profiles: [debug]\n",
+		"prod.yaml":     "services:\n  web:\n    image: nginx:${TAG}\n    ports:\n      - \"80:80\"\n",
+	})
+	paths := []string{composePath, filepath.Join(filepath.Dir(composePath), "prod.yaml")}
+
+	project, err := LoadProject(t.Context(), paths, testProject, WithEnvironment(map[string]string{"TAG": "1.28"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web := project.Services["web"]; web.Image != "nginx:1.28" || len(web.Ports) != 1 {
+		t.Errorf("web = %s with %d ports, want the override applied", web.Image, len(web.Ports))
+	}
+	if _, ok := project.Services["debug"]; ok {
+		t.Error("debug service loaded without its profile")
+	}
+
+	project, err = LoadProject(t.Context(), paths, testProject, WithProfiles("debug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := project.Services["debug"]; !ok {
+		t.Error("debug service missing with its profile enabled")
+	}
+
+	if _, err := LoadProject(t.Context(), nil, testProject); !errors.Is(err, ErrNoComposeFiles) {
+		t.Errorf("LoadProject(nil) error = %v, want ErrNoComposeFiles", err)
+	}
+}
+
+func TestLoadProjectMultiDocument(t *testing.T) {
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: "---\n# base.yaml\nservices:\n  web:\n    image: nginx:1.27\n---\n# prod.yaml\nservices:\n  web:\n    image: nginx:1.28\n",
+	})
+
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := project.Services["web"].Image; image != "nginx:1.28" {
+		t.Errorf("image = %q, want the later document to win", image)
+	}
+}
@@ -0,0 +1,193 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/compose-spec/compose-go/v2/types"
+
+	"github.com/LoriKarikari/kedge/internal/sops"
+)
+
+var ErrDecrypt = errors.New("cannot decrypt SOPS file")
+
+var (
+	secretDirsMu sync.Mutex
+	secretDirs   = map[string]string{}
+)
+
+// decrypter writes plaintext copies of SOPS-encrypted files to a private
+// directory, so the checkout keeps only ciphertext.
+type decrypter struct {
+	projectName string
+	workDir     string
+	identities  []age.Identity
+	keysErr     error
+	keysLoaded  bool
+}
+
+// secretDir returns the owner-only directory holding a project's decrypted
+// files, created once per process.
+func secretDir(projectName string) (string, error) {
+	secretDirsMu.Lock()
+	defer secretDirsMu.Unlock()
+	if dir, ok := secretDirs[projectName]; ok {
+		if _, err := os.Stat(dir); err == nil {
+			return dir, nil
+		}
+	}
+	dir, err := os.MkdirTemp("", "kedge-secrets-"+projectName+"-")
+	if err != nil {
+		return "", err
+	}
+	secretDirs[projectName] = dir
+	return dir, nil
+}
+
+// RemoveSecrets deletes the plaintext copies decrypted for a project by this
+// process.
+func RemoveSecrets(projectName string) error {
+	secretDirsMu.Lock()
+	dir, ok := secretDirs[projectName]
+	delete(secretDirs, projectName)
+	secretDirsMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return os.RemoveAll(dir)
+}
+
+// decrypt returns the path of a plaintext copy of path, or path itself when
+// the file is not SOPS-encrypted. Env files are rewritten as quoted dotenv
+// so compose does not interpolate or strip the decrypted values.
+func (d *decrypter) decrypt(path string, env bool) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is referenced by the compose project
+	if errors.Is(err, fs.ErrNotExist) {
+		return path, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	format := sops.Detect(path, data)
+	if format == sops.FormatNone {
+		return path, nil
+	}
+
+	plaintext, err := d.plaintext(path, data, format, env)
+	if err != nil {
+		name := path
+		if rel, relErr := filepath.Rel(d.workDir, path); relErr == nil && filepath.IsLocal(rel) {
+			name = rel
+		}
+		return "", fmt.Errorf("%w %s: %w", ErrDecrypt, name, err)
+	}
+
+	dir, err := secretDir(d.projectName)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(path))
+	target := filepath.Join(dir, hex.EncodeToString(sum[:6])+"-"+filepath.Base(path))
+	if err := writePrivate(target, plaintext); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func (d *decrypter) plaintext(path string, data []byte, format sops.Format, env bool) ([]byte, error) {
+	if !d.keysLoaded {
+		d.identities, d.keysErr = sops.LoadIdentities()
+		d.keysLoaded = true
+	}
+	if d.keysErr != nil {
+		return nil, d.keysErr
+	}
+	if !env || format != sops.FormatDotenv {
+		return sops.Decrypt(path, data, d.identities)
+	}
+	vars, err := sops.DecryptDotenv(data, d.identities)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, v := range vars {
+		b.WriteString(v.Key + "=" + quoteEnv(v.Value) + "\n")
+	}
+	return []byte(b.String()), nil
+}
+
+var envEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+
+func quoteEnv(value string) string {
+	return `"` + envEscaper.Replace(value) + `"`
+}
+
+func writePrivate(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// dotEnv returns the .env file to interpolate the compose file with, or ""
+// if there is none. Without decryption an encrypted .env is skipped.
+func (d *decrypter) dotEnv(path string, noDecrypt bool) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // .env next to the compose file
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if sops.Detect(path, data) == sops.FormatNone {
+		return path, nil
+	}
+	if noDecrypt {
+		return "", nil
+	}
+	return d.decrypt(path, true)
+}
+
+// decryptFiles points env_file and secret entries that are SOPS-encrypted at
+// their decrypted copies.
+func (d *decrypter) decryptFiles(project *types.Project) error {
+	for name, svc := range project.Services {
+		for i, envFile := range svc.EnvFiles {
+			path, err := d.decrypt(envFile.Path, true)
+			if err != nil {
+				return err
+			}
+			svc.EnvFiles[i].Path = path
+		}
+		project.Services[name] = svc
+	}
+	for name, secret := range project.Secrets {
+		if secret.File == "" {
+			continue
+		}
+		path, err := d.decrypt(secret.File, false)
+		if err != nil {
+			return err
+		}
+		secret.File = path
+		project.Secrets[name] = secret
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const encryptedEnv = "DB_PASSWORD=ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]\n" +
+	"sops_lastmodified=2026-01-02T03:04:05Z\n" +
+	"sops_mac=ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]\n"
+
+func writeProjectFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, TestComposeFile)
+}
+
+func TestLoadProjectDotEnv(t *testing.T) {
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: "services:\n  web:\n    image: nginx:${TAG}\n",
+		".env":          "TAG=1.27\n",
+	})
+
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := project.Services["web"].Image; got != "nginx:1.27" {
+		t.Errorf("image = %q, want nginx:1.27", got)
+	}
+}
+
+func TestLoadProjectEncryptedEnvFile(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join(t.TempDir(), "keys.txt"))
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: "services:\n  web:\n    image: nginx\n    env_file: secrets.env\n",
+		"secrets.env":   encryptedEnv,
+	})
+
+	_, err := LoadProject(t.Context(), []string{composePath}, testProject)
+	if !errors.Is(err, ErrDecrypt) || !strings.Contains(err.Error(), "secrets.env") {
+		t.Fatalf("LoadProject() error = %v, want ErrDecrypt for secrets.env", err)
+	}
+
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject, WithoutDecryption())
+	if err != nil {
+		t.Fatalf("LoadProject(WithoutDecryption) error = %v", err)
+	}
+	if files := ReferencedFiles(project); !strings.HasSuffix(files[1], "secrets.env") {
+		t.Errorf("ReferencedFiles() = %v", files)
+	}
+}
+
+func TestRemoveSecrets(t *testing.T) {
+	dir, err := secretDir(testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writePrivate(filepath.Join(dir, "db.env"), []byte("DB_PASSWORD=s3cret\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveSecrets(testProject); err != nil {
+		t.Fatalf("RemoveSecrets() error = %v", err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("secret dir still exists: %v", err)
+	}
+	if err := RemoveSecrets(testProject); err != nil {
+		t.Errorf("RemoveSecrets() on a removed project error = %v", err)
+	}
+}
+
+func TestQuoteEnv(t *testing.T) {
+	value := "p@ss \"word\"\\n$HOME\nline2 # not a comment"
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: "services:\n  web:\n    image: nginx\n    env_file: app.env\n",
+		"app.env":       "SECRET=" + quoteEnv(value) + "\n",
+	})
+
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := project.Services["web"].Environment["SECRET"]; got == nil || *got != value {
+		t.Errorf("SECRET = %v, want %q", got, value)
+	}
+}
+
+func TestSecretMounts(t *testing.T) {
+	composePath := writeProjectFiles(t, map[string]string{
+		TestComposeFile: `services:
+  web:
+    image: nginx
+    secrets:
+      - db_password
+      - source: api_key
+        target: api.key
+      - source: tls_key
+        target: /etc/ssl/private/tls.key
+secrets:
+  db_password:
+    file: db_password.txt
+  api_key:
+    file: api_key.txt
+  tls_key:
+    file: tls.key
+`,
+		"db_password.txt": "s3cret",
+		"api_key.txt":     "key",
+		"tls.key":         "pem",
+	})
+	dir := filepath.Dir(composePath)
+
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := project.Services["web"]
+	mounts := secretMounts(project, svc)
+
+	want := map[string]string{
+		"/run/secrets/db_password": filepath.Join(dir, "db_password.txt"),
+		"/run/secrets/api.key":     filepath.Join(dir, "api_key.txt"),
+		"/etc/ssl/private/tls.key": filepath.Join(dir, "tls.key"),
+	}
+	if len(mounts) != len(want) {
+		t.Fatalf("secretMounts() = %+v, want %d mounts", mounts, len(want))
+	}
+	for _, m := range mounts {
+		if want[m.Target] != m.Source || !m.ReadOnly || m.Type != "bind" {
+			t.Errorf("mount = %+v, want a read-only bind of %s", m, want[m.Target])
+		}
+	}
+
+	hash := ConfigHash(svc, mounts...)
+	if hash == ConfigHash(svc) {
+		t.Error("ConfigHash() ignores secrets")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "db_password.txt"), []byte("rotated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ConfigHash(svc, mounts...) == hash {
+		t.Error("ConfigHash() unchanged after a secret changed")
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"path"
 	"slices"
 	"time"
 
@@ -17,14 +20,23 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/go-connections/nat"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
 const pullTimeout = 5 * time.Minute
 
-func (c *Client) Deploy(ctx context.Context, project *types.Project, commit string) error {
+func (c *Client) Deploy(ctx context.Context, project *types.Project, commit string) (err error) {
+	ctx, span := c.startSpan(ctx, "Client.Deploy", telemetry.AttrCommit.String(commit))
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	c.logger.Info("deploying project", slog.Int("services", len(project.Services)))
 
 	if err := c.ensureNetworks(ctx, project); err != nil {
@@ -33,7 +45,8 @@ func (c *Client) Deploy(ctx context.Context, project *types.Project, commit stri
 
 	for name := range project.Services {
 		svc := project.Services[name]
-		if err := c.deployService(ctx, project.Name, name, svc, commit); err != nil {
+		if err := c.deployService(ctx, project.Name, name, svc, secretMounts(project, svc), commit); err != nil {
+			c.publish(bus.TypeServiceDeployFailed, name, commit, map[string]any{"error": err.Error()})
 			return fmt.Errorf("deploy service %s: %w", name, err)
 		}
 	}
@@ -41,7 +54,10 @@ func (c *Client) Deploy(ctx context.Context, project *types.Project, commit stri
 	return nil
 }
 
-func (c *Client) ensureNetworks(ctx context.Context, project *types.Project) error {
+func (c *Client) ensureNetworks(ctx context.Context, project *types.Project) (err error) {
+	ctx, span := c.startSpan(ctx, "Client.ensureNetworks")
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	defaultNetwork := fmt.Sprintf("%s_default", project.Name)
 	if err := c.ensureNetwork(ctx, defaultNetwork); err != nil {
 		return fmt.Errorf("ensure default network: %w", err)
@@ -77,13 +93,33 @@ func (c *Client) ensureNetwork(ctx context.Context, name string) error {
 	return err
 }
 
-func (c *Client) deployService(ctx context.Context, projectName, serviceName string, svc types.ServiceConfig, commit string) error {
+func (c *Client) deployService(ctx context.Context, projectName, serviceName string, svc types.ServiceConfig, secrets []mount.Mount, commit string) (err error) {
+	ctx, span := c.startSpan(ctx, "Client.deployService",
+		telemetry.AttrService.String(serviceName),
+		telemetry.AttrCommit.String(commit),
+		telemetry.AttrImage.String(svc.Image),
+	)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	start := time.Now()
+	defer func() {
+		if c.metrics != nil {
+			c.metrics.RecordServiceDeploy(ctx, telemetry.RepoLabel(c.repoName, c.appName), serviceName, time.Since(start), err == nil)
+		}
+	}()
+
 	c.logger.Info("deploying service", slog.String("service", serviceName), slog.String("image", svc.Image))
+	c.publish(bus.TypeServiceDeployStarted, serviceName, commit, map[string]any{"image": svc.Image})
 
-	imageID, err := c.pullImage(ctx, svc.Image)
+	pullStart := time.Now()
+	imageID, pulled, err := c.pullImage(ctx, svc.Image)
+	if c.metrics != nil {
+		c.metrics.RecordImagePull(ctx, telemetry.RepoLabel(c.repoName, c.appName), serviceName, time.Since(pullStart), pulled, err == nil)
+	}
 	if err != nil {
 		return fmt.Errorf("pull image: %w", err)
 	}
+	c.publish(bus.TypeServiceImagePulled, serviceName, commit, map[string]any{"image": svc.Image, "image_id": imageID})
 
 	existing, err := c.findContainer(ctx, serviceName)
 	if err != nil {
@@ -92,9 +128,11 @@ func (c *Client) deployService(ctx context.Context, projectName, serviceName str
 
 	if existing != nil {
 		storedHash := existing.Labels[LabelConfigHash]
-		currentHash := ConfigHash(svc)
+		currentHash := ConfigHash(svc, secrets...)
 		if existing.ImageID == imageID && existing.State == "running" && storedHash == currentHash {
 			c.logger.Info("service already running with correct config", slog.String("service", serviceName))
+			c.publish(bus.TypeServiceUnchanged, serviceName, commit, nil)
+			span.SetAttributes(attribute.Bool("kedge.unchanged", true))
 			return nil
 		}
 		if err := c.removeContainer(ctx, existing.ID); err != nil {
@@ -102,10 +140,17 @@ func (c *Client) deployService(ctx context.Context, projectName, serviceName str
 		}
 	}
 
-	return c.createAndStartContainer(ctx, projectName, serviceName, svc, commit)
+	if err := c.createAndStartContainer(ctx, projectName, serviceName, svc, secrets, commit); err != nil {
+		return err
+	}
+	c.publish(bus.TypeServiceDeployFinished, serviceName, commit, map[string]any{"image": svc.Image})
+	return nil
 }
 
-func (c *Client) pullImage(ctx context.Context, imageName string) (string, error) {
+func (c *Client) pullImage(ctx context.Context, imageName string) (_ string, _ int64, err error) {
+	ctx, span := c.startSpan(ctx, "Client.pullImage", telemetry.AttrImage.String(imageName))
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	pullCtx, cancel := context.WithTimeout(ctx, pullTimeout)
 	defer cancel()
 
@@ -113,23 +158,46 @@ func (c *Client) pullImage(ctx context.Context, imageName string) (string, error
 
 	reader, err := c.cli.ImagePull(pullCtx, imageName, image.PullOptions{})
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	defer reader.Close()
 
-	if _, err = io.Copy(io.Discard, reader); err != nil {
-		return "", err
+	pulled, err := readPullProgress(reader)
+	if err != nil {
+		return "", pulled, err
 	}
+	span.SetAttributes(attribute.Int64("kedge.pulled_bytes", pulled))
 
 	inspectCtx, inspectCancel := context.WithTimeout(ctx, defaultTimeout)
 	defer inspectCancel()
 
 	inspect, err := c.cli.ImageInspect(inspectCtx, imageName)
 	if err != nil {
-		return "", fmt.Errorf("inspect image: %w", err)
+		return "", pulled, fmt.Errorf("inspect image: %w", err)
 	}
 
-	return inspect.ID, nil
+	return inspect.ID, pulled, nil
+}
+
+func readPullProgress(r io.Reader) (int64, error) {
+	layers := make(map[string]int64)
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return lo.Sum(lo.Values(layers)), err
+		}
+		if msg.Error != nil {
+			return lo.Sum(lo.Values(layers)), msg.Error
+		}
+		if msg.Status == "Downloading" && msg.Progress != nil && msg.Progress.Total > 0 {
+			layers[msg.ID] = msg.Progress.Total
+		}
+	}
+	return lo.Sum(lo.Values(layers)), nil
 }
 
 func (c *Client) findContainer(ctx context.Context, serviceName string) (*container.Summary, error) {
@@ -166,8 +234,15 @@ func (c *Client) removeContainer(ctx context.Context, containerID string) error
 	return err
 }
 
-func (c *Client) createAndStartContainer(ctx context.Context, projectName, serviceName string, svc types.ServiceConfig, commit string) error {
-	labels := lo.Assign(svc.Labels, kedgeLabels(projectName, serviceName, commit, svc))
+func (c *Client) createAndStartContainer(ctx context.Context, projectName, serviceName string, svc types.ServiceConfig, secrets []mount.Mount, commit string) (err error) {
+	ctx, span := c.startSpan(ctx, "Client.createAndStartContainer",
+		telemetry.AttrService.String(serviceName),
+		telemetry.AttrCommit.String(commit),
+		telemetry.AttrImage.String(svc.Image),
+	)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	labels := lo.Assign(svc.Labels, kedgeLabels(projectName, serviceName, commit, svc, secrets...))
 
 	exposedPorts, portBindings := c.buildPortMappings(svc.Ports)
 
@@ -188,6 +263,7 @@ func (c *Client) createAndStartContainer(ctx context.Context, projectName, servi
 	hostConfig := &container.HostConfig{
 		PortBindings:  portBindings,
 		RestartPolicy: buildRestartPolicy(svc),
+		Mounts:        secrets,
 	}
 
 	contName := containerName(projectName, serviceName)
@@ -284,17 +360,35 @@ func containerName(projectName, serviceName string) string {
 	return fmt.Sprintf("%s-%s-1", projectName, serviceName)
 }
 
-func kedgeLabels(projectName, serviceName, commit string, svc types.ServiceConfig) map[string]string {
+func kedgeLabels(projectName, serviceName, commit string, svc types.ServiceConfig, secrets ...mount.Mount) map[string]string {
 	return lo.OmitByValues(map[string]string{
 		LabelManaged:    "true",
 		LabelProject:    projectName,
 		LabelService:    serviceName,
 		LabelCommit:     commit,
-		LabelConfigHash: ConfigHash(svc),
+		LabelConfigHash: ConfigHash(svc, secrets...),
 	}, []string{""})
 }
 
-func ConfigHash(svc types.ServiceConfig) string {
+// secretMounts returns read-only bind mounts of the file secrets a service
+// uses, at /run/secrets/<name> unless the service sets another target.
+func secretMounts(project *types.Project, svc types.ServiceConfig) []mount.Mount {
+	return lo.FilterMap(svc.Secrets, func(s types.ServiceSecretConfig, _ int) (mount.Mount, bool) {
+		secret, ok := project.Secrets[s.Source]
+		if !ok || secret.File == "" {
+			return mount.Mount{}, false
+		}
+		target := s.Target
+		if !path.IsAbs(target) {
+			target = path.Join("/run/secrets", cmp.Or(target, s.Source))
+		}
+		return mount.Mount{Type: mount.TypeBind, Source: secret.File, Target: target, ReadOnly: true}, true
+	})
+}
+
+// ConfigHash hashes the parts of a service kedge deploys. Secrets are hashed
+// by content, so a changed secret file recreates the container.
+func ConfigHash(svc types.ServiceConfig, secrets ...mount.Mount) string {
 	cfg := struct {
 		Image      string
 		Command    []string
@@ -305,6 +399,7 @@ func ConfigHash(svc types.ServiceConfig) string {
 		Networks   []string
 		WorkingDir string
 		Restart    string
+		Secrets    map[string]string `json:",omitempty"`
 	}{
 		Image:      svc.Image,
 		Command:    svc.Command,
@@ -315,6 +410,9 @@ func ConfigHash(svc types.ServiceConfig) string {
 		Networks:   lo.Keys(svc.Networks),
 		WorkingDir: svc.WorkingDir,
 		Restart:    svc.Restart,
+		Secrets: lo.SliceToMap(secrets, func(m mount.Mount) (string, string) {
+			return m.Target, fileDigest(m.Source)
+		}),
 	}
 	slices.SortFunc(cfg.Env, func(a, b lo.Entry[string, *string]) int { return cmp.Compare(a.Key, b.Key) })
 	slices.Sort(cfg.Networks)
@@ -326,3 +424,12 @@ func ConfigHash(svc types.ServiceConfig) string {
 	hash := sha256.Sum256(data)
 	return hex.EncodeToString(hash[:8])
 }
+
+func fileDigest(path string) string {
+	data, err := os.ReadFile(path) //nolint:gosec // path is a secret file of the compose project
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -8,7 +8,11 @@ import (
 	"github.com/compose-spec/compose-go/v2/types"
 	"github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
 type DiffAction string
@@ -33,7 +37,10 @@ type DiffResult struct {
 	Summary string
 }
 
-func (c *Client) Diff(ctx context.Context, project *types.Project) (*DiffResult, error) {
+func (c *Client) Diff(ctx context.Context, project *types.Project) (_ *DiffResult, err error) {
+	ctx, span := c.startSpan(ctx, "Client.Diff")
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	listCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
@@ -50,7 +57,7 @@ func (c *Client) Diff(ctx context.Context, project *types.Project) (*DiffResult,
 
 	for name := range project.Services {
 		svc := project.Services[name]
-		diff, err := c.diffService(ctx, name, svc, actual[name])
+		diff, err := c.diffService(ctx, name, svc, secretMounts(project, svc), actual[name])
 		if err != nil {
 			return nil, fmt.Errorf("diff service %s: %w", name, err)
 		}
@@ -69,6 +76,7 @@ func (c *Client) Diff(ctx context.Context, project *types.Project) (*DiffResult,
 		})
 	}
 
+	span.SetAttributes(attribute.Int("kedge.changes", len(changes)))
 	return &DiffResult{
 		Changes: changes,
 		InSync:  len(changes) == 0,
@@ -83,7 +91,7 @@ func (c *Client) listManagedContainers(ctx context.Context) ([]container.Summary
 	})
 }
 
-func (c *Client) diffService(ctx context.Context, name string, desired types.ServiceConfig, actual container.Summary) (*ServiceDiff, error) {
+func (c *Client) diffService(ctx context.Context, name string, desired types.ServiceConfig, secrets []mount.Mount, actual container.Summary) (*ServiceDiff, error) {
 	if actual.ID == "" {
 		return &ServiceDiff{
 			Service:      name,
@@ -118,7 +126,7 @@ func (c *Client) diffService(ctx context.Context, name string, desired types.Ser
 	}
 
 	storedHash := actual.Labels[LabelConfigHash]
-	currentHash := ConfigHash(desired)
+	currentHash := ConfigHash(desired, secrets...)
 	if storedHash != currentHash {
 		return &ServiceDiff{
 			Service:      name,
@@ -81,7 +81,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(ctx, composePath, testProjectName)
+	project, err := LoadProject(ctx, []string{composePath}, testProjectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -129,7 +129,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(ctx, composePath, testProjectName)
+	project, err := LoadProject(ctx, []string{composePath}, testProjectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -176,7 +176,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := LoadProject(ctx, composePath, testProjectName)
+	project, err := LoadProject(ctx, []string{composePath}, testProjectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -196,7 +196,7 @@ services:
 		t.Fatal(err)
 	}
 
-	reducedProject, err := LoadProject(ctx, composePath, testProjectName)
+	reducedProject, err := LoadProject(ctx, []string{composePath}, testProjectName)
 	if err != nil {
 		t.Fatal(err)
 	}
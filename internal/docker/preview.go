@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// WithPreview loads the project so that it can run next to other projects
+// built from the same files: fixed container names are dropped, and
+// published host ports are shifted by portOffset, or not published at all
+// when portOffset is zero.
+func WithPreview(portOffset int) LoadOption {
+	return func(o *loadOptions) {
+		o.preview = true
+		o.portOffset = portOffset
+	}
+}
+
+func isolateProject(project *types.Project, portOffset int) error {
+	for name, svc := range project.Services {
+		svc.ContainerName = ""
+		if portOffset == 0 {
+			svc.Ports = nil
+		}
+		for i, port := range svc.Ports {
+			published, err := shiftPorts(port.Published, portOffset)
+			if err != nil {
+				return fmt.Errorf("service %s: %w", name, err)
+			}
+			svc.Ports[i].Published = published
+		}
+		project.Services[name] = svc
+	}
+	return nil
+}
+
+// shiftPorts adds offset to a published port or port range. An empty value
+// lets Docker pick the host port and is kept as is.
+func shiftPorts(published string, offset int) (string, error) {
+	if published == "" {
+		return "", nil
+	}
+	parts := strings.Split(published, "-")
+	for i, part := range parts {
+		port, err := strconv.Atoi(part)
+		if err != nil {
+			return "", fmt.Errorf("invalid published port %q", published)
+		}
+		if port += offset; port < 1 || port > 65535 {
+			return "", fmt.Errorf("published port %s shifted by %d is out of range", part, offset)
+		}
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, "-"), nil
+}
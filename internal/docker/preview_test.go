@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProjectPreview(t *testing.T) {
+	dir := t.TempDir()
+	composePath := filepath.Join(dir, TestComposeFile)
+	content := `
+services:
+  web:
+    image: nginx:latest
+    container_name: web
+    ports:
+      - "8080:80"
+      - "9000-9001:9000-9001"
+      - "443"
+`
+	if err := os.WriteFile(composePath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := LoadProject(t.Context(), []string{composePath}, testProject, WithPreview(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := project.Services["web"]
+	if web.ContainerName != "" {
+		t.Errorf("container_name = %q, want it dropped", web.ContainerName)
+	}
+	var published []string
+	for _, port := range web.Ports {
+		published = append(published, port.Published)
+	}
+	if len(published) != 4 || published[0] != "8180" || published[1] != "9100" || published[2] != "9101" || published[3] != "" {
+		t.Errorf("published ports = %q", published)
+	}
+
+	project, err = LoadProject(t.Context(), []string{composePath}, testProject, WithPreview(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ports := project.Services["web"].Ports; len(ports) != 0 {
+		t.Errorf("ports = %+v, want none published", ports)
+	}
+
+	if _, err := LoadProject(t.Context(), []string{composePath}, testProject, WithPreview(60000)); err == nil {
+		t.Error("LoadProject() accepted a port shifted out of range")
+	}
+}
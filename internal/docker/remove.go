@@ -10,6 +10,9 @@ import (
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
 	"github.com/samber/lo"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
 func (c *Client) kedgeFilters() filters.Args {
@@ -86,7 +89,10 @@ func (c *Client) RemoveService(ctx context.Context, serviceName string) error {
 	return c.removeContainer(ctx, cont.ID)
 }
 
-func (c *Client) Prune(ctx context.Context, keepServices []string) error {
+func (c *Client) Prune(ctx context.Context, keepServices []string) (err error) {
+	ctx, span := c.startSpan(ctx, "Client.Prune")
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	listCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
@@ -107,7 +113,9 @@ func (c *Client) Prune(ctx context.Context, keepServices []string) error {
 		c.logger.Info("pruning orphan container", slog.String("service", serviceName))
 		if err := c.removeContainer(ctx, containers[i].ID); err != nil {
 			errs = append(errs, err)
+			continue
 		}
+		c.publish(bus.TypeServicePruned, serviceName, "", nil)
 	}
 
 	return errors.Join(errs...)
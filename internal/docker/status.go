@@ -31,16 +31,25 @@ func (c *Client) Status(ctx context.Context) ([]ServiceStatus, error) {
 
 	return lo.Map(containers, func(cont container.Summary, _ int) ServiceStatus {
 		return ServiceStatus{
-			Service:   cont.Labels[LabelService],
-			Container: shortContainerID(cont),
-			Image:     cont.Image,
-			State:     cont.State,
-			Health:    extractHealth(cont),
-			CreatedAt: time.Unix(cont.Created, 0),
+			Service:      cont.Labels[LabelService],
+			Container:    shortContainerID(cont),
+			Image:        cont.Image,
+			State:        cont.State,
+			Health:       extractHealth(cont),
+			RestartCount: c.restartCount(ctx, cont.ID),
+			CreatedAt:    time.Unix(cont.Created, 0),
 		}
 	}), nil
 }
 
+func (c *Client) restartCount(ctx context.Context, containerID string) int {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil || inspect.ContainerJSONBase == nil {
+		return 0
+	}
+	return inspect.RestartCount
+}
+
 func shortContainerID(cont container.Summary) string {
 	return lo.CoalesceOrEmpty(
 		lo.FirstOrEmpty(cont.Names),
@@ -3,12 +3,13 @@ package docker
 import "time"
 
 type ServiceStatus struct {
-	Service   string    `json:"service"`
-	Container string    `json:"container"`
-	Image     string    `json:"image"`
-	State     string    `json:"state"`
-	Health    string    `json:"health,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	Service      string    `json:"service"`
+	Container    string    `json:"container"`
+	Image        string    `json:"image"`
+	State        string    `json:"state"`
+	Health       string    `json:"health,omitempty"`
+	RestartCount int       `json:"restart_count"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 const (
@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
@@ -13,16 +15,42 @@ import (
 type Type string
 
 const (
-	TypeNone   Type = ""
-	TypeSSHKey Type = "ssh-key"
-	TypeToken  Type = "token"
+	TypeNone             Type = ""
+	TypeSSHKey           Type = "ssh-key"
+	TypeSSHAgent         Type = "ssh-agent"
+	TypeToken            Type = "token"
+	TypeTokenFile        Type = "token-file"
+	TypeGitHubApp        Type = "github-app"
+	TypeCredentialHelper Type = "credential-helper"
+)
+
+const (
+	defaultSSHUser   = "git"
+	defaultTokenUser = "x-access-token"
 )
 
 type Config struct {
-	Type        Type
-	SSHKeyPath  string
-	Username    string
-	PasswordEnv string
+	Type           Type
+	URL            string
+	SSHKeyPath     string
+	Username       string
+	PasswordEnv    string
+	PassphraseEnv  string
+	PassphraseFile string
+	KnownHostsPath string
+	HostKeys       []string
+
+	TokenFile        string
+	AppID            int64
+	InstallationID   int64
+	AppKeyPath       string
+	GitHubAPIURL     string
+	CredentialHelper string
+
+	// Password and SSHKey hold credentials decrypted from the state store;
+	// they take precedence over PasswordEnv and SSHKeyPath.
+	Password string
+	SSHKey   []byte
 }
 
 func (c *Config) Resolve(logger *slog.Logger) (transport.AuthMethod, error) {
@@ -37,29 +65,94 @@ func (c *Config) Resolve(logger *slog.Logger) (transport.AuthMethod, error) {
 	case TypeSSHKey:
 		return c.resolveSSH(logger)
 
+	case TypeSSHAgent:
+		return c.resolveSSHAgent()
+
 	case TypeToken:
 		return c.resolveToken()
 
+	case TypeTokenFile, TypeGitHubApp, TypeCredentialHelper:
+		source, err := c.NewSource(logger)
+		if err != nil {
+			return nil, err
+		}
+		return source.AuthMethod(context.Background())
+
 	default:
 		return nil, fmt.Errorf("unknown auth type: %s", c.Type)
 	}
 }
 
 func (c *Config) resolveSSH(logger *slog.Logger) (transport.AuthMethod, error) {
-	if c.SSHKeyPath == "" {
+	if c.SSHKeyPath == "" && len(c.SSHKey) == 0 {
 		return nil, fmt.Errorf("SSH key path is required for ssh-key auth")
 	}
 
-	c.warnInsecureKeyPermissions(logger)
+	passphrase, err := c.passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	var auth *ssh.PublicKeys
+	if len(c.SSHKey) > 0 {
+		auth, err = ssh.NewPublicKeys(c.sshUser(), c.SSHKey, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("loading stored SSH key: %w", err)
+		}
+	} else {
+		c.warnInsecureKeyPermissions(logger)
+		auth, err = ssh.NewPublicKeysFromFile(c.sshUser(), c.SSHKeyPath, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("loading SSH key from %s: %w", c.SSHKeyPath, err)
+		}
+	}
+
+	auth.HostKeyCallbackHelper, err = c.hostKeyHelper()
+	if err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
 
-	auth, err := ssh.NewPublicKeysFromFile("git", c.SSHKeyPath, "")
+func (c *Config) resolveSSHAgent() (transport.AuthMethod, error) {
+	auth, err := ssh.NewSSHAgentAuth(c.sshUser())
 	if err != nil {
-		return nil, fmt.Errorf("loading SSH key from %s: %w", c.SSHKeyPath, err)
+		return nil, err
 	}
 
+	auth.HostKeyCallbackHelper, err = c.hostKeyHelper()
+	if err != nil {
+		return nil, err
+	}
 	return auth, nil
 }
 
+func (c *Config) sshUser() string {
+	if c.Username == "" {
+		return defaultSSHUser
+	}
+	return c.Username
+}
+
+func (c *Config) passphrase() (string, error) {
+	switch {
+	case c.PassphraseEnv != "":
+		passphrase := os.Getenv(c.PassphraseEnv)
+		if passphrase == "" {
+			return "", fmt.Errorf("environment variable %s is not set or empty", c.PassphraseEnv)
+		}
+		return passphrase, nil
+	case c.PassphraseFile != "":
+		data, err := os.ReadFile(c.PassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("reading SSH key passphrase: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return "", nil
+	}
+}
+
 func (c *Config) warnInsecureKeyPermissions(logger *slog.Logger) {
 	if logger == nil {
 		return
@@ -81,6 +174,9 @@ func (c *Config) warnInsecureKeyPermissions(logger *slog.Logger) {
 }
 
 func (c *Config) resolveToken() (transport.AuthMethod, error) {
+	if c.Password != "" {
+		return c.basicAuth(c.Password), nil
+	}
 	if c.PasswordEnv == "" {
 		return nil, fmt.Errorf("password env var name is required for token auth")
 	}
@@ -90,15 +186,19 @@ func (c *Config) resolveToken() (transport.AuthMethod, error) {
 		return nil, fmt.Errorf("environment variable %s is not set or empty", c.PasswordEnv)
 	}
 
+	return c.basicAuth(password), nil
+}
+
+func (c *Config) basicAuth(password string) *http.BasicAuth {
 	username := c.Username
 	if username == "" {
-		username = "x-access-token"
+		username = defaultTokenUser
 	}
 
 	return &http.BasicAuth{
 		Username: username,
 		Password: password,
-	}, nil
+	}
 }
 
 func (c *Config) IsEmpty() bool {
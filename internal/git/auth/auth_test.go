@@ -58,6 +58,76 @@ func TestConfigResolveSSH(t *testing.T) {
 	}
 }
 
+func TestConfigResolveStoredCredentials(t *testing.T) {
+	key, err := os.ReadFile(createTestSSHKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshAuth, err := (&Config{Type: TypeSSHKey, SSHKey: key, SSHKeyPath: "/nonexistent"}).Resolve(nil)
+	if err != nil {
+		t.Fatalf("Resolve() ssh error = %v", err)
+	}
+	if _, ok := sshAuth.(*gitssh.PublicKeys); !ok {
+		t.Errorf("Resolve() = %T, want *ssh.PublicKeys", sshAuth)
+	}
+
+	tokenAuth, err := (&Config{Type: TypeToken, Password: "stored", PasswordEnv: "TEST_KEDGE_UNSET"}).Resolve(nil)
+	if err != nil {
+		t.Fatalf("Resolve() token error = %v", err)
+	}
+	if basic := tokenAuth.(*http.BasicAuth); basic.Password != "stored" || basic.Username != defaultUsername {
+		t.Errorf("got %s:%s", basic.Username, basic.Password)
+	}
+}
+
+func TestConfigResolveSSHPassphrase(t *testing.T) {
+	const passphrase = "correct horse"
+	keyPath := createEncryptedTestSSHKey(t, passphrase)
+
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte(passphrase+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_KEDGE_PASSPHRASE", passphrase)
+	t.Setenv("TEST_KEDGE_WRONG_PASSPHRASE", "wrong")
+
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{name: "env", config: &Config{Type: TypeSSHKey, SSHKeyPath: keyPath, PassphraseEnv: "TEST_KEDGE_PASSPHRASE"}},
+		{name: "file", config: &Config{Type: TypeSSHKey, SSHKeyPath: keyPath, PassphraseFile: passphraseFile}},
+		{name: "missing", config: &Config{Type: TypeSSHKey, SSHKeyPath: keyPath}, wantErr: true},
+		{name: "wrong", config: &Config{Type: TypeSSHKey, SSHKeyPath: keyPath, PassphraseEnv: "TEST_KEDGE_WRONG_PASSPHRASE"}, wantErr: true},
+		{name: "unset env", config: &Config{Type: TypeSSHKey, SSHKeyPath: keyPath, PassphraseEnv: "TEST_KEDGE_UNSET_PASSPHRASE"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.config.Resolve(nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigResolveSSHUser(t *testing.T) {
+	keyPath := createTestSSHKey(t)
+
+	for _, tt := range []struct{ username, want string }{{"", "git"}, {"deploy", "deploy"}} {
+		auth, err := (&Config{Type: TypeSSHKey, SSHKeyPath: keyPath, Username: tt.username}).Resolve(nil)
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		if got := auth.(*gitssh.PublicKeys).User; got != tt.want {
+			t.Errorf("User = %q, want %q", got, tt.want)
+		}
+	}
+}
+
 func TestConfigResolveSSHMissingKeyPath(t *testing.T) {
 	cfg := &Config{
 		Type:       TypeSSHKey,
@@ -196,6 +266,11 @@ func TestConfigIsEmpty(t *testing.T) {
 
 func createTestSSHKey(t *testing.T) string {
 	t.Helper()
+	return createEncryptedTestSSHKey(t, "")
+}
+
+func createEncryptedTestSSHKey(t *testing.T, passphrase string) string {
+	t.Helper()
 
 	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
@@ -203,6 +278,9 @@ func createTestSSHKey(t *testing.T) string {
 	}
 
 	pemBlock, err := cryptossh.MarshalPrivateKey(privateKey, "")
+	if passphrase != "" {
+		pemBlock, err = cryptossh.MarshalPrivateKeyWithPassphrase(privateKey, "", []byte(passphrase))
+	}
 	if err != nil {
 		t.Fatalf("failed to marshal private key: %v", err)
 	}
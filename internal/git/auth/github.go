@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const (
+	defaultGitHubAPIURL = "https://api.github.com"
+	tokenRefreshMargin  = 5 * time.Minute
+)
+
+type gitHubApp struct {
+	cfg    *Config
+	key    *rsa.PrivateKey
+	apiURL string
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newGitHubApp(c *Config) (*gitHubApp, error) {
+	if c.AppID == 0 || c.InstallationID == 0 || c.AppKeyPath == "" {
+		return nil, fmt.Errorf("app ID, installation ID and private key are required for github-app auth")
+	}
+	data, err := os.ReadFile(c.AppKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading GitHub App private key: %w", err)
+	}
+	key, err := parseRSAKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing GitHub App private key %s: %w", c.AppKeyPath, err)
+	}
+	apiURL := c.GitHubAPIURL
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+	return &gitHubApp{
+		cfg:    c,
+		key:    key,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+func parseRSAKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return key, nil
+}
+
+func (g *gitHubApp) AuthMethod(ctx context.Context) (transport.AuthMethod, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.token == "" || g.now().Add(tokenRefreshMargin).After(g.expires) {
+		token, expires, err := g.installationToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		g.token, g.expires = token, expires
+	}
+	return g.cfg.basicAuth(g.token), nil
+}
+
+func (g *gitHubApp) installationToken(ctx context.Context) (string, time.Time, error) {
+	jwt, err := g.jwt()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", g.apiURL, g.cfg.InstallationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("requesting installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, fmt.Errorf("requesting installation token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", time.Time{}, fmt.Errorf("decoding installation token: %w", err)
+	}
+	if result.Token == "" {
+		return "", time.Time{}, errors.New("installation token response has no token")
+	}
+	return result.Token, result.ExpiresAt, nil
+}
+
+func (g *gitHubApp) jwt() (string, error) {
+	now := g.now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(g.cfg.AppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, g.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
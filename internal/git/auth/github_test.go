@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeAppKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key, path
+}
+
+func verifyJWT(t *testing.T, header string, pub *rsa.PublicKey) map[string]any {
+	t.Helper()
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		t.Errorf("Authorization = %q", header)
+		return nil
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Errorf("malformed JWT %q", token)
+		return nil
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("JWT signature: %v", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Error(err)
+	}
+	return claims
+}
+
+func TestGitHubAppSource(t *testing.T) {
+	key, keyPath := writeAppKey(t)
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		if claims := verifyJWT(t, r.Header.Get("Authorization"), &key.PublicKey); claims["iss"] != "7" {
+			t.Errorf("iss = %v", claims["iss"])
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":%q}`, n, now.Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	cfg := &Config{Type: TypeGitHubApp, AppID: 7, InstallationID: 42, AppKeyPath: keyPath, GitHubAPIURL: server.URL + "/"}
+	source, err := cfg.NewSource(nil)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	app := source.(*gitHubApp)
+	app.now = func() time.Time { return now }
+
+	if got := basicAuthOf(t, source); got.Username != defaultUsername || got.Password != "ghs_1" {
+		t.Errorf("got %s:%s", got.Username, got.Password)
+	}
+	if got := basicAuthOf(t, source); got.Password != "ghs_1" {
+		t.Errorf("cached token = %q", got.Password)
+	}
+
+	app.now = func() time.Time { return now.Add(56 * time.Minute) }
+	if got := basicAuthOf(t, source); got.Password != "ghs_2" {
+		t.Errorf("refreshed token = %q", got.Password)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("token requests = %d, want 2", requests.Load())
+	}
+}
+
+func TestGitHubAppSourceErrors(t *testing.T) {
+	_, keyPath := writeAppKey(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	source, err := (&Config{Type: TypeGitHubApp, AppID: 7, InstallationID: 42, AppKeyPath: keyPath, GitHubAPIURL: server.URL}).NewSource(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.AuthMethod(t.Context()); err == nil || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("AuthMethod() error = %v", err)
+	}
+
+	if _, err := (&Config{Type: TypeGitHubApp, AppID: 7, AppKeyPath: keyPath}).NewSource(nil); err == nil {
+		t.Error("NewSource() error = nil without installation ID")
+	}
+	if _, err := (&Config{Type: TypeGitHubApp, AppID: 7, InstallationID: 42, AppKeyPath: createTestSSHKey(t)}).NewSource(nil); err == nil {
+		t.Error("NewSource() error = nil for a non-RSA key")
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/samber/lo"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrHostKeyMismatch = errors.New("host key mismatch")
+	errHostKeyScanned  = errors.New("host key scanned")
+)
+
+func ParseHostKey(line string) (ssh.PublicKey, error) {
+	line = strings.TrimSpace(line)
+	if key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line)); err == nil {
+		return key, nil
+	}
+	_, _, key, _, _, err := ssh.ParseKnownHosts([]byte(line))
+	if err != nil {
+		return nil, fmt.Errorf("parse host key %q: %w", line, err)
+	}
+	return key, nil
+}
+
+func FormatHostKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+func ScanHostKey(ctx context.Context, url string) (ssh.PublicKey, error) {
+	addr, err := hostWithPort(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var key ssh.PublicKey
+	_, _, _, err = ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User: defaultSSHUser,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errHostKeyScanned
+		},
+	})
+	if key == nil {
+		return nil, fmt.Errorf("scan host key of %s: %w", addr, err)
+	}
+	return key, nil
+}
+
+func (c *Config) hostKeyHelper() (gitssh.HostKeyCallbackHelper, error) {
+	switch {
+	case len(c.HostKeys) > 0:
+		keys := make([]ssh.PublicKey, 0, len(c.HostKeys))
+		for _, line := range c.HostKeys {
+			key, err := ParseHostKey(line)
+			if err != nil {
+				return gitssh.HostKeyCallbackHelper{}, err
+			}
+			keys = append(keys, key)
+		}
+		return gitssh.HostKeyCallbackHelper{
+			HostKeyCallback:   pinnedHostKeys(keys),
+			HostKeyAlgorithms: hostKeyAlgorithms(keys),
+		}, nil
+
+	case c.KnownHostsPath != "":
+		db, err := gitssh.NewKnownHostsDb(c.KnownHostsPath)
+		if err != nil {
+			return gitssh.HostKeyCallbackHelper{}, fmt.Errorf("loading known hosts from %s: %w", c.KnownHostsPath, err)
+		}
+		helper := gitssh.HostKeyCallbackHelper{HostKeyCallback: db.HostKeyCallback()}
+		if addr, err := hostWithPort(c.URL); err == nil {
+			helper.HostKeyAlgorithms = db.HostKeyAlgorithms(addr)
+		}
+		return helper, nil
+
+	default:
+		return gitssh.HostKeyCallbackHelper{}, nil
+	}
+}
+
+func pinnedHostKeys(keys []ssh.PublicKey) ssh.HostKeyCallback {
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		for _, pinned := range keys {
+			if bytes.Equal(pinned.Marshal(), key.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s presented %s", ErrHostKeyMismatch, hostname, ssh.FingerprintSHA256(key))
+	}
+}
+
+func hostKeyAlgorithms(keys []ssh.PublicKey) []string {
+	var algos []string
+	for _, key := range keys {
+		if key.Type() == ssh.KeyAlgoRSA {
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+		}
+		algos = append(algos, key.Type())
+	}
+	return lo.Uniq(algos)
+}
+
+func hostWithPort(url string) (string, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return "", err
+	}
+	if ep.Protocol != "ssh" {
+		return "", fmt.Errorf("not an SSH URL: %s", url)
+	}
+	return net.JoinHostPort(ep.Host, strconv.Itoa(lo.Ternary(ep.Port == 0, 22, ep.Port))), nil
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+func newHostKey(t *testing.T) cryptossh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := cryptossh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func startSSHServer(t *testing.T, hostKey cryptossh.Signer) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	cfg := &cryptossh.ServerConfig{NoClientAuth: true}
+	cfg.AddHostKey(hostKey)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _, _, _ = cryptossh.NewServerConn(conn, cfg)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestParseHostKey(t *testing.T) {
+	key := newHostKey(t).PublicKey()
+	line := FormatHostKey(key)
+
+	for _, input := range []string{line, line + " comment", "github.com " + line, "[example.com]:2222,10.0.0.1 " + line} {
+		got, err := ParseHostKey(input)
+		if err != nil {
+			t.Fatalf("ParseHostKey(%q) error = %v", input, err)
+		}
+		if FormatHostKey(got) != line {
+			t.Errorf("ParseHostKey(%q) = %s", input, FormatHostKey(got))
+		}
+	}
+
+	if _, err := ParseHostKey("not a key"); err == nil {
+		t.Error("ParseHostKey() error = nil, want error")
+	}
+}
+
+func TestScanHostKey(t *testing.T) {
+	hostKey := newHostKey(t)
+	addr := startSSHServer(t, hostKey)
+
+	key, err := ScanHostKey(t.Context(), fmt.Sprintf("ssh://git@%s/acme/webapp.git", addr))
+	if err != nil {
+		t.Fatalf("ScanHostKey() error = %v", err)
+	}
+	if FormatHostKey(key) != FormatHostKey(hostKey.PublicKey()) {
+		t.Errorf("ScanHostKey() = %s", FormatHostKey(key))
+	}
+
+	if _, err := ScanHostKey(t.Context(), "https://github.com/acme/webapp.git"); err == nil {
+		t.Error("ScanHostKey() error = nil for HTTPS URL")
+	}
+}
+
+func TestConfigHostKeyPinning(t *testing.T) {
+	hostKey := newHostKey(t)
+	other := newHostKey(t)
+	addr := startSSHServer(t, hostKey)
+	keyPath := createTestSSHKey(t)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	host, port, _ := net.SplitHostPort(addr)
+	line := fmt.Sprintf("[%s]:%s %s\n", host, port, FormatHostKey(hostKey.PublicKey()))
+	if err := os.WriteFile(knownHosts, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		mismatch bool
+	}{
+		{name: "pinned", config: Config{HostKeys: []string{FormatHostKey(hostKey.PublicKey())}}},
+		{name: "pinned other", config: Config{HostKeys: []string{FormatHostKey(other.PublicKey())}}, mismatch: true},
+		{name: "known hosts", config: Config{KnownHostsPath: knownHosts}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Type = TypeSSHKey
+			cfg.SSHKeyPath = keyPath
+			cfg.URL = fmt.Sprintf("ssh://git@%s/acme/webapp.git", addr)
+			auth, err := cfg.Resolve(nil)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			clientCfg, err := auth.(*gitssh.PublicKeys).ClientConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := cryptossh.Dial("tcp", addr, clientCfg)
+			if client != nil {
+				_ = client.Close()
+			}
+			if tt.mismatch != errors.Is(err, ErrHostKeyMismatch) {
+				t.Errorf("Dial() error = %v, want mismatch %v", err, tt.mismatch)
+			}
+			if !tt.mismatch && err != nil {
+				t.Errorf("Dial() error = %v", err)
+			}
+		})
+	}
+}
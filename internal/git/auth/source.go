@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+type Source interface {
+	AuthMethod(ctx context.Context) (transport.AuthMethod, error)
+}
+
+func (c *Config) NewSource(logger *slog.Logger) (Source, error) {
+	switch c.Type {
+	case TypeTokenFile:
+		if c.TokenFile == "" {
+			return nil, fmt.Errorf("token file is required for token-file auth")
+		}
+		return &tokenFile{cfg: c}, nil
+
+	case TypeGitHubApp:
+		return newGitHubApp(c)
+
+	case TypeCredentialHelper:
+		if c.CredentialHelper == "" {
+			return nil, fmt.Errorf("credential helper is required for credential-helper auth")
+		}
+		if c.URL == "" {
+			return nil, fmt.Errorf("repository URL is required for credential-helper auth")
+		}
+		return &credentialHelper{cfg: c}, nil
+
+	default:
+		method, err := c.Resolve(logger)
+		if err != nil {
+			return nil, err
+		}
+		return staticSource{method}, nil
+	}
+}
+
+type staticSource struct {
+	method transport.AuthMethod
+}
+
+func (s staticSource) AuthMethod(context.Context) (transport.AuthMethod, error) {
+	return s.method, nil
+}
+
+type tokenFile struct {
+	cfg *Config
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   string
+}
+
+func (t *tokenFile) AuthMethod(context.Context) (transport.AuthMethod, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, err := os.Stat(t.cfg.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading token file: %w", err)
+	}
+	if t.token == "" || !info.ModTime().Equal(t.modTime) || info.Size() != t.size {
+		data, err := os.ReadFile(t.cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading token file: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return nil, fmt.Errorf("token file %s is empty", t.cfg.TokenFile)
+		}
+		t.token, t.modTime, t.size = token, info.ModTime(), info.Size()
+	}
+	return t.cfg.basicAuth(t.token), nil
+}
+
+type credentialHelper struct {
+	cfg *Config
+}
+
+func (h *credentialHelper) AuthMethod(ctx context.Context) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(h.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	host := ep.Host
+	if ep.Port != 0 {
+		host = fmt.Sprintf("%s:%d", ep.Host, ep.Port)
+	}
+
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\npath=%s\n", ep.Protocol, host, strings.TrimPrefix(ep.Path, "/"))
+	if h.cfg.Username != "" {
+		fmt.Fprintf(&input, "username=%s\n", h.cfg.Username)
+	}
+	input.WriteString("\n")
+
+	cmd := helperCommand(ctx, h.cfg.CredentialHelper)
+	cmd.Stdin = &input
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %q: %w: %s", h.cfg.CredentialHelper, err, strings.TrimSpace(stderr.String()))
+	}
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			values[key] = value
+		}
+	}
+	if values["password"] == "" {
+		return nil, fmt.Errorf("credential helper %q returned no password for %s", h.cfg.CredentialHelper, host)
+	}
+
+	basic := h.cfg.basicAuth(values["password"])
+	if values["username"] != "" {
+		basic.Username = values["username"]
+	}
+	return basic, nil
+}
+
+// helperCommand follows git's credential.helper rules: a leading "!" runs a
+// shell snippet, an absolute path runs that program and anything else runs
+// git credential-<name>.
+func helperCommand(ctx context.Context, helper string) *exec.Cmd {
+	script := helper
+	switch {
+	case strings.HasPrefix(helper, "!"):
+		script = helper[1:]
+	case !strings.HasPrefix(helper, "/"):
+		script = "git credential-" + helper
+	}
+	return exec.CommandContext(ctx, "sh", "-c", script+` "$@"`, "sh", "get") //nolint:gosec // helper is configured by the operator
+}
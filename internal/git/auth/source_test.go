@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func basicAuthOf(t *testing.T, source Source) *http.BasicAuth {
+	t.Helper()
+	method, err := source.AuthMethod(t.Context())
+	if err != nil {
+		t.Fatalf("AuthMethod() error = %v", err)
+	}
+	basic, ok := method.(*http.BasicAuth)
+	if !ok {
+		t.Fatalf("AuthMethod() = %T, want *http.BasicAuth", method)
+	}
+	return basic
+}
+
+func TestTokenFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := (&Config{Type: TypeTokenFile, TokenFile: path}).NewSource(nil)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	if got := basicAuthOf(t, source); got.Password != "first" || got.Username != defaultUsername {
+		t.Errorf("got %s:%s", got.Username, got.Password)
+	}
+
+	if err := os.WriteFile(path, []byte("second-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := basicAuthOf(t, source); got.Password != "second-token" {
+		t.Errorf("after rotation got %q", got.Password)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.AuthMethod(t.Context()); err == nil {
+		t.Error("AuthMethod() error = nil after the file was removed")
+	}
+}
+
+func TestCredentialHelperSource(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "helper")
+	input := filepath.Join(dir, "input")
+	helper := "#!/bin/sh\ntest \"$1\" = get || exit 1\ncat > " + input + "\necho username=bot\necho password=s3cret\n"
+	if err := os.WriteFile(script, []byte(helper), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		helper string
+	}{
+		{name: "path", helper: script},
+		{name: "shell", helper: "!" + script},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Type: TypeCredentialHelper, CredentialHelper: tt.helper, URL: "https://git.example.com:8443/acme/webapp.git"}
+			source, err := cfg.NewSource(nil)
+			if err != nil {
+				t.Fatalf("NewSource() error = %v", err)
+			}
+			if got := basicAuthOf(t, source); got.Username != "bot" || got.Password != "s3cret" {
+				t.Errorf("got %s:%s", got.Username, got.Password)
+			}
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "protocol=https\nhost=git.example.com:8443\npath=acme/webapp.git\n\n"
+			if string(data) != want {
+				t.Errorf("helper input = %q, want %q", data, want)
+			}
+		})
+	}
+
+	failing := &Config{Type: TypeCredentialHelper, CredentialHelper: "!exit 1", URL: "https://example.com/repo.git"}
+	source, err := failing.NewSource(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.AuthMethod(t.Context()); err == nil {
+		t.Error("AuthMethod() error = nil for failing helper")
+	}
+}
@@ -0,0 +1,58 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+type Branch struct {
+	Name   string
+	Commit string
+}
+
+// ListBranches returns the remote branches whose names match pattern, other
+// than the branch the watcher follows. Patterns use the same syntax as path
+// filters, so "preview/*" matches one level and "preview/**" any depth.
+func (w *Watcher) ListBranches(ctx context.Context, pattern string) ([]Branch, error) {
+	if w.authErr != nil {
+		return nil, w.authErr
+	}
+	if err := ValidatePattern(pattern); err != nil {
+		return nil, fmt.Errorf("branch pattern %q: %w", pattern, err)
+	}
+	authMethod := w.auth
+	if w.credentials != nil {
+		var err error
+		if authMethod, err = w.credentials.AuthMethod(ctx); err != nil {
+			return nil, fmt.Errorf("resolve auth: %w", err)
+		}
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{w.repoURL}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: authMethod})
+	if err != nil {
+		return nil, fmt.Errorf("list branches: %w", err)
+	}
+
+	var branches []Branch
+	for _, ref := range refs {
+		if !ref.Name().IsBranch() || ref.Hash().IsZero() {
+			continue
+		}
+		name := ref.Name().Short()
+		if name == w.branch {
+			continue
+		}
+		if ok, _ := MatchPath(pattern, name); ok {
+			branches = append(branches, Branch{Name: name, Commit: ref.Hash().String()})
+		}
+	}
+	slices.SortFunc(branches, func(a, b Branch) int { return strings.Compare(a.Name, b.Name) })
+	return branches, nil
+}
@@ -0,0 +1,56 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func branchRemote(t *testing.T, tr *testRepo, name, commit string) {
+	t.Helper()
+	bare, err := git.PlainOpen(tr.bareRepoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), plumbing.NewHash(commit))
+	if err := bare.Storer.SetReference(ref); err != nil {
+		t.Fatalf("create branch %s: %v", name, err)
+	}
+}
+
+func TestWatcherListBranches(t *testing.T) {
+	tr := setupTestRepo(t)
+	first := tr.addCommit(t, testSecondCommit)
+	branchRemote(t, tr, "preview/login", first)
+	branchRemote(t, tr, "preview/team/search", first)
+	branchRemote(t, tr, "feature/x", first)
+
+	w := NewWatcher(tr.bareRepoPath, "master", filepath.Join(tr.tmpDir, testWorkDir), time.Second, nil)
+
+	branches, err := w.ListBranches(t.Context(), "preview/*")
+	if err != nil {
+		t.Fatalf("ListBranches() error = %v", err)
+	}
+	if len(branches) != 1 || branches[0].Name != "preview/login" || branches[0].Commit != first {
+		t.Errorf("ListBranches(preview/*) = %+v", branches)
+	}
+
+	branches, err = w.ListBranches(t.Context(), "**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(branches))
+	for _, b := range branches {
+		names = append(names, b.Name)
+	}
+	if len(names) != 3 || names[0] != "feature/x" || names[2] != "preview/team/search" {
+		t.Errorf("ListBranches(**) = %v, want every branch except master", names)
+	}
+
+	if _, err := w.ListBranches(t.Context(), "preview/["); err == nil {
+		t.Error("ListBranches() accepted a malformed pattern")
+	}
+}
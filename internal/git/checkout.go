@@ -0,0 +1,59 @@
+package git
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/samber/lo"
+)
+
+func sparseDirs(dirs []string) []string {
+	if len(dirs) == 0 {
+		return nil
+	}
+	out := []string{".gitmodules"}
+	for _, dir := range dirs {
+		dir = path.Clean(strings.TrimPrefix(strings.TrimSpace(dir), "/"))
+		if dir == "." {
+			return nil
+		}
+		out = append(out, dir)
+	}
+	return lo.Uniq(out)
+}
+
+func inSparse(dirs []string, name string) bool {
+	return dirs == nil || lo.SomeBy(dirs, func(dir string) bool {
+		return name == dir || strings.HasPrefix(name, dir+"/")
+	})
+}
+
+func (w *Watcher) recurseSubmodules() git.SubmoduleRescursivity {
+	return lo.Ternary(w.submodules, git.DefaultSubmoduleRecursionDepth, git.NoRecurseSubmodules)
+}
+
+func (w *Watcher) updateSubmodules(ctx context.Context, worktree *git.Worktree) error {
+	if !w.submodules {
+		return nil
+	}
+	subs, err := worktree.Submodules()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if !inSparse(w.sparse, sub.Config().Path) {
+			continue
+		}
+		err := sub.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+			Auth:              w.auth,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func commitFiles(t *testing.T, repo *git.Repository, dir string, files map[string]string, gitlinks map[string]plumbing.Hash) plumbing.Hash {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(gitlinks) > 0 {
+		idx, err := repo.Storer.Index()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, hash := range gitlinks {
+			if e, err := idx.Entry(name); err == nil {
+				e.Hash = hash
+				continue
+			}
+			idx.Entries = append(idx.Entries, &index.Entry{Name: name, Mode: filemode.Submodule, Hash: hash})
+		}
+		if err := repo.Storer.SetIndex(idx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := wt.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	return hash
+}
+
+func readFile(t *testing.T, name string) (string, bool) {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), true
+}
+
+func TestWatcherCheckoutOptions(t *testing.T) {
+	for _, submodules := range []bool{false, true} {
+		for _, depth := range []int{0, 1} {
+			for _, sparse := range []bool{false, true} {
+				t.Run(fmt.Sprintf("submodules=%v/depth=%d/sparse=%v", submodules, depth, sparse), func(t *testing.T) {
+					testCheckoutOptions(t, submodules, depth, sparse)
+				})
+			}
+		}
+	}
+}
+
+func testCheckoutOptions(t *testing.T, submodules bool, depth int, sparse bool) {
+	tr := setupTestRepo(t)
+
+	subPath := filepath.Join(tr.tmpDir, "shared")
+	sub, err := git.PlainInit(subPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subHash := commitFiles(t, sub, subPath, map[string]string{"shared.env": "v1"}, nil)
+
+	gitmodules := fmt.Sprintf("[submodule \"apps/web/shared\"]\n\tpath = apps/web/shared\n\turl = %s\n", subPath)
+	commitFiles(t, tr.clone, tr.clonePath, map[string]string{
+		".gitmodules":      gitmodules,
+		"kedge.yaml":       "name: test\n",
+		"apps/web/app.txt": "web v1",
+		"apps/api/app.txt": "api v1",
+	}, map[string]plumbing.Hash{"apps/web/shared": subHash})
+	if err := tr.clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var opts []WatcherOption
+	if submodules {
+		opts = append(opts, WithSubmodules())
+	}
+	if depth > 0 {
+		opts = append(opts, WithDepth(depth))
+	}
+	if sparse {
+		opts = append(opts, WithSparseCheckout([]string{"apps/web", "kedge.yaml"}))
+	}
+	workDir := filepath.Join(tr.tmpDir, testWorkDir)
+	w := NewWatcher(tr.bareRepoPath, "master", workDir, time.Second, nil, opts...)
+	ctx := t.Context()
+	if err := w.Clone(ctx); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+
+	if shallow, _ := w.repo.Storer.Shallow(); (len(shallow) > 0) != (depth > 0) {
+		t.Errorf("shallow commits = %v", shallow)
+	}
+	check := func(web, shared string) {
+		t.Helper()
+		if got, _ := readFile(t, filepath.Join(workDir, "apps/web/app.txt")); got != web {
+			t.Errorf("apps/web/app.txt = %q, want %q", got, web)
+		}
+		if _, ok := readFile(t, filepath.Join(workDir, "kedge.yaml")); !ok {
+			t.Error("kedge.yaml missing")
+		}
+		if _, ok := readFile(t, filepath.Join(workDir, "apps/api/app.txt")); ok == sparse {
+			t.Errorf("apps/api/app.txt present = %v with sparse = %v", ok, sparse)
+		}
+		got, _ := readFile(t, filepath.Join(workDir, "apps/web/shared/shared.env"))
+		if !submodules {
+			shared = ""
+		}
+		if got != shared {
+			t.Errorf("shared.env = %q, want %q", got, shared)
+		}
+	}
+	check("web v1", "v1")
+
+	subHash = commitFiles(t, sub, subPath, map[string]string{"shared.env": "v2"}, nil)
+	commitFiles(t, tr.clone, tr.clonePath, map[string]string{"apps/web/app.txt": "web v2", "apps/api/app.txt": "api v2"},
+		map[string]plumbing.Hash{"apps/web/shared": subHash})
+	if err := tr.clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	changed, _, err := w.Pull(ctx)
+	if err != nil || !changed {
+		t.Fatalf("Pull = %v, %v", changed, err)
+	}
+	check("web v2", "v2")
+
+	if err := os.WriteFile(filepath.Join(workDir, "apps/web/app.txt"), []byte("local"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tr.addCommit(t, "remote commit")
+	changed, _, err = w.Pull(ctx)
+	if err != nil || !changed {
+		t.Fatalf("Pull with dirty worktree = %v, %v", changed, err)
+	}
+	check("web v2", "v2")
+}
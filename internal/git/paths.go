@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/samber/lo"
+)
+
+var ErrNoBaseCommit = errors.New("no base commit to diff against")
+
+func MatchPath(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func ValidatePattern(pattern string) error {
+	for segment := range strings.SplitSeq(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if ok, err := matchSegments(pattern[1:], name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+func (w *Watcher) ChangedPaths(from, to string) ([]string, error) {
+	if from == "" {
+		return nil, ErrNoBaseCommit
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	fromTree, err := w.tree(from)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := w.tree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(changes))
+	for _, change := range changes {
+		paths = append(paths, change.From.Name, change.To.Name)
+	}
+	return lo.Uniq(lo.Compact(paths)), nil
+}
+
+func (w *Watcher) tree(hash string) (*object.Tree, error) {
+	commit, err := w.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"docker-compose.yaml", "docker-compose.yaml", true},
+		{"*.yaml", "docker-compose.yaml", true},
+		{"*.yaml", "config/app.yaml", false},
+		{"config/*", "config/app.yaml", true},
+		{"config/**", "config/nested/app.yaml", true},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "docs/guide/intro.md", true},
+		{"docs/**/*.md", "docs/intro.md", true},
+		{"docs/**", "src/docs/intro.md", false},
+	}
+	for _, tt := range tests {
+		got, err := MatchPath(tt.pattern, tt.name)
+		if err != nil {
+			t.Fatalf("MatchPath(%q, %q) error = %v", tt.pattern, tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+
+	if err := ValidatePattern("config/[a"); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestWatcherChangedPaths(t *testing.T) {
+	tr := setupTestRepo(t)
+
+	w := NewWatcher(tr.bareRepoPath, "master", filepath.Join(tr.tmpDir, testWorkDir), time.Second, nil)
+	if err := w.Clone(t.Context()); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+	initial := w.LastCommit()
+
+	docs := filepath.Join(tr.clonePath, "docs")
+	if err := os.MkdirAll(docs, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(docs, "intro.md"), []byte("intro"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.worktree.Add("docs/intro.md"); err != nil {
+		t.Fatal(err)
+	}
+	head := tr.addCommit(t, testSecondCommit)
+
+	if _, _, err := w.Pull(t.Context()); err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	paths, err := w.ChangedPaths(initial, head)
+	if err != nil {
+		t.Fatalf("ChangedPaths() error = %v", err)
+	}
+	slices.Sort(paths)
+	if !slices.Equal(paths, []string{"docs/intro.md", testFileName}) {
+		t.Errorf("ChangedPaths() = %v", paths)
+	}
+
+	if _, err := w.ChangedPaths("", head); err == nil {
+		t.Error("expected error without a base commit")
+	}
+}
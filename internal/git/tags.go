@@ -0,0 +1,246 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"unicode"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/samber/lo"
+	"golang.org/x/mod/semver"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+)
+
+var (
+	ErrInvalidConstraint = errors.New("invalid semver constraint")
+	ErrNoMatchingTag     = errors.New("no tag matches")
+)
+
+// Constraint selects tag versions using the Masterminds/semver range syntax.
+type Constraint struct {
+	constraints *mmsemver.Constraints
+}
+
+type Tag struct {
+	Name    string
+	Version string
+	Commit  plumbing.Hash
+}
+
+func ParseConstraint(s string) (*Constraint, error) {
+	constraints, err := mmsemver.NewConstraint(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConstraint, err)
+	}
+	return &Constraint{constraints: constraints}, nil
+}
+
+// Check reports whether version satisfies c. A nil constraint accepts every
+// release and no pre-release.
+func (c *Constraint) Check(version string) bool {
+	if c == nil {
+		return semver.Prerelease(version) == ""
+	}
+	v, err := mmsemver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.constraints.Check(v)
+}
+
+func TagVersion(name string) (string, bool) {
+	version := "v" + strings.TrimLeftFunc(name, func(r rune) bool { return !unicode.IsDigit(r) })
+	if !semver.IsValid(version) {
+		return "", false
+	}
+	return semver.Canonical(version), true
+}
+
+func ValidTagPattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+func SelectTag(tags []Tag, pattern string, constraint *Constraint) (Tag, error) {
+	var best Tag
+	for _, tag := range tags {
+		if ok, _ := path.Match(pattern, tag.Name); !ok {
+			continue
+		}
+		version, ok := TagVersion(tag.Name)
+		if !ok || !constraint.Check(version) {
+			continue
+		}
+		tag.Version = version
+		if best.Name == "" || semver.Compare(version, best.Version) > 0 ||
+			(semver.Compare(version, best.Version) == 0 && tag.Name > best.Name) {
+			best = tag
+		}
+	}
+	if best.Name == "" {
+		return Tag{}, fmt.Errorf("%w %q", ErrNoMatchingTag, pattern)
+	}
+	return best, nil
+}
+
+func (w *Watcher) CurrentTag() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.currentTag
+}
+
+func (w *Watcher) setCurrentTag(tag string) {
+	w.mu.Lock()
+	w.currentTag = tag
+	w.mu.Unlock()
+}
+
+func (w *Watcher) cloneTags(ctx context.Context) error {
+	repo, err := git.PlainCloneContext(ctx, w.workDir, false, &git.CloneOptions{
+		URL:        w.repoURL,
+		Tags:       git.AllTags,
+		Depth:      w.depth,
+		NoCheckout: true,
+		Auth:       w.auth,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.repo = repo
+	_, _, err = w.checkoutTag(ctx)
+	return err
+}
+
+func (w *Watcher) pullTags(ctx context.Context) (bool, string, error) {
+	err := w.repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/tags/*:refs/tags/*"},
+		Tags:       git.NoTags,
+		Depth:      w.depth,
+		Prune:      true,
+		Force:      true,
+		Auth:       w.auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return false, "", err
+	}
+	return w.checkoutTag(ctx)
+}
+
+func (w *Watcher) checkoutTag(ctx context.Context) (bool, string, error) {
+	prev := w.LastCommit()
+	current := w.CurrentTag()
+
+	tags, err := w.tags()
+	if err != nil {
+		return false, "", err
+	}
+	tag, err := SelectTag(tags, w.tagPattern, w.constraint)
+	if err != nil {
+		if current == "" {
+			return false, "", err
+		}
+		w.refuse(current, "", "no tag matches; staying on the deployed tag")
+		return false, prev, nil
+	}
+
+	if current != "" && tag.Name != current {
+		if version, ok := TagVersion(current); ok && semver.Compare(tag.Version, version) < 0 {
+			reason := "highest matching tag is older than the deployed tag"
+			if !lo.ContainsBy(tags, func(t Tag) bool { return t.Name == current }) {
+				reason = "deployed tag was deleted"
+			}
+			w.refuse(current, tag.Name, reason)
+			return false, prev, nil
+		}
+	}
+	if tag.Name == current && tag.Commit.String() != prev {
+		w.logger.Warn("tag moved to a different commit", slog.String("tag", tag.Name), slog.String("commit", tag.Commit.String()))
+	}
+
+	if tag.Commit.String() != prev {
+		worktree, err := w.repo.Worktree()
+		if err != nil {
+			return false, "", err
+		}
+		if err := worktree.Checkout(&git.CheckoutOptions{Hash: tag.Commit, Force: true, SparseCheckoutDirectories: w.sparse}); err != nil {
+			return false, "", fmt.Errorf("checkout %s: %w", tag.Name, err)
+		}
+		if err := w.updateSubmodules(ctx, worktree); err != nil {
+			return false, "", err
+		}
+		if err := w.updateLastCommit(); err != nil {
+			return false, "", err
+		}
+	}
+
+	w.mu.Lock()
+	w.currentTag = tag.Name
+	w.refused = ""
+	w.mu.Unlock()
+	return tag.Commit.String() != prev, tag.Commit.String(), nil
+}
+
+func (w *Watcher) refuse(current, candidate, reason string) {
+	key := current + "\x00" + candidate
+	w.mu.Lock()
+	repeated := w.refused == key
+	w.refused = key
+	w.mu.Unlock()
+	if repeated {
+		return
+	}
+
+	w.logger.Warn("not downgrading", slog.String("tag", current), slog.String("candidate", candidate), slog.String("reason", reason))
+	w.bus.Publish(bus.Event{
+		Type: bus.TypeTagDowngradeRefused,
+		Repo: w.repoName,
+		Data: map[string]any{"tag": current, "candidate": candidate, "reason": reason},
+	})
+}
+
+func (w *Watcher) tags() ([]Tag, error) {
+	iter, err := w.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var tags []Tag
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		if obj, err := w.repo.TagObject(hash); err == nil {
+			commit, err := obj.Commit()
+			if err != nil {
+				return nil
+			}
+			hash = commit.Hash
+		}
+		tags = append(tags, Tag{Name: ref.Name().Short(), Commit: hash})
+		return nil
+	})
+	return tags, err
+}
+
+func (w *Watcher) tagAtHead() string {
+	head := plumbing.NewHash(w.LastCommit())
+	tags, err := w.tags()
+	if err != nil {
+		return ""
+	}
+	tags = lo.Filter(tags, func(t Tag, _ int) bool { return t.Commit == head })
+	tag, err := SelectTag(tags, w.tagPattern, w.constraint)
+	if err != nil {
+		return ""
+	}
+	return tag.Name
+}
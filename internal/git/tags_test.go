@@ -0,0 +1,193 @@
+package git
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=1.2.0, <2.0.0", "v1.4.0", true},
+		{">=1.2.0, <2.0.0", "v2.0.0", false},
+		{">= 1.2.0 < 2", "v1.1.9", false},
+		{"=>1.2.0", "v1.2.0", true},
+		{"^1.2.0", "v1.9.0", true},
+		{"^1.2.0", "v2.0.0-rc.1", false},
+		{"^0.2.3", "v0.2.9", true},
+		{"^0.2.3", "v0.3.0", false},
+		{"^0.0.3", "v0.0.3", true},
+		{"^0.0.3", "v0.0.4", false},
+		{"^0", "v0.9.0", true},
+		{"^0", "v1.0.0", false},
+		{"~1.2.3", "v1.2.9", true},
+		{"~1.2.3", "v1.3.0", false},
+		{"~1", "v1.9.0", true},
+		{"!=1.2.3", "v1.2.3", false},
+		{"1.2", "v1.2.0", true},
+		{"1.2.x", "v1.2.7", true},
+		{"1.2 - 1.4", "v1.4.9", true},
+		{"1.2 - 1.4", "v1.5.0", false},
+		{"<1.0.0 || >=2.0.0", "v1.5.0", false},
+		{"<1.0.0 || >=2.0.0", "v2.1.0", true},
+		{">=1.0.0", "v1.5.0-rc.1", false},
+		{"*", "v3.0.0-rc.1", false},
+		{"^1.2.0", "v1.2.1-rc.1", false},
+		{">=1.5.0-0", "v1.5.0-rc.1", true},
+		{">=1.2.0-rc.1, <1.3.0", "v1.2.0-rc.2", true},
+		{">=1.2.0-rc.1, <1.3.0", "v1.2.0", true},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) error = %v", tt.constraint, err)
+		}
+		if got := c.Check(tt.version); got != tt.want {
+			t.Errorf("%q.Check(%s) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", " ", ">=", ">=one", ">>1.0.0", "!1.0.0", "1.2.3.4", "^", ">=1.0.0 ||", "1.0.0, ", "1.2.3 - ", "latest"} {
+		if _, err := ParseConstraint(bad); !errors.Is(err, ErrInvalidConstraint) {
+			t.Errorf("ParseConstraint(%q) error = %v, want ErrInvalidConstraint", bad, err)
+		}
+	}
+
+	var none *Constraint
+	if !none.Check("v1.0.0") || none.Check("v1.1.0-rc.1") {
+		t.Error("a nil constraint should accept releases and reject pre-releases")
+	}
+}
+
+func TestSelectTag(t *testing.T) {
+	tags := []Tag{{Name: "v1.2.0"}, {Name: "v1.10.0"}, {Name: "v2.0.0-rc.1"}, {Name: "latest"}, {Name: "api-v3.0.0"}}
+
+	tag, err := SelectTag(tags, "v*", nil)
+	if err != nil || tag.Name != "v1.10.0" {
+		t.Errorf("SelectTag() = %v, %v; want v1.10.0", tag.Name, err)
+	}
+
+	tag, err = SelectTag(tags, "api-v*", nil)
+	if err != nil || tag.Name != "api-v3.0.0" {
+		t.Errorf("SelectTag(api-v*) = %v, %v; want api-v3.0.0", tag.Name, err)
+	}
+
+	c, err := ParseConstraint("<1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err = SelectTag(tags, "v*", c)
+	if err != nil || tag.Name != "v1.2.0" {
+		t.Errorf("SelectTag(<1.5) = %v, %v; want v1.2.0", tag.Name, err)
+	}
+
+	if _, err := SelectTag(tags, "release-*", nil); err == nil {
+		t.Error("expected error when nothing matches")
+	}
+}
+
+func tagRemote(t *testing.T, tr *testRepo, name, commit string) {
+	t.Helper()
+	bare, err := git.PlainOpen(tr.bareRepoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bare.CreateTag(name, plumbing.NewHash(commit), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		Message: name,
+	})
+	if err != nil {
+		t.Fatalf("create tag %s: %v", name, err)
+	}
+}
+
+func deleteRemoteTag(t *testing.T, tr *testRepo, name string) {
+	t.Helper()
+	bare, err := git.PlainOpen(tr.bareRepoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bare.DeleteTag(name); err != nil {
+		t.Fatalf("delete tag %s: %v", name, err)
+	}
+}
+
+func TestWatcherTags(t *testing.T) {
+	tr := setupTestRepo(t)
+	head, err := tr.clone.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := head.Hash().String()
+	tagRemote(t, tr, "v1.0.0", first)
+
+	w := NewWatcher(tr.bareRepoPath, "", filepath.Join(tr.tmpDir, testWorkDir), time.Second, nil, WithTags("v*", "<2.0.0"))
+	ctx := t.Context()
+	if err := w.Clone(ctx); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+	if w.CurrentTag() != "v1.0.0" || w.LastCommit() != first {
+		t.Fatalf("after clone tag = %s commit = %s", w.CurrentTag(), w.LastCommit())
+	}
+
+	second := tr.addCommit(t, testSecondCommit)
+	third := tr.addCommit(t, "third commit")
+	tagRemote(t, tr, "v1.1.0", second)
+	tagRemote(t, tr, "v2.0.0", third)
+
+	changed, hash, err := w.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if !changed || hash != second || w.CurrentTag() != "v1.1.0" {
+		t.Errorf("Pull = %v, %s, tag %s; want v1.1.0 at %s", changed, hash, w.CurrentTag(), second)
+	}
+
+	deleteRemoteTag(t, tr, "v1.1.0")
+	changed, hash, err = w.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Pull after delete failed: %v", err)
+	}
+	if changed || hash != second || w.CurrentTag() != "v1.1.0" {
+		t.Errorf("expected no downgrade after delete, got %v, %s, tag %s", changed, hash, w.CurrentTag())
+	}
+
+	tagRemote(t, tr, "v1.2.0", third)
+	changed, hash, err = w.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Pull after new tag failed: %v", err)
+	}
+	if !changed || hash != third || w.CurrentTag() != "v1.2.0" {
+		t.Errorf("Pull = %v, %s, tag %s; want v1.2.0 at %s", changed, hash, w.CurrentTag(), third)
+	}
+
+	restarted := NewWatcher(tr.bareRepoPath, "", w.WorkDir(), time.Second, nil, WithTags("v*", "<2.0.0"))
+	if err := restarted.Clone(ctx); err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if restarted.CurrentTag() != "v1.2.0" || restarted.LastCommit() != third {
+		t.Errorf("after reopen tag = %s commit = %s", restarted.CurrentTag(), restarted.LastCommit())
+	}
+}
+
+func TestWatcherTagsNoMatch(t *testing.T) {
+	tr := setupTestRepo(t)
+	w := NewWatcher(tr.bareRepoPath, "", filepath.Join(tr.tmpDir, testWorkDir), time.Second, nil, WithTags("v*", ""))
+	if err := w.Clone(t.Context()); err == nil {
+		t.Error("expected clone to fail without a matching tag")
+	}
+
+	w = NewWatcher(tr.bareRepoPath, "", filepath.Join(tr.tmpDir, "other"), time.Second, nil, WithTags("v*", ">=x"))
+	if err := w.Clone(t.Context()); err == nil {
+		t.Error("expected clone to fail with an invalid constraint")
+	}
+}
@@ -0,0 +1,243 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrUnsigned  = errors.New("not signed")
+	ErrUntrusted = errors.New("not signed by a trusted key")
+	ErrNoKeys    = errors.New("no trusted keys found")
+)
+
+const (
+	pgpBegin     = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpEnd       = "-----END PGP PUBLIC KEY BLOCK-----"
+	sshSigBegin  = "-----BEGIN SSH SIGNATURE-----"
+	sshSigMagic  = "SSHSIG"
+	sshNamespace = "git"
+)
+
+type Keyring struct {
+	pgp openpgp.EntityList
+	ssh []ssh.PublicKey
+}
+
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is configured by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read trusted keys: %w", err)
+	}
+	return ParseKeyring(data)
+}
+
+func ParseKeyring(data []byte) (*Keyring, error) {
+	k := &Keyring{}
+	var block strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == pgpBegin || block.Len() > 0:
+			block.WriteString(line + "\n")
+			if line != pgpEnd {
+				continue
+			}
+			entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(block.String()))
+			if err != nil {
+				return nil, fmt.Errorf("parse PGP key: %w", err)
+			}
+			k.pgp = append(k.pgp, entities...)
+			block.Reset()
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			key, err := parseSSHKey(line)
+			if err != nil {
+				return nil, fmt.Errorf("parse SSH key %q: %w", line, err)
+			}
+			k.ssh = append(k.ssh, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(k.pgp) == 0 && len(k.ssh) == 0 {
+		return nil, ErrNoKeys
+	}
+	return k, nil
+}
+
+func parseSSHKey(line string) (ssh.PublicKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err == nil {
+		return key, nil
+	}
+	if _, rest, ok := strings.Cut(line, " "); ok {
+		if key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(rest)); err == nil {
+			return key, nil
+		}
+	}
+	return nil, err
+}
+
+func (k *Keyring) Verify(signature string, payload []byte) (string, error) {
+	switch {
+	case signature == "":
+		return "", ErrUnsigned
+	case strings.HasPrefix(signature, sshSigBegin):
+		return k.verifySSH(signature, payload)
+	}
+
+	entity, err := openpgp.CheckArmoredDetachedSignature(k.pgp, bytes.NewReader(payload), strings.NewReader(signature), nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrusted, err)
+	}
+	if identity := entity.PrimaryIdentity(); identity != nil {
+		return identity.Name, nil
+	}
+	return entity.PrimaryKey.KeyIdString(), nil
+}
+
+func (k *Keyring) verifySSH(signature string, payload []byte) (string, error) {
+	block, _ := pem.Decode([]byte(signature))
+	if block == nil || !bytes.HasPrefix(block.Bytes, []byte(sshSigMagic)) {
+		return "", fmt.Errorf("%w: malformed SSH signature", ErrUntrusted)
+	}
+	var sig sshSignature
+	if err := ssh.Unmarshal(block.Bytes[len(sshSigMagic):], &sig); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrusted, err)
+	}
+	if sig.Namespace != sshNamespace {
+		return "", fmt.Errorf("%w: unexpected namespace %q", ErrUntrusted, sig.Namespace)
+	}
+
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrusted, err)
+	}
+	trusted := false
+	for _, key := range k.ssh {
+		if bytes.Equal(key.Marshal(), pub.Marshal()) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return "", fmt.Errorf("%w: %s", ErrUntrusted, ssh.FingerprintSHA256(pub))
+	}
+
+	var hash []byte
+	switch sig.HashAlgorithm {
+	case "sha256":
+		sum := sha256.Sum256(payload)
+		hash = sum[:]
+	case "sha512":
+		sum := sha512.Sum512(payload)
+		hash = sum[:]
+	default:
+		return "", fmt.Errorf("%w: unsupported hash %q", ErrUntrusted, sig.HashAlgorithm)
+	}
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          hash,
+	})...)
+
+	var s ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &s); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrusted, err)
+	}
+	if err := pub.Verify(signed, &s); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrusted, err)
+	}
+	return ssh.FingerprintSHA256(pub), nil
+}
+
+func (w *Watcher) VerifyCommit(hash string, keys *Keyring) (string, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	commit, err := w.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return "", err
+	}
+	payload, err := encodeWithoutSignature(commit.EncodeWithoutSignature)
+	if err != nil {
+		return "", err
+	}
+	signer, err := keys.Verify(commit.PGPSignature, payload)
+	if err != nil {
+		return "", fmt.Errorf("commit %s %w", hex.EncodeToString(commit.Hash[:4]), err)
+	}
+	return signer, nil
+}
+
+func (w *Watcher) VerifyTag(name string, keys *Keyring) (string, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	ref, err := w.repo.Tag(name)
+	if err != nil {
+		return "", err
+	}
+	tag, err := w.repo.TagObject(ref.Hash())
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return "", fmt.Errorf("tag %s is %w: lightweight tags cannot be signed", name, ErrUnsigned)
+	}
+	if err != nil {
+		return "", err
+	}
+	payload, err := encodeWithoutSignature(tag.EncodeWithoutSignature)
+	if err != nil {
+		return "", err
+	}
+	signer, err := keys.Verify(tag.PGPSignature, payload)
+	if err != nil {
+		return "", fmt.Errorf("tag %s %w", name, err)
+	}
+	return signer, nil
+}
+
+func encodeWithoutSignature(encode func(plumbing.EncodedObject) error) ([]byte, error) {
+	obj := &plumbing.MemoryObject{}
+	if err := encode(obj); err != nil {
+		return nil, err
+	}
+	r, err := obj.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
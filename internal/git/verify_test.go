@@ -0,0 +1,204 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+type sshTestSigner struct {
+	signer ssh.Signer
+}
+
+func (s sshTestSigner) Sign(message io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha512.Sum512(data)
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSignedData{Namespace: sshNamespace, HashAlgorithm: "sha512", Hash: hash[:]})...)
+	sig, err := s.signer.Sign(rand.Reader, signed)
+	if err != nil {
+		return nil, err
+	}
+	blob := append([]byte(sshSigMagic), ssh.Marshal(sshSignature{
+		Version:       1,
+		PublicKey:     s.signer.PublicKey().Marshal(),
+		Namespace:     sshNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(sig),
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}), nil
+}
+
+func newSSHTestSigner(t *testing.T) sshTestSigner {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sshTestSigner{signer: signer}
+}
+
+func newPGPTestEntity(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Release Bot", "", "release@test.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return entity, buf.String()
+}
+
+func (r *testRepo) addSignedCommit(t *testing.T, message string, opts *git.CommitOptions) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(r.clonePath, testFileName), []byte(message), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.worktree.Add(testFileName); err != nil {
+		t.Fatal(err)
+	}
+	opts.Author = &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()}
+	hash, err := r.worktree.Commit(message, opts)
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := r.clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	return hash.String()
+}
+
+func TestParseKeyring(t *testing.T) {
+	_, armored := newPGPTestEntity(t)
+	signer := newSSHTestSigner(t)
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.signer.PublicKey())))
+
+	data := strings.Join([]string{"# release keys", armored, authorized + " ci", "", "dev@test.com " + authorized}, "\n")
+	keys, err := ParseKeyring([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	if len(keys.pgp) != 1 || len(keys.ssh) != 2 {
+		t.Errorf("got %d PGP and %d SSH keys", len(keys.pgp), len(keys.ssh))
+	}
+
+	if _, err := ParseKeyring([]byte("# nothing here\n")); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("expected ErrNoKeys, got %v", err)
+	}
+	if _, err := ParseKeyring([]byte("not a key\n")); err == nil {
+		t.Error("expected error for garbage line")
+	}
+}
+
+func TestWatcherVerifyCommit(t *testing.T) {
+	tr := setupTestRepo(t)
+	entity, armored := newPGPTestEntity(t)
+	sshSigner := newSSHTestSigner(t)
+	untrusted := newSSHTestSigner(t)
+
+	keys, err := ParseKeyring([]byte(armored + "\n" + string(ssh.MarshalAuthorizedKey(sshSigner.signer.PublicKey()))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pgpSigned := tr.addSignedCommit(t, "pgp signed", &git.CommitOptions{SignKey: entity})
+	sshSigned := tr.addSignedCommit(t, "ssh signed", &git.CommitOptions{Signer: sshSigner})
+	foreign := tr.addSignedCommit(t, "foreign key", &git.CommitOptions{Signer: untrusted})
+	unsigned := tr.addCommit(t, "unsigned")
+
+	w := NewWatcher(tr.bareRepoPath, "master", filepath.Join(tr.tmpDir, testWorkDir), time.Second, nil)
+	if err := w.Clone(t.Context()); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+
+	if signer, err := w.VerifyCommit(pgpSigned, keys); err != nil || signer != "Release Bot <release@test.com>" {
+		t.Errorf("PGP commit: signer %q, err %v", signer, err)
+	}
+	if signer, err := w.VerifyCommit(sshSigned, keys); err != nil || !strings.HasPrefix(signer, "SHA256:") {
+		t.Errorf("SSH commit: signer %q, err %v", signer, err)
+	}
+	if _, err := w.VerifyCommit(foreign, keys); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("expected ErrUntrusted, got %v", err)
+	}
+	if _, err := w.VerifyCommit(unsigned, keys); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned, got %v", err)
+	}
+}
+
+func TestWatcherVerifyTag(t *testing.T) {
+	tr := setupTestRepo(t)
+	entity, armored := newPGPTestEntity(t)
+	keys, err := ParseKeyring([]byte(armored))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := tr.clone.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bare, err := git.PlainOpen(tr.bareRepoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bare.CreateTag("v1.0.0", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		Message: "v1.0.0",
+		SignKey: entity,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagRemote(t, tr, "v1.0.1", head.Hash().String())
+	if _, err := bare.CreateTag("v1.0.2", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher(tr.bareRepoPath, "", filepath.Join(tr.tmpDir, testWorkDir), time.Second, nil, WithTags("v*", ""))
+	if err := w.Clone(t.Context()); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+
+	if _, err := w.VerifyTag("v1.0.0", keys); err != nil {
+		t.Errorf("signed tag: %v", err)
+	}
+	if _, err := w.VerifyTag("v1.0.1", keys); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned for unsigned annotated tag, got %v", err)
+	}
+	if _, err := w.VerifyTag("v1.0.2", keys); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned for lightweight tag, got %v", err)
+	}
+	if _, err := w.VerifyCommit(head.Hash().String(), keys); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned for commit, got %v", err)
+	}
+}
@@ -11,16 +11,25 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/LoriKarikari/kedge/internal/bus"
 	"github.com/LoriKarikari/kedge/internal/git/auth"
 	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
+const maxPendingCommits = 1000
+
 type ChangeEvent struct {
 	Commit    string
 	Message   string
 	Timestamp time.Time
+	Paths     []string
+	Tag       string
 }
 
 type Watcher struct {
@@ -31,12 +40,23 @@ type Watcher struct {
 	pollInterval time.Duration
 	repo         *git.Repository
 	metrics      *telemetry.Metrics
+	bus          *bus.Bus
 	logger       *slog.Logger
 	auth         transport.AuthMethod
+	credentials  auth.Source
 	authErr      error
+	tagPattern   string
+	constraint   *Constraint
+	tagErr       error
+	submodules   bool
+	depth        int
+	sparse       []string
 
 	mu         sync.RWMutex
 	lastCommit string
+	currentTag string
+	refused    string
+	reset      chan struct{}
 }
 
 type WatcherOption func(*Watcher)
@@ -47,6 +67,12 @@ func WithMetrics(metrics *telemetry.Metrics) WatcherOption {
 	}
 }
 
+func WithEventBus(b *bus.Bus) WatcherOption {
+	return func(w *Watcher) {
+		w.bus = b
+	}
+}
+
 func WithRepoName(name string) WatcherOption {
 	return func(w *Watcher) {
 		w.repoName = name
@@ -58,12 +84,55 @@ func WithAuth(authCfg *auth.Config, logger *slog.Logger) WatcherOption {
 		if authCfg == nil || authCfg.IsEmpty() {
 			return
 		}
-		authMethod, err := authCfg.Resolve(logger)
+		source, err := authCfg.NewSource(logger)
 		if err != nil {
 			w.authErr = fmt.Errorf("resolve auth: %w", err)
 			return
 		}
-		w.auth = authMethod
+		w.credentials = source
+	}
+}
+
+func WithCredentials(source auth.Source) WatcherOption {
+	return func(w *Watcher) {
+		w.credentials = source
+	}
+}
+
+func WithTags(pattern, constraint string) WatcherOption {
+	return func(w *Watcher) {
+		if err := ValidTagPattern(pattern); err != nil {
+			w.tagErr = fmt.Errorf("tag pattern %q: %w", pattern, err)
+			return
+		}
+		w.tagPattern = pattern
+		if constraint == "" {
+			return
+		}
+		c, err := ParseConstraint(constraint)
+		if err != nil {
+			w.tagErr = err
+			return
+		}
+		w.constraint = c
+	}
+}
+
+func WithSubmodules() WatcherOption {
+	return func(w *Watcher) {
+		w.submodules = true
+	}
+}
+
+func WithDepth(depth int) WatcherOption {
+	return func(w *Watcher) {
+		w.depth = max(depth, 0)
+	}
+}
+
+func WithSparseCheckout(dirs []string) WatcherOption {
+	return func(w *Watcher) {
+		w.sparse = sparseDirs(dirs)
 	}
 }
 
@@ -77,6 +146,7 @@ func NewWatcher(repoURL, branch, workDir string, pollInterval time.Duration, log
 		workDir:      workDir,
 		pollInterval: pollInterval,
 		logger:       logger.With(slog.String("component", "watcher")),
+		reset:        make(chan struct{}, 1),
 	}
 	for _, opt := range opts {
 		opt(w)
@@ -88,36 +158,101 @@ func (w *Watcher) Clone(ctx context.Context) error {
 	if w.authErr != nil {
 		return w.authErr
 	}
+	if w.tagErr != nil {
+		return w.tagErr
+	}
 
 	if _, err := os.Stat(w.workDir); err == nil {
-		repo, err := git.PlainOpen(w.workDir)
-		if err != nil {
-			return fmt.Errorf("failed to open existing repo at %s: %w", w.workDir, err)
+		if err := w.open(); err != nil {
+			return err
 		}
-		w.repo = repo
 		_, _, err = w.Pull(ctx)
 		return err
 	}
 
+	if err := w.refreshAuth(ctx); err != nil {
+		return err
+	}
+	if w.tagPattern != "" {
+		return w.cloneTags(ctx)
+	}
 	return w.clone(ctx)
 }
 
+// Open loads an existing checkout without fetching, so that the commit and
+// tag it has checked out can be inspected and verified.
+func (w *Watcher) Open() error {
+	if w.tagErr != nil {
+		return w.tagErr
+	}
+	return w.open()
+}
+
+func (w *Watcher) open() error {
+	repo, err := git.PlainOpen(w.workDir)
+	if err != nil {
+		return fmt.Errorf("failed to open existing repo at %s: %w", w.workDir, err)
+	}
+	w.repo = repo
+	if err := w.updateLastCommit(); err != nil {
+		return err
+	}
+	if w.tagPattern != "" {
+		w.setCurrentTag(w.tagAtHead())
+	}
+	return nil
+}
+
 func (w *Watcher) clone(ctx context.Context) error {
 	repo, err := git.PlainCloneContext(ctx, w.workDir, false, &git.CloneOptions{
-		URL:           w.repoURL,
-		ReferenceName: plumbing.NewBranchReferenceName(w.branch),
-		SingleBranch:  true,
-		Auth:          w.auth,
+		URL:               w.repoURL,
+		ReferenceName:     plumbing.NewBranchReferenceName(w.branch),
+		SingleBranch:      true,
+		Depth:             w.depth,
+		NoCheckout:        w.sparse != nil,
+		RecurseSubmodules: w.recurseSubmodules(),
+		Auth:              w.auth,
 	})
 	if err != nil {
 		return err
 	}
 
 	w.repo = repo
+	if w.sparse != nil {
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return err
+		}
+		err = worktree.Checkout(&git.CheckoutOptions{
+			Branch:                    plumbing.NewBranchReferenceName(w.branch),
+			Force:                     true,
+			SparseCheckoutDirectories: w.sparse,
+		})
+		if err != nil {
+			return err
+		}
+		if err := w.updateSubmodules(ctx, worktree); err != nil {
+			return err
+		}
+	}
 	return w.updateLastCommit()
 }
 
 func (w *Watcher) Pull(ctx context.Context) (changed bool, hash string, err error) {
+	ref := lo.Ternary(w.tagPattern != "", attribute.String("kedge.tag_pattern", w.tagPattern), attribute.String("kedge.branch", w.branch))
+	ctx, span := telemetry.StartSpan(ctx, "Watcher.Pull", telemetry.AttrRepo.String(w.repoName), ref)
+	defer func() {
+		span.SetAttributes(telemetry.AttrCommit.String(hash), attribute.Bool("kedge.changed", changed))
+		telemetry.EndSpan(span, err)
+	}()
+
+	if err := w.refreshAuth(ctx); err != nil {
+		return false, "", err
+	}
+	if w.tagPattern != "" {
+		return w.pullTags(ctx)
+	}
+
 	worktree, err := w.repo.Worktree()
 	if err != nil {
 		return false, "", err
@@ -125,12 +260,19 @@ func (w *Watcher) Pull(ctx context.Context) (changed bool, hash string, err erro
 
 	prevCommit := w.LastCommit()
 
-	err = worktree.PullContext(ctx, &git.PullOptions{
-		RemoteName:    "origin",
-		ReferenceName: plumbing.NewBranchReferenceName(w.branch),
-		SingleBranch:  true,
-		Auth:          w.auth,
-	})
+	if w.sparse != nil {
+		// Pull checks out the whole tree, so sparse checkouts always reset.
+		err = w.hardReset(ctx)
+	} else {
+		err = worktree.PullContext(ctx, &git.PullOptions{
+			RemoteName:        "origin",
+			ReferenceName:     plumbing.NewBranchReferenceName(w.branch),
+			SingleBranch:      true,
+			Depth:             w.depth,
+			RecurseSubmodules: w.recurseSubmodules(),
+			Auth:              w.auth,
+		})
+	}
 
 	if err == nil || errors.Is(err, git.NoErrAlreadyUpToDate) {
 		if err := w.updateLastCommit(); err != nil {
@@ -155,6 +297,18 @@ func (w *Watcher) Pull(ctx context.Context) (changed bool, hash string, err erro
 	return false, "", err
 }
 
+func (w *Watcher) refreshAuth(ctx context.Context) error {
+	if w.credentials == nil {
+		return nil
+	}
+	method, err := w.credentials.AuthMethod(ctx)
+	if err != nil {
+		return fmt.Errorf("resolve auth: %w", err)
+	}
+	w.auth = method
+	return nil
+}
+
 func isRecoverableError(err error) bool {
 	return errors.Is(err, git.ErrNonFastForwardUpdate) ||
 		errors.Is(err, git.ErrUnstagedChanges) ||
@@ -164,6 +318,7 @@ func isRecoverableError(err error) bool {
 func (w *Watcher) hardReset(ctx context.Context) error {
 	if err := w.repo.FetchContext(ctx, &git.FetchOptions{
 		RemoteName: "origin",
+		Depth:      w.depth,
 		Force:      true,
 		Auth:       w.auth,
 	}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
@@ -183,14 +338,18 @@ func (w *Watcher) hardReset(ctx context.Context) error {
 		return err
 	}
 
-	return worktree.Reset(&git.ResetOptions{
+	err = worktree.ResetSparsely(&git.ResetOptions{
 		Commit: ref.Hash(),
 		Mode:   git.HardReset,
-	})
+	}, w.sparse)
+	if err != nil {
+		return err
+	}
+	return w.updateSubmodules(ctx, worktree)
 }
 
 func (w *Watcher) Watch(ctx context.Context, onChange func(ChangeEvent)) {
-	ticker := time.NewTicker(w.pollInterval)
+	ticker := time.NewTicker(w.PollInterval())
 	defer ticker.Stop()
 
 	const eventQueueSize = 16
@@ -235,6 +394,8 @@ func (w *Watcher) pollLoop(ctx context.Context, ticker *time.Ticker, events chan
 		select {
 		case <-ctx.Done():
 			return
+		case <-w.reset:
+			ticker.Reset(w.PollInterval())
 		case <-ticker.C:
 			w.handleTick(ctx, events)
 		}
@@ -242,6 +403,7 @@ func (w *Watcher) pollLoop(ctx context.Context, ticker *time.Ticker, events chan
 }
 
 func (w *Watcher) handleTick(ctx context.Context, events chan<- ChangeEvent) {
+	prev := w.LastCommit()
 	start := time.Now()
 	changed, hash, err := w.Pull(ctx)
 	duration := time.Since(start)
@@ -252,6 +414,7 @@ func (w *Watcher) handleTick(ctx context.Context, events chan<- ChangeEvent) {
 
 	if err != nil {
 		w.logger.Error("failed to pull", slog.Any("error", err))
+		w.bus.Publish(bus.Event{Type: bus.TypeGitPollFailed, Repo: w.repoName, Data: map[string]any{"error": err.Error()}})
 		return
 	}
 
@@ -263,7 +426,23 @@ func (w *Watcher) handleTick(ctx context.Context, events chan<- ChangeEvent) {
 		Commit:    hash,
 		Timestamp: time.Now(),
 		Message:   w.getCommitMessage(hash),
+		Tag:       w.CurrentTag(),
+	}
+	if paths, err := w.ChangedPaths(prev, hash); err != nil {
+		w.logger.Warn("failed to list changed paths", slog.String("commit", hash), slog.Any("error", err))
+	} else {
+		event.Paths = paths
+	}
+	data := map[string]any{"message": event.Message, "branch": w.branch}
+	if w.tagPattern != "" {
+		data = map[string]any{"message": event.Message, "tag": event.Tag}
 	}
+	w.bus.Publish(bus.Event{
+		Type:   bus.TypeGitCommit,
+		Repo:   w.repoName,
+		Commit: hash,
+		Data:   data,
+	})
 	w.enqueueEvent(ctx, events, event)
 }
 
@@ -274,7 +453,7 @@ func (w *Watcher) enqueueEvent(ctx context.Context, events chan<- ChangeEvent, e
 			return
 		case <-ctx.Done():
 			return
-		case <-time.After(w.pollInterval):
+		case <-time.After(w.PollInterval()):
 			w.logger.Warn("event queue full; waiting for handler", slog.String("commit", event.Commit))
 		}
 	}
@@ -286,6 +465,29 @@ func (w *Watcher) LastCommit() string {
 	return w.lastCommit
 }
 
+func (w *Watcher) PollInterval() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.pollInterval
+}
+
+func (w *Watcher) SetPollInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	w.mu.Lock()
+	changed := w.pollInterval != d
+	w.pollInterval = d
+	w.mu.Unlock()
+	if !changed {
+		return
+	}
+	select {
+	case w.reset <- struct{}{}:
+	default:
+	}
+}
+
 func (w *Watcher) WorkDir() string {
 	return w.workDir
 }
@@ -312,3 +514,38 @@ func (w *Watcher) getCommitMessage(hash string) string {
 	}
 	return commit.Message
 }
+
+func (w *Watcher) CommitTime(hash string) (time.Time, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	commit, err := w.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return commit.Committer.When, nil
+}
+
+func (w *Watcher) CommitsSince(hash string) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.lastCommit == "" || w.lastCommit == hash {
+		return 0, nil
+	}
+
+	iter, err := w.repo.Log(&git.LogOptions{From: plumbing.NewHash(w.lastCommit)})
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	count := 0
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.Hash.String() == hash || count >= maxPendingCommits {
+			return storer.ErrStop
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
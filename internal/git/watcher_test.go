@@ -1,6 +1,8 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +13,10 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
 const (
@@ -163,6 +169,117 @@ func TestWatcherCloneAndPull(t *testing.T) {
 	}
 }
 
+type countingSource struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (s *countingSource) AuthMethod(context.Context) (transport.AuthMethod, error) {
+	s.calls.Add(1)
+	return nil, s.err
+}
+
+func TestWatcherCredentialsRefresh(t *testing.T) {
+	tr := setupTestRepo(t)
+	source := &countingSource{}
+
+	workDir := filepath.Join(tr.tmpDir, testWorkDir)
+	w := NewWatcher(tr.bareRepoPath, "master", workDir, time.Second, nil, WithCredentials(source))
+
+	ctx := t.Context()
+	if err := w.Clone(ctx); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+	for range 2 {
+		if _, _, err := w.Pull(ctx); err != nil {
+			t.Fatalf("Pull failed: %v", err)
+		}
+	}
+	if got := source.calls.Load(); got != 3 {
+		t.Errorf("AuthMethod calls = %d, want 3", got)
+	}
+
+	source.err = errors.New("token expired")
+	if _, _, err := w.Pull(ctx); err == nil || !errors.Is(err, source.err) {
+		t.Errorf("Pull error = %v, want %v", err, source.err)
+	}
+}
+
+func TestWatcherPullSpan(t *testing.T) {
+	exporter := telemetry.NewTestTracer(t)
+	tr := setupTestRepo(t)
+
+	workDir := filepath.Join(tr.tmpDir, testWorkDir)
+	w := NewWatcher(tr.bareRepoPath, "master", workDir, time.Second, nil, WithRepoName("web"))
+
+	ctx := t.Context()
+	if err := w.Clone(ctx); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+	newCommitHash := tr.addCommit(t, testSecondCommit)
+
+	if _, _, err := w.Pull(ctx); err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "Watcher.Pull" {
+		t.Fatalf("expected a single Watcher.Pull span, got %v", spans)
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans[0].Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if got := attrs[telemetry.AttrRepo].AsString(); got != "web" {
+		t.Errorf("repo attribute = %q, want %q", got, "web")
+	}
+	if got := attrs[telemetry.AttrCommit].AsString(); got != newCommitHash {
+		t.Errorf("commit attribute = %q, want %q", got, newCommitHash)
+	}
+	if !attrs["kedge.changed"].AsBool() {
+		t.Error("expected kedge.changed to be true")
+	}
+}
+
+func TestWatcherCommitsSince(t *testing.T) {
+	tr := setupTestRepo(t)
+
+	workDir := filepath.Join(tr.tmpDir, testWorkDir)
+	w := NewWatcher(tr.bareRepoPath, "master", workDir, time.Second, nil)
+
+	ctx := t.Context()
+	if err := w.Clone(ctx); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+	deployed := w.LastCommit()
+
+	tr.addCommit(t, testSecondCommit)
+	head := tr.addCommit(t, "third commit")
+	if _, _, err := w.Pull(ctx); err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+
+	pending, err := w.CommitsSince(deployed)
+	if err != nil {
+		t.Fatalf("CommitsSince failed: %v", err)
+	}
+	if pending != 2 {
+		t.Errorf("CommitsSince = %d, want 2", pending)
+	}
+
+	if pending, _ := w.CommitsSince(head); pending != 0 {
+		t.Errorf("CommitsSince(head) = %d, want 0", pending)
+	}
+
+	committed, err := w.CommitTime(head)
+	if err != nil {
+		t.Fatalf("CommitTime failed: %v", err)
+	}
+	if time.Since(committed) > time.Minute {
+		t.Errorf("CommitTime = %v, want a recent time", committed)
+	}
+}
+
 func TestWatcherCloneExistingWorkDir(t *testing.T) {
 	tr := setupTestRepo(t)
 
@@ -255,6 +372,45 @@ func TestWatcherWatch(t *testing.T) {
 	}
 }
 
+func TestWatcherSetPollInterval(t *testing.T) {
+	tr := setupTestRepo(t)
+
+	workDir := filepath.Join(tr.tmpDir, testWorkDir)
+	w := NewWatcher(tr.bareRepoPath, "master", workDir, time.Hour, nil)
+
+	ctx := t.Context()
+
+	if err := w.Clone(ctx); err != nil {
+		t.Fatalf(testCloneFailedFmt, err)
+	}
+
+	received := make(chan ChangeEvent, 1)
+	go w.Watch(ctx, func(event ChangeEvent) {
+		select {
+		case received <- event:
+		default:
+		}
+	})
+
+	w.SetPollInterval(0)
+	if got := w.PollInterval(); got != time.Hour {
+		t.Errorf("PollInterval() = %v, want %v", got, time.Hour)
+	}
+
+	pollInterval := 50 * time.Millisecond
+	w.SetPollInterval(pollInterval)
+	newCommitHash := tr.addCommit(t, testSecondCommit)
+
+	select {
+	case event := <-received:
+		if event.Commit != newCommitHash {
+			t.Errorf("Watch event commit = %s, want %s", event.Commit, newCommitHash)
+		}
+	case <-time.After(pollInterval*3 + 500*time.Millisecond):
+		t.Error("Watch did not pick up the new poll interval")
+	}
+}
+
 func TestWatcherWatchBackpressure(t *testing.T) {
 	tr := setupTestRepo(t)
 
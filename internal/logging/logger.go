@@ -1,26 +1,30 @@
 package logging
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"os"
 
 	"github.com/lmittmann/tint"
 )
 
+var ErrInvalidLevel = errors.New("invalid log level")
+
+var (
+	Levels  = []string{"debug", "info", "warn", "error"}
+	Formats = []string{"text", "json"}
+)
+
 type Config struct {
 	Level  string
 	Format string
 }
 
 func New(cfg Config) *slog.Logger {
-	level := slog.LevelInfo
-	switch cfg.Level {
-	case "debug":
-		level = slog.LevelDebug
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		level = slog.LevelInfo
 	}
 
 	if cfg.Format == "json" {
@@ -28,3 +32,42 @@ func New(cfg Config) *slog.Logger {
 	}
 	return slog.New(tint.NewHandler(os.Stdout, &tint.Options{Level: level}))
 }
+
+func ParseLevel(s string) (slog.Level, error) {
+	switch s {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, ErrInvalidLevel
+}
+
+func WithLevel(logger *slog.Logger, level slog.Leveler) *slog.Logger {
+	return slog.New(&levelHandler{level: level, handler: logger.Handler()})
+}
+
+type levelHandler struct {
+	level   slog.Leveler
+	handler slog.Handler
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithGroup(name)}
+}
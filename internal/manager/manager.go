@@ -2,28 +2,44 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/samber/lo"
 
+	"github.com/LoriKarikari/kedge/internal/bus"
 	"github.com/LoriKarikari/kedge/internal/config"
 	"github.com/LoriKarikari/kedge/internal/controller"
 	"github.com/LoriKarikari/kedge/internal/git"
 	"github.com/LoriKarikari/kedge/internal/git/auth"
 	"github.com/LoriKarikari/kedge/internal/reconcile"
+	"github.com/LoriKarikari/kedge/internal/secrets"
 	"github.com/LoriKarikari/kedge/internal/state"
 	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
+var (
+	ErrRepoNotRunning = errors.New("repository is not running")
+	ErrAppRequired    = errors.New("repository has multiple apps; an app name is required")
+)
+
 type Config struct {
-	StatePath    string
-	PollInterval string
+	StatePath      string
+	PollInterval   time.Duration
+	Reconciliation reconcile.Config
+	LogLevel       string
+	EventRetention time.Duration
+	KeyFile        string
 }
 
+const pruneInterval = time.Hour
+
 type RepoStatus struct {
 	Running bool
 	Error   error
@@ -32,23 +48,42 @@ type RepoStatus struct {
 type Manager struct {
 	store       *state.Store
 	telemetry   *telemetry.Provider
+	bus         *bus.Bus
 	controllers map[string]*controller.Controller
+	previews    map[string]*preview
 	repoStatus  map[string]*RepoStatus
 	logger      *slog.Logger
 	mu          sync.RWMutex
 }
 
-func New(store *state.Store, tp *telemetry.Provider, logger *slog.Logger) *Manager {
-	return &Manager{
+type Option func(*Manager)
+
+func WithEventBus(b *bus.Bus) Option {
+	return func(m *Manager) {
+		m.bus = b
+	}
+}
+
+func New(store *state.Store, tp *telemetry.Provider, logger *slog.Logger, opts ...Option) *Manager {
+	m := &Manager{
 		store:       store,
 		telemetry:   tp,
 		controllers: make(map[string]*controller.Controller),
+		previews:    make(map[string]*preview),
 		repoStatus:  make(map[string]*RepoStatus),
 		logger:      logger.With(slog.String("component", "manager")),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 func (m *Manager) Start(ctx context.Context, cfg Config) error {
+	if cfg.EventRetention > 0 {
+		go m.pruneEvents(ctx, cfg.EventRetention)
+	}
+
 	repos, err := m.store.ListRepos(ctx)
 	if err != nil {
 		return fmt.Errorf("list repos: %w", err)
@@ -98,85 +133,254 @@ func (m *Manager) Start(ctx context.Context, cfg Config) error {
 	return nil
 }
 
+func decryptSecret(authCfg *auth.Config, secret, keyFile string) error {
+	keys, err := secrets.Load(keyFile)
+	if err != nil {
+		return fmt.Errorf("decrypt credentials: %w", err)
+	}
+	plaintext, err := keys.Decrypt(secret)
+	if err != nil {
+		return fmt.Errorf("decrypt credentials: %w", err)
+	}
+	if authCfg.Type == auth.TypeSSHKey {
+		authCfg.SSHKey = plaintext
+	} else {
+		authCfg.Password = string(plaintext)
+	}
+	return nil
+}
+
 func (m *Manager) startRepo(ctx context.Context, repo *state.Repo, mgrCfg Config) error {
 	workDir := repoWorkDir(repo.Name)
 
-	var watcherOpts []git.WatcherOption
-	watcherOpts = append(watcherOpts, git.WithRepoName(repo.Name))
-	if m.telemetry != nil {
-		watcherOpts = append(watcherOpts, git.WithMetrics(m.telemetry.Metrics))
+	watcherOpts, err := m.watcherOptions(repo, mgrCfg)
+	if err != nil {
+		m.setStatus(repo.Name, err)
+		return err
+	}
+	previewOpts := slices.Clip(watcherOpts)
+	if repo.TagPattern != "" {
+		watcherOpts = append(watcherOpts, git.WithTags(repo.TagPattern, repo.TagConstraint))
 	}
 
-	if repo.AuthType != "" {
-		authCfg := &auth.Config{
-			Type:        auth.Type(repo.AuthType),
-			SSHKeyPath:  repo.SSHKeyPath,
-			Username:    repo.Username,
-			PasswordEnv: repo.PasswordEnv,
+	var keys *git.Keyring
+	if repo.TrustKeysPath != "" {
+		if keys, err = git.LoadKeyring(repo.TrustKeysPath); err != nil {
+			m.setStatus(repo.Name, err)
+			return err
 		}
-		watcherOpts = append(watcherOpts, git.WithAuth(authCfg, m.logger))
 	}
 
-	watcher := git.NewWatcher(repo.URL, repo.Branch, workDir, config.Default().Git.PollInterval, m.logger, watcherOpts...)
+	pollInterval := mgrCfg.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = config.Default().Git.PollInterval
+	}
+	watcher := git.NewWatcher(repo.URL, repo.Branch, workDir, pollInterval, m.logger, watcherOpts...)
 
 	if err := watcher.Clone(ctx); err != nil {
-		m.mu.Lock()
-		m.repoStatus[repo.Name] = &RepoStatus{Running: false, Error: fmt.Errorf("clone: %w", err)}
-		m.mu.Unlock()
-		return fmt.Errorf("clone: %w", err)
+		err = fmt.Errorf("clone: %w", err)
+		m.setStatus(repo.Name, err)
+		return err
 	}
 
-	repoCfg, err := loadRepoConfig(repo.Name)
-	if err != nil {
-		m.mu.Lock()
-		m.repoStatus[repo.Name] = &RepoStatus{Running: false, Error: fmt.Errorf("kedge.yaml not found")}
-		m.mu.Unlock()
-		return fmt.Errorf("kedge.yaml not found")
+	defaults := controller.Config{
+		RepoName:     repo.Name,
+		ProjectName:  config.Default().Docker.ProjectName,
+		ComposeFiles: config.Default().Docker.Files(),
+		StatePath:    mgrCfg.StatePath,
+		PollInterval: pollInterval,
+		ReconcileCfg: mgrCfg.Reconciliation,
+		LogLevel:     mgrCfg.LogLevel,
+
+		TrustKeys:         keys,
+		RequireSignedTags: repo.SignedTags,
+
+		KeyFile: mgrCfg.KeyFile,
 	}
 
-	mode, err := reconcile.ParseMode(repoCfg.Reconciliation.Mode)
+	apps, err := config.LoadApps(workDir)
 	if err != nil {
-		mode = reconcile.ModeAuto
+		m.setStatus(repo.Name, err)
+		return err
 	}
 
-	ctrlCfg := controller.Config{
-		RepoName:     repo.Name,
-		ProjectName:  repoCfg.Docker.ProjectName,
-		ComposePath:  repoCfg.Docker.ComposeFile,
-		StatePath:    mgrCfg.StatePath,
-		ReconcileCfg: reconcile.Config{Mode: mode},
+	if len(apps) == 0 {
+		ctrl, err := m.newController(ctx, watcher, defaults)
+		if err != nil {
+			m.setStatus(repo.Name, err)
+			return err
+		}
+		m.register(ctrl)
+		m.logger.Info("starting repo", slog.String("repo", repo.Name), slog.String("url", repo.URL))
+
+		go func() {
+			if err := ctrl.Run(ctx); err != nil && ctx.Err() == nil {
+				m.unregister(ctrl, err)
+			}
+		}()
+		if repo.PreviewPattern != "" {
+			go m.watchPreviews(ctx, repo, watcher, previewOpts, defaults)
+		}
+		return nil
+	}
+	if repo.PreviewPattern != "" {
+		m.logger.Warn("preview environments are not supported for repositories with apps", slog.String("repo", repo.Name))
+	}
+
+	var ctrls []*controller.Controller
+	var failed []string
+	for _, app := range apps {
+		appDefaults := defaults
+		appDefaults.AppName = app.Name
+		appDefaults.AppPath = app.Path
+		appDefaults.ProjectName = app.Name
+
+		ctrl, err := m.newController(ctx, watcher, appDefaults)
+		if err != nil {
+			m.setStatus(telemetry.RepoLabel(repo.Name, app.Name), err)
+			m.logger.Error("failed to start app", slog.String("repo", repo.Name), slog.String("app", app.Name), slog.Any("error", err))
+			failed = append(failed, fmt.Sprintf("app %s: %v", app.Name, err))
+			continue
+		}
+		m.register(ctrl)
+		ctrls = append(ctrls, ctrl)
+	}
+	if len(ctrls) == 0 {
+		return fmt.Errorf("all apps failed to start: %s", strings.Join(failed, "; "))
+	}
+
+	m.logger.Info("starting repo", slog.String("repo", repo.Name), slog.String("url", repo.URL), slog.Int("apps", len(ctrls)))
+	go m.runApps(ctx, watcher, ctrls)
+	return nil
+}
+
+// watcherOptions returns the options shared by the repository's watcher and
+// its preview watchers. Tag tracking is left to the caller.
+func (m *Manager) watcherOptions(repo *state.Repo, mgrCfg Config) ([]git.WatcherOption, error) {
+	opts := []git.WatcherOption{git.WithRepoName(repo.Name), git.WithEventBus(m.bus)}
+	if m.telemetry != nil {
+		opts = append(opts, git.WithMetrics(m.telemetry.Metrics))
+	}
+
+	if repo.AuthType != "" {
+		authCfg := &auth.Config{
+			Type:           auth.Type(repo.AuthType),
+			URL:            repo.URL,
+			SSHKeyPath:     repo.SSHKeyPath,
+			Username:       repo.Username,
+			PasswordEnv:    repo.PasswordEnv,
+			PassphraseEnv:  repo.PassphraseEnv,
+			PassphraseFile: repo.PassphraseFile,
+			KnownHostsPath: repo.KnownHostsPath,
+			HostKeys:       repo.HostKeys,
+
+			TokenFile:        repo.TokenFile,
+			AppID:            repo.AppID,
+			InstallationID:   repo.InstallationID,
+			AppKeyPath:       repo.AppKeyPath,
+			GitHubAPIURL:     repo.GitHubAPIURL,
+			CredentialHelper: repo.CredentialHelper,
+		}
+		if repo.Secret != "" {
+			if err := decryptSecret(authCfg, repo.Secret, mgrCfg.KeyFile); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, git.WithAuth(authCfg, m.logger))
+	}
+
+	if repo.Submodules {
+		opts = append(opts, git.WithSubmodules())
+	}
+	if repo.Depth > 0 {
+		opts = append(opts, git.WithDepth(repo.Depth))
+	}
+	if len(repo.SparsePaths) > 0 {
+		opts = append(opts, git.WithSparseCheckout(append(repo.SparsePaths, config.RepoFiles...)))
+	}
+	return opts, nil
+}
+
+func (m *Manager) newController(ctx context.Context, watcher *git.Watcher, defaults controller.Config) (*controller.Controller, error) {
+	ctrlCfg, err := controller.LoadRepoConfig(watcher.WorkDir(), defaults)
+	if err != nil {
+		return nil, err
 	}
+	watcher.SetPollInterval(ctrlCfg.PollInterval)
 
 	var metrics *telemetry.Metrics
 	if m.telemetry != nil {
 		metrics = m.telemetry.Metrics
 	}
-	ctrl, err := controller.New(ctx, watcher, ctrlCfg, metrics, m.logger)
+	ctrl, err := controller.New(ctx, watcher, ctrlCfg, metrics, m.logger, controller.WithEventBus(m.bus), controller.WithReload(defaults))
 	if err != nil {
-		m.mu.Lock()
-		m.repoStatus[repo.Name] = &RepoStatus{Running: false, Error: fmt.Errorf("create controller: %w", err)}
-		m.mu.Unlock()
-		return fmt.Errorf("create controller: %w", err)
+		return nil, fmt.Errorf("create controller: %w", err)
 	}
+	return ctrl, nil
+}
 
+func (m *Manager) runApps(ctx context.Context, watcher *git.Watcher, ctrls []*controller.Controller) {
+	running := make([]*controller.Controller, 0, len(ctrls))
+	for _, ctrl := range ctrls {
+		if err := ctrl.Start(ctx); err != nil {
+			if ctx.Err() == nil {
+				m.unregister(ctrl, err)
+			}
+			continue
+		}
+		running = append(running, ctrl)
+	}
+	if len(running) == 0 {
+		return
+	}
+
+	watcher.Watch(ctx, func(event git.ChangeEvent) {
+		for _, ctrl := range running {
+			ctrl.HandleChange(ctx, event)
+		}
+	})
+}
+
+func (m *Manager) register(ctrl *controller.Controller) {
 	m.mu.Lock()
-	m.controllers[repo.Name] = ctrl
-	m.repoStatus[repo.Name] = &RepoStatus{Running: true}
+	defer m.mu.Unlock()
+	m.controllers[ctrl.Name()] = ctrl
+	m.repoStatus[ctrl.Name()] = &RepoStatus{Running: true}
+}
+
+func (m *Manager) unregister(ctrl *controller.Controller, err error) {
+	m.mu.Lock()
+	m.repoStatus[ctrl.Name()] = &RepoStatus{Running: false, Error: err}
+	delete(m.controllers, ctrl.Name())
 	m.mu.Unlock()
+	m.logger.Error("controller stopped", slog.String("repo", ctrl.Name()), slog.Any("error", err))
+}
 
-	m.logger.Info("starting repo", slog.String("repo", repo.Name), slog.String("url", repo.URL))
+func (m *Manager) setStatus(name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.repoStatus[name] = &RepoStatus{Running: false, Error: err}
+}
 
-	go func() {
-		if err := ctrl.Run(ctx); err != nil && ctx.Err() == nil {
-			m.mu.Lock()
-			m.repoStatus[repo.Name] = &RepoStatus{Running: false, Error: err}
-			delete(m.controllers, repo.Name)
-			m.mu.Unlock()
-			m.logger.Error("controller stopped", slog.String("repo", repo.Name), slog.Any("error", err))
+func (m *Manager) pruneEvents(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := m.store.PruneEvents(ctx, time.Now().Add(-retention))
+		switch {
+		case err != nil && ctx.Err() == nil:
+			m.logger.Warn("failed to prune events", slog.Any("error", err))
+		case removed > 0:
+			m.logger.Debug("pruned events", slog.Int64("removed", removed))
 		}
-	}()
 
-	return nil
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 func (m *Manager) IsReady() bool {
@@ -199,6 +403,44 @@ func (m *Manager) Status() map[string]*RepoStatus {
 	return result
 }
 
+func (m *Manager) controller(repoName, app string) (*controller.Controller, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ctrl, ok := m.controllers[telemetry.RepoLabel(repoName, app)]
+	if ok {
+		return ctrl, nil
+	}
+	if app == "" && lo.SomeBy(lo.Keys(m.controllers), func(name string) bool { return strings.HasPrefix(name, repoName+"/") }) {
+		return nil, ErrAppRequired
+	}
+	return nil, ErrRepoNotRunning
+}
+
+func (m *Manager) PendingApprovals(ctx context.Context, repoName, app string) ([]*state.Deployment, error) {
+	ctrl, err := m.controller(repoName, app)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.PendingApprovals(ctx)
+}
+
+func (m *Manager) Approve(ctx context.Context, repoName, app string, id int64, approver string) (*state.Deployment, error) {
+	ctrl, err := m.controller(repoName, app)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.Approve(ctx, id, approver)
+}
+
+func (m *Manager) Reject(ctx context.Context, repoName, app string, id int64, reviewer string) (*state.Deployment, error) {
+	ctrl, err := m.controller(repoName, app)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.Reject(ctx, id, reviewer)
+}
+
 func (m *Manager) Close() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -216,14 +458,3 @@ func (m *Manager) Close() error {
 func repoWorkDir(name string) string {
 	return filepath.Join(".kedge", "repos", name)
 }
-
-func loadRepoConfig(name string) (*config.Config, error) {
-	workDir := repoWorkDir(name)
-	configPath := filepath.Join(workDir, "kedge.yaml")
-	cfg, err := config.Load(configPath)
-	if err != nil {
-		configPath = filepath.Join(workDir, "kedge.yml")
-		cfg, err = config.Load(configPath)
-	}
-	return cfg, err
-}
@@ -2,12 +2,15 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/LoriKarikari/kedge/internal/git/auth"
+	"github.com/LoriKarikari/kedge/internal/secrets"
 	"github.com/LoriKarikari/kedge/internal/state"
 )
 
@@ -64,7 +67,7 @@ func TestStartNoRepos(t *testing.T) {
 func TestStartAllReposFail(t *testing.T) {
 	store := newTestStore(t)
 
-	_, err := store.SaveRepo(t.Context(), testRepoName, "https://github.com/octocat/Hello-World.git", "master", nil)
+	_, err := store.SaveRepo(t.Context(), state.RepoSpec{Name: testRepoName, URL: "https://github.com/octocat/Hello-World.git", Branch: "master"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -99,11 +102,11 @@ func TestStartAllReposFail(t *testing.T) {
 func TestStartMultipleReposFail(t *testing.T) {
 	store := newTestStore(t)
 
-	_, err := store.SaveRepo(t.Context(), "repo1", "https://github.com/octocat/Hello-World.git", "master", nil)
+	_, err := store.SaveRepo(t.Context(), state.RepoSpec{Name: "repo1", URL: "https://github.com/octocat/Hello-World.git", Branch: "master"})
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = store.SaveRepo(t.Context(), "repo2", "https://github.com/octocat/Spoon-Knife.git", "main", nil)
+	_, err = store.SaveRepo(t.Context(), state.RepoSpec{Name: "repo2", URL: "https://github.com/octocat/Spoon-Knife.git", Branch: "main"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -154,3 +157,46 @@ func TestClose(t *testing.T) {
 		t.Errorf("expected no error closing empty manager, got %v", err)
 	}
 }
+
+func TestApproveRepoNotRunning(t *testing.T) {
+	store := newTestStore(t)
+	mgr := New(store, nil, slog.Default())
+
+	_, err := mgr.Approve(t.Context(), testRepoName, "", 1, "alice")
+	if err != ErrRepoNotRunning {
+		t.Errorf("error: got %v, want ErrRepoNotRunning", err)
+	}
+}
+
+func TestDecryptSecret(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "secret.key")
+	keys, err := secrets.LoadOrCreate(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := keys.Encrypt([]byte("ghp_stored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenCfg := &auth.Config{Type: auth.TypeToken}
+	if err := decryptSecret(tokenCfg, secret, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if tokenCfg.Password != "ghp_stored" {
+		t.Errorf("Password = %q", tokenCfg.Password)
+	}
+
+	sshCfg := &auth.Config{Type: auth.TypeSSHKey}
+	if err := decryptSecret(sshCfg, secret, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if string(sshCfg.SSHKey) != "ghp_stored" {
+		t.Errorf("SSHKey = %q", sshCfg.SSHKey)
+	}
+
+	err = decryptSecret(&auth.Config{Type: auth.TypeToken}, secret, filepath.Join(t.TempDir(), "missing.key"))
+	if !errors.Is(err, secrets.ErrNoKeyFile) {
+		t.Errorf("error = %v, want ErrNoKeyFile", err)
+	}
+}
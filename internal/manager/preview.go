@@ -0,0 +1,247 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/LoriKarikari/kedge/internal/controller"
+	"github.com/LoriKarikari/kedge/internal/docker"
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/state"
+	"github.com/LoriKarikari/kedge/internal/telemetry"
+)
+
+var ErrPreviewConflict = errors.New("preview project name is already used by another branch")
+
+const previewActor = "kedge"
+
+type preview struct {
+	ctrl   *controller.Controller
+	commit string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// watchPreviews deploys every remote branch matching the repository's preview
+// pattern as its own compose project until the branch is deleted or goes
+// stale.
+func (m *Manager) watchPreviews(ctx context.Context, repo *state.Repo, watcher *git.Watcher, opts []git.WatcherOption, defaults controller.Config) {
+	m.logger.Info("watching preview branches", slog.String("repo", repo.Name), slog.String("pattern", repo.PreviewPattern))
+	for {
+		m.syncPreviews(ctx, repo, watcher, opts, defaults)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(defaults.PollInterval):
+		}
+	}
+}
+
+func (m *Manager) syncPreviews(ctx context.Context, repo *state.Repo, watcher *git.Watcher, opts []git.WatcherOption, defaults controller.Config) {
+	branches, err := watcher.ListBranches(ctx, repo.PreviewPattern)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Warn("failed to list preview branches", slog.String("repo", repo.Name), slog.Any("error", err))
+		}
+		return
+	}
+	known, err := m.store.ListPreviews(ctx, repo.Name)
+	if err != nil {
+		m.logger.Warn("failed to list previews", slog.String("repo", repo.Name), slog.Any("error", err))
+		return
+	}
+
+	remote := lo.SliceToMap(branches, func(b git.Branch) (string, bool) { return b.Name, true })
+	for _, p := range known {
+		if !remote[p.Branch] {
+			m.removePreview(ctx, repo, p, "branch deleted")
+		}
+	}
+
+	existing := lo.SliceToMap(known, func(p *state.Preview) (string, bool) { return p.Branch, true })
+	for _, b := range branches {
+		p, err := m.store.SavePreview(ctx, repo.Name, b.Name, b.Commit)
+		if err != nil {
+			m.logger.Warn("failed to save preview", slog.String("repo", repo.Name), slog.String("branch", b.Name), slog.Any("error", err))
+			continue
+		}
+		if !existing[b.Name] {
+			m.recordPreviewEvent(ctx, state.EventPreviewCreated, p, "")
+		}
+		switch {
+		case p.Retired():
+		case repo.PreviewTTL > 0 && time.Since(p.UpdatedAt) > repo.PreviewTTL:
+			m.removePreview(ctx, repo, p, "stale")
+		case !m.previewRunning(p):
+			if err := m.startPreview(ctx, repo, p, opts, defaults); err != nil {
+				m.setStatus(previewName(repo.Name, p.Branch), err)
+				m.logger.Error("failed to start preview", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+func (m *Manager) startPreview(ctx context.Context, repo *state.Repo, p *state.Preview, opts []git.WatcherOption, defaults controller.Config) error {
+	workDir := previewWorkDir(repo.Name, p.Branch)
+	watcher := git.NewWatcher(repo.URL, p.Branch, workDir, defaults.PollInterval, m.logger, opts...)
+	if err := watcher.Clone(ctx); err != nil {
+		return fmt.Errorf("clone: %w", err)
+	}
+
+	defaults.AppName = state.PreviewApp(p.Branch)
+	defaults.Preview = p.Branch
+	defaults.PreviewPortOffset = p.Slot * repo.PreviewPortOffset
+	ctrl, err := m.newController(ctx, watcher, defaults)
+	if err != nil {
+		return err
+	}
+	project := ctrl.ProjectName()
+	if err := m.claimProject(ctx, repo.Name, p.Branch, project); err != nil {
+		_ = ctrl.Close()
+		return err
+	}
+
+	previewCtx, cancel := context.WithCancel(ctx)
+	running := &preview{ctrl: ctrl, commit: p.Commit, cancel: cancel, done: make(chan struct{})}
+	m.register(ctrl)
+	m.mu.Lock()
+	m.previews[ctrl.Name()] = running
+	m.mu.Unlock()
+	m.logger.Info("starting preview", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.String("project", project))
+
+	go func() {
+		defer close(running.done)
+		if err := ctrl.Run(previewCtx); err != nil && previewCtx.Err() == nil {
+			m.unregister(ctrl, err)
+		}
+	}()
+	return nil
+}
+
+// claimProject records the preview's compose project, refusing one that
+// another branch of the repository already deploys to.
+func (m *Manager) claimProject(ctx context.Context, repoName, branch, project string) error {
+	previews, err := m.store.ListPreviews(ctx, repoName)
+	if err != nil {
+		return err
+	}
+	if other, ok := lo.Find(previews, func(o *state.Preview) bool {
+		return o.Branch != branch && !o.Retired() && o.ProjectName == project
+	}); ok {
+		return fmt.Errorf("%w: %s (%s)", ErrPreviewConflict, project, other.Branch)
+	}
+	return m.store.SetPreviewProject(ctx, repoName, branch, project)
+}
+
+// removePreview stops a preview's controller and removes its containers,
+// networks and checkout. Deleted branches are forgotten; stale ones are kept
+// as retired until they receive a new commit.
+func (m *Manager) removePreview(ctx context.Context, repo *state.Repo, p *state.Preview, reason string) {
+	name := previewName(repo.Name, p.Branch)
+	m.mu.Lock()
+	running := m.previews[name]
+	delete(m.previews, name)
+	delete(m.controllers, name)
+	delete(m.repoStatus, name)
+	m.mu.Unlock()
+
+	if running != nil {
+		running.cancel()
+		<-running.done
+		if err := running.ctrl.Close(); err != nil {
+			m.logger.Warn("failed to close preview controller", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.Any("error", err))
+		}
+	}
+
+	if p.ProjectName != "" && !p.Retired() {
+		if err := removeProject(ctx, p.ProjectName, m.logger); err != nil {
+			m.logger.Error("failed to remove preview", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.Any("error", err))
+			return
+		}
+	}
+	if err := docker.RemoveSecrets(p.ProjectName); err != nil {
+		m.logger.Warn("failed to remove preview secrets", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.Any("error", err))
+	}
+	if err := os.RemoveAll(previewWorkDir(repo.Name, p.Branch)); err != nil {
+		m.logger.Warn("failed to remove preview checkout", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.Any("error", err))
+	}
+
+	var err error
+	if reason == "stale" {
+		err = m.store.RetirePreview(ctx, repo.Name, p.Branch)
+	} else {
+		err = m.store.DeletePreview(ctx, repo.Name, p.Branch)
+	}
+	if err != nil {
+		m.logger.Warn("failed to update preview", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.Any("error", err))
+	}
+	if !p.Retired() {
+		m.logger.Info("removed preview", slog.String("repo", repo.Name), slog.String("branch", p.Branch), slog.String("reason", reason))
+		m.recordPreviewEvent(ctx, state.EventPreviewRemoved, p, reason)
+	}
+}
+
+func removeProject(ctx context.Context, project string, logger *slog.Logger) error {
+	client, err := docker.NewClient(project, logger)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Remove(ctx)
+}
+
+// previewRunning reports whether the preview has a controller. A controller
+// that stopped on an error is replaced once the branch gets a new commit.
+func (m *Manager) previewRunning(p *state.Preview) bool {
+	name := previewName(p.RepoName, p.Branch)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running, ok := m.previews[name]
+	if !ok {
+		return false
+	}
+	select {
+	case <-running.done:
+		if running.commit == p.Commit {
+			return true
+		}
+		delete(m.previews, name)
+		_ = running.ctrl.Close()
+		return false
+	default:
+		return true
+	}
+}
+
+func (m *Manager) recordPreviewEvent(ctx context.Context, eventType state.EventType, p *state.Preview, reason string) {
+	payload := map[string]any{"branch": p.Branch, "slot": p.Slot}
+	if reason != "" {
+		payload["reason"] = reason
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	event := &state.Event{Type: eventType, RepoName: p.RepoName, App: state.PreviewApp(p.Branch), Commit: p.Commit, Actor: previewActor, Payload: data}
+	if _, err := m.store.RecordEvent(ctx, event); err != nil {
+		m.logger.Warn("failed to record event", slog.String("type", string(eventType)), slog.Any("error", err))
+	}
+}
+
+func previewName(repoName, branch string) string {
+	return telemetry.RepoLabel(repoName, state.PreviewApp(branch))
+}
+
+func previewWorkDir(repoName, branch string) string {
+	return filepath.Join(".kedge", "previews", repoName, url.PathEscape(branch))
+}
@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/LoriKarikari/kedge/internal/controller"
+	gitwatch "github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+func setupPreviewRemote(t *testing.T, branches ...string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kedge.yaml"), []byte("docker:\n  project_name: web\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("kedge.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, branch := range branches {
+		ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), hash)
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, hash.String()
+}
+
+func TestSyncPreviewsRemovesDeletedAndStale(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	remote, commit := setupPreviewRemote(t, "preview/stale")
+	repo, err := store.SaveRepo(ctx, state.RepoSpec{Name: "web", URL: remote, Branch: "master", Preview: &state.RepoPreview{Pattern: "preview/*", TTL: time.Second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SavePreview(ctx, "web", "preview/gone", commit); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SavePreview(ctx, "web", "preview/stale", commit); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	checkout := previewWorkDir("web", "preview/gone")
+	if err := os.MkdirAll(checkout, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := New(store, nil, slog.Default())
+	watcher := gitwatch.NewWatcher(remote, "master", t.TempDir(), time.Minute, nil)
+	defaults := controller.Config{RepoName: "web", PollInterval: time.Minute}
+	mgr.syncPreviews(ctx, repo, watcher, nil, defaults)
+
+	if _, err := os.Stat(checkout); !os.IsNotExist(err) {
+		t.Errorf("checkout of deleted branch still exists: %v", err)
+	}
+	previews, err := store.ListPreviews(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 1 || previews[0].Branch != "preview/stale" || !previews[0].Retired() {
+		t.Fatalf("ListPreviews() = %+v, want only preview/stale, retired", previews)
+	}
+
+	mgr.syncPreviews(ctx, repo, watcher, nil, defaults)
+	events, err := store.ListEvents(ctx, state.EventFilter{RepoName: "web", Types: []string{string(state.EventPreviewRemoved)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := map[string]string{}
+	for _, e := range events {
+		var payload struct{ Branch, Reason string }
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		reasons[payload.Branch] = payload.Reason
+	}
+	if len(events) != 2 || reasons["preview/gone"] != "branch deleted" || reasons["preview/stale"] != "stale" {
+		t.Errorf("preview_removed events = %v", reasons)
+	}
+	if mgr.previewRunning(previews[0]) {
+		t.Error("retired preview was started")
+	}
+}
@@ -0,0 +1,324 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"github.com/samber/lo"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+)
+
+type EventType string
+
+const (
+	EventDeploymentSucceeded EventType = "deployment_succeeded"
+	EventDeploymentFailed    EventType = "deployment_failed"
+	EventDriftDetected       EventType = "drift_detected"
+	EventAwaitingApproval    EventType = "awaiting_approval"
+	EventRollback            EventType = "rollback"
+	EventControllerStopped   EventType = "controller_stopped"
+	EventCommitRejected      EventType = "commit_rejected"
+)
+
+var EventTypes = []string{
+	string(EventDeploymentSucceeded),
+	string(EventDeploymentFailed),
+	string(EventDriftDetected),
+	string(EventAwaitingApproval),
+	string(EventRollback),
+	string(EventControllerStopped),
+	string(EventCommitRejected),
+}
+
+var eventTypeSchema = z.String().OneOf(EventTypes)
+
+var ErrInvalidEvent = errors.New("invalid notification event")
+
+// defaultDedupeEvents are the events repeated while a condition persists, such
+// as drift found on every reconcile.
+var defaultDedupeEvents = []EventType{EventDriftDetected}
+
+func ParseEventType(s string) (EventType, error) {
+	if err := eventTypeSchema.Validate(&s); err != nil {
+		return "", ErrInvalidEvent
+	}
+	return EventType(s), nil
+}
+
+const (
+	defaultDedupeWindow = 15 * time.Minute
+	defaultRateLimit    = 30
+	defaultRetries      = 3
+	defaultBackoff      = 2 * time.Second
+	deliveryTimeout     = 30 * time.Second
+
+	defaultTemplate = `[kedge] {{.Target}}: {{.Summary}}{{with .Commit}} ({{short .}}){{end}}{{with .Message}}
+{{.}}{{end}}`
+)
+
+type Event struct {
+	Type     EventType `json:"type"`
+	Repo     string    `json:"repo"`
+	App      string    `json:"app,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Services []string  `json:"services,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+func (e Event) Target() string {
+	if e.App == "" {
+		return e.Repo
+	}
+	return e.Repo + "/" + e.App
+}
+
+func (e Event) Summary() string {
+	switch e.Type {
+	case EventDeploymentSucceeded:
+		return "deployment succeeded"
+	case EventDeploymentFailed:
+		return "deployment failed"
+	case EventDriftDetected:
+		if len(e.Services) > 0 {
+			return "drift detected in " + strings.Join(e.Services, ", ")
+		}
+		return "drift detected"
+	case EventAwaitingApproval:
+		return "deployment awaiting approval"
+	case EventRollback:
+		return "rolled back"
+	case EventControllerStopped:
+		return "controller stopped"
+	case EventCommitRejected:
+		return "commit rejected"
+	default:
+		return string(e.Type)
+	}
+}
+
+func (e Event) key() string {
+	services := slices.Clone(e.Services)
+	slices.Sort(services)
+	return strings.Join([]string{string(e.Type), e.Target(), e.Commit, strings.Join(services, ",")}, "|")
+}
+
+type Sender interface {
+	Send(ctx context.Context, event Event, text string) error
+}
+
+type target struct {
+	name      string
+	sender    Sender
+	events    []EventType
+	tmpl      *template.Template
+	dedupe    time.Duration
+	dedupeOn  []EventType
+	rateLimit int
+	retries   int
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	recent   []time.Time
+}
+
+type Notifier struct {
+	targets []*target
+	logger  *slog.Logger
+	backoff time.Duration
+	now     func() time.Time
+	wg      sync.WaitGroup
+}
+
+func New(cfgs []config.Notification, logger *slog.Logger) (*Notifier, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	n := &Notifier{
+		logger:  logger.With(slog.String("component", "notifier")),
+		backoff: defaultBackoff,
+		now:     time.Now,
+	}
+
+	for i := range cfgs {
+		t, err := newTarget(&cfgs[i])
+		if err != nil {
+			return nil, fmt.Errorf("notification %s: %w", lo.CoalesceOrEmpty(cfgs[i].Name, fmt.Sprintf("#%d", i+1)), err)
+		}
+		n.targets = append(n.targets, t)
+	}
+
+	return n, nil
+}
+
+func newTarget(cfg *config.Notification) (*target, error) {
+	sender, err := newSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := parseEventTypes(cfg.Events)
+	if err != nil {
+		return nil, err
+	}
+	dedupeOn, err := parseEventTypes(cfg.DedupeEvents)
+	if err != nil {
+		return nil, fmt.Errorf("dedupe_events: %w", err)
+	}
+	if cfg.DedupeEvents == nil {
+		dedupeOn = defaultDedupeEvents
+	}
+
+	tmpl, err := template.New("notification").
+		Funcs(template.FuncMap{
+			"short": func(s string) string { return lo.Substring(s, 0, 8) },
+			"join":  strings.Join,
+		}).
+		Parse(lo.CoalesceOrEmpty(cfg.Template, defaultTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	retries := cfg.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+
+	return &target{
+		name:      lo.CoalesceOrEmpty(cfg.Name, cfg.Type),
+		sender:    sender,
+		events:    events,
+		tmpl:      tmpl,
+		dedupe:    lo.CoalesceOrEmpty(cfg.DedupeWindow, defaultDedupeWindow),
+		dedupeOn:  dedupeOn,
+		rateLimit: lo.CoalesceOrEmpty(cfg.RateLimit, defaultRateLimit),
+		retries:   max(retries, 0),
+		lastSent:  make(map[string]time.Time),
+	}, nil
+}
+
+func parseEventTypes(names []string) ([]EventType, error) {
+	types := make([]EventType, 0, len(names))
+	for _, name := range names {
+		eventType, err := ParseEventType(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+		types = append(types, eventType)
+	}
+	return types, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, event Event) {
+	if n == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = n.now()
+	}
+
+	for _, t := range n.targets {
+		if !t.wants(event.Type) {
+			continue
+		}
+
+		text, err := t.render(event)
+		if err != nil {
+			n.logger.Warn("failed to render notification", slog.String("target", t.name), slog.Any("error", err))
+			continue
+		}
+
+		if !t.allow(event, n.now()) {
+			n.logger.Debug("notification suppressed", slog.String("target", t.name), slog.String("event", string(event.Type)))
+			continue
+		}
+
+		n.wg.Go(func() {
+			deliverCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deliveryTimeout)
+			defer cancel()
+			n.deliver(deliverCtx, t, event, text)
+		})
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, t *target, event Event, text string) {
+	backoff := n.backoff
+	var err error
+	for attempt := 0; attempt <= t.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				n.logger.Warn("notification abandoned", slog.String("target", t.name), slog.Any("error", ctx.Err()))
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		if err = t.sender.Send(ctx, event, text); err == nil {
+			return
+		}
+		n.logger.Debug("notification attempt failed", slog.String("target", t.name), slog.Int("attempt", attempt+1), slog.Any("error", err))
+	}
+	n.logger.Warn("failed to deliver notification", slog.String("target", t.name), slog.String("event", string(event.Type)), slog.Any("error", err))
+}
+
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	n.wg.Wait()
+}
+
+func (t *target) wants(eventType EventType) bool {
+	return len(t.events) == 0 || slices.Contains(t.events, eventType)
+}
+
+func (t *target) render(event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (t *target) allow(event Event, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, last := range t.lastSent {
+		if now.Sub(last) >= t.dedupe {
+			delete(t.lastSent, key)
+		}
+	}
+
+	key := event.key()
+	deduped := slices.Contains(t.dedupeOn, event.Type)
+	if _, ok := t.lastSent[key]; ok && deduped {
+		return false
+	}
+
+	t.recent = lo.Filter(t.recent, func(sent time.Time, _ int) bool {
+		return now.Sub(sent) < time.Minute
+	})
+	if len(t.recent) >= t.rateLimit {
+		return false
+	}
+
+	t.recent = append(t.recent, now)
+	if deduped {
+		t.lastSent[key] = now
+	}
+	return true
+}
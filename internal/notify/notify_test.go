@@ -0,0 +1,367 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+)
+
+const (
+	testRepo   = "test-repo"
+	testCommit = "0123456789abcdef"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+func newRecorder(t *testing.T, failures int) (*recorder, *httptest.Server) {
+	t.Helper()
+	rec := &recorder{}
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, r)
+		rec.bodies = append(rec.bodies, string(body))
+		rec.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+func newTestNotifier(t *testing.T, cfgs ...config.Notification) *Notifier {
+	t.Helper()
+	n, err := New(cfgs, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	n.backoff = time.Millisecond
+	return n
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Notification
+	}{
+		{"unknown type", config.Notification{Type: "pager"}},
+		{"slack without url", config.Notification{Type: "slack"}},
+		{"ntfy without topic", config.Notification{Type: "ntfy", URL: "https://ntfy.sh"}},
+		{"smtp without recipients", config.Notification{Type: "smtp", SMTP: config.SMTP{Host: "localhost", From: "kedge@example.com"}}},
+		{"unknown event", config.Notification{Type: "slack", URL: "http://localhost", Events: []string{"exploded"}}},
+		{"unknown dedupe event", config.Notification{Type: "slack", URL: "http://localhost", DedupeEvents: []string{"exploded"}}},
+		{"bad template", config.Notification{Type: "slack", URL: "http://localhost", Template: "{{.Repo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]config.Notification{tt.cfg}, nil); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestNotifySlack(t *testing.T) {
+	rec, srv := newRecorder(t, 0)
+	n := newTestNotifier(t, config.Notification{Type: "slack", URL: srv.URL})
+
+	n.Notify(t.Context(), Event{Type: EventDeploymentFailed, Repo: testRepo, Commit: testCommit, Message: "pull failed"})
+	n.Close()
+
+	if rec.count() != 1 {
+		t.Fatalf("requests: got %d, want 1", rec.count())
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(rec.bodies[0]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := "[kedge] test-repo: deployment failed (01234567)\npull failed"
+	if payload["text"] != want {
+		t.Errorf("text: got %q, want %q", payload["text"], want)
+	}
+}
+
+func TestNotifyWebhookHeadersAndTemplate(t *testing.T) {
+	rec, srv := newRecorder(t, 0)
+	n := newTestNotifier(t, config.Notification{
+		Type:     "webhook",
+		URL:      srv.URL,
+		Headers:  map[string]string{"X-Token": "secret"},
+		Template: "{{.Repo}} {{join .Services \"+\"}}",
+	})
+
+	n.Notify(t.Context(), Event{Type: EventDriftDetected, Repo: testRepo, Services: []string{"web", "db"}})
+	n.Close()
+
+	if rec.count() != 1 {
+		t.Fatalf("requests: got %d, want 1", rec.count())
+	}
+	if got := rec.requests[0].Header.Get("X-Token"); got != "secret" {
+		t.Errorf("header: got %q, want %q", got, "secret")
+	}
+
+	var payload struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(rec.bodies[0]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != string(EventDriftDetected) {
+		t.Errorf("type: got %q, want %q", payload.Type, EventDriftDetected)
+	}
+	if payload.Text != "test-repo web+db" {
+		t.Errorf("text: got %q, want %q", payload.Text, "test-repo web+db")
+	}
+}
+
+func TestNotifyNtfy(t *testing.T) {
+	rec, srv := newRecorder(t, 0)
+	n := newTestNotifier(t, config.Notification{Type: "ntfy", URL: srv.URL, Topic: "deploys"})
+
+	n.Notify(t.Context(), Event{Type: EventControllerStopped, Repo: testRepo})
+	n.Close()
+
+	if rec.count() != 1 {
+		t.Fatalf("requests: got %d, want 1", rec.count())
+	}
+	req := rec.requests[0]
+	if req.URL.Path != "/deploys" {
+		t.Errorf("path: got %q, want %q", req.URL.Path, "/deploys")
+	}
+	if req.Header.Get("Priority") != "high" {
+		t.Errorf("priority: got %q, want %q", req.Header.Get("Priority"), "high")
+	}
+}
+
+func TestNotifyEventFilter(t *testing.T) {
+	rec, srv := newRecorder(t, 0)
+	n := newTestNotifier(t, config.Notification{Type: "slack", URL: srv.URL, Events: []string{"deployment_failed"}})
+
+	n.Notify(t.Context(), Event{Type: EventDeploymentSucceeded, Repo: testRepo})
+	n.Notify(t.Context(), Event{Type: EventDeploymentFailed, Repo: testRepo})
+	n.Close()
+
+	if rec.count() != 1 {
+		t.Errorf("requests: got %d, want 1", rec.count())
+	}
+}
+
+func TestNotifyRetries(t *testing.T) {
+	rec, srv := newRecorder(t, 2)
+	n := newTestNotifier(t, config.Notification{Type: "slack", URL: srv.URL, Retries: 2})
+
+	n.Notify(t.Context(), Event{Type: EventRollback, Repo: testRepo})
+	n.Close()
+
+	if rec.count() != 1 {
+		t.Errorf("delivered: got %d, want 1", rec.count())
+	}
+}
+
+func TestNotifyDedupe(t *testing.T) {
+	rec, srv := newRecorder(t, 0)
+	n := newTestNotifier(t, config.Notification{Type: "slack", URL: srv.URL, DedupeWindow: time.Minute})
+
+	now := time.Now()
+	n.now = func() time.Time { return now }
+
+	drift := Event{Type: EventDriftDetected, Repo: testRepo, Services: []string{"web"}}
+	n.Notify(t.Context(), drift)
+	n.Notify(t.Context(), drift)
+	n.Notify(t.Context(), Event{Type: EventDriftDetected, Repo: testRepo, Services: []string{"db"}})
+
+	now = now.Add(2 * time.Minute)
+	n.Notify(t.Context(), drift)
+	n.Close()
+
+	if rec.count() != 3 {
+		t.Errorf("requests: got %d, want 3", rec.count())
+	}
+}
+
+func TestNotifyDedupeEvents(t *testing.T) {
+	failed := Event{Type: EventDeploymentFailed, Repo: testRepo, Commit: testCommit, Message: "pull failed"}
+	drift := Event{Type: EventDriftDetected, Repo: testRepo, Services: []string{"web"}}
+
+	tests := []struct {
+		name   string
+		events []string
+		want   int
+	}{
+		{"default dedupes drift only", nil, 3},
+		{"configured types", []string{"deployment_failed"}, 3},
+		{"both", []string{"deployment_failed", "drift_detected"}, 2},
+		{"disabled", []string{}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, srv := newRecorder(t, 0)
+			n := newTestNotifier(t, config.Notification{Type: "slack", URL: srv.URL, DedupeEvents: tt.events})
+			for _, e := range []Event{failed, failed, drift, drift} {
+				n.Notify(t.Context(), e)
+			}
+			n.Close()
+
+			if rec.count() != tt.want {
+				t.Errorf("requests: got %d, want %d", rec.count(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyRateLimit(t *testing.T) {
+	rec, srv := newRecorder(t, 0)
+	n := newTestNotifier(t, config.Notification{Type: "slack", URL: srv.URL, RateLimit: 2})
+
+	for i := range 5 {
+		n.Notify(t.Context(), Event{Type: EventDeploymentSucceeded, Repo: testRepo, Commit: strconv.Itoa(i)})
+	}
+	n.Close()
+
+	if rec.count() != 2 {
+		t.Errorf("requests: got %d, want 2", rec.count())
+	}
+}
+
+func TestNotifyNil(t *testing.T) {
+	var n *Notifier
+	n.Notify(t.Context(), Event{Type: EventRollback})
+	n.Close()
+}
+
+type smtpStandIn struct {
+	addr string
+	mu   sync.Mutex
+	rcpt []string
+	data string
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &smtpStandIn{addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, strings.TrimSpace(line[len("RCPT TO:"):]))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestNotifySMTP(t *testing.T) {
+	standIn := startSMTPStandIn(t)
+	host, port, err := net.SplitHostPort(standIn.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := newTestNotifier(t, config.Notification{
+		Type: "smtp",
+		SMTP: config.SMTP{
+			Host: host,
+			Port: portNum,
+			From: "kedge@example.com",
+			To:   []string{"ops@example.com"},
+		},
+	})
+
+	n.Notify(t.Context(), Event{Type: EventAwaitingApproval, Repo: testRepo, Commit: testCommit})
+	n.Close()
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	if len(standIn.rcpt) != 1 || standIn.rcpt[0] != "<ops@example.com>" {
+		t.Errorf("recipients: got %v", standIn.rcpt)
+	}
+	if !strings.Contains(standIn.data, "Subject: [kedge] test-repo: deployment awaiting approval") {
+		t.Errorf("missing subject in message: %q", standIn.data)
+	}
+}
@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	z "github.com/Oudwins/zog"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+)
+
+type TargetType string
+
+const (
+	TypeSlack   TargetType = "slack"
+	TypeWebhook TargetType = "webhook"
+	TypeNtfy    TargetType = "ntfy"
+	TypeSMTP    TargetType = "smtp"
+)
+
+var TargetTypes = []string{
+	string(TypeSlack),
+	string(TypeWebhook),
+	string(TypeNtfy),
+	string(TypeSMTP),
+}
+
+var targetTypeSchema = z.String().OneOf(TargetTypes)
+
+var ErrInvalidType = errors.New("invalid notification type")
+
+const httpTimeout = 10 * time.Second
+
+func newSender(cfg *config.Notification) (Sender, error) {
+	typ := cfg.Type
+	if err := targetTypeSchema.Validate(&typ); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidType, cfg.Type)
+	}
+
+	client := &http.Client{Timeout: httpTimeout}
+
+	switch TargetType(typ) {
+	case TypeSlack:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("url is required for slack notifications")
+		}
+		return &slackSender{url: cfg.URL, client: client}, nil
+
+	case TypeWebhook:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("url is required for webhook notifications")
+		}
+		return &webhookSender{url: cfg.URL, headers: cfg.Headers, client: client}, nil
+
+	case TypeNtfy:
+		if cfg.URL == "" || cfg.Topic == "" {
+			return nil, fmt.Errorf("url and topic are required for ntfy notifications")
+		}
+		return &ntfySender{
+			url:     strings.TrimSuffix(cfg.URL, "/") + "/" + cfg.Topic,
+			headers: cfg.Headers,
+			client:  client,
+		}, nil
+
+	default:
+		return newSMTPSender(&cfg.SMTP)
+	}
+}
+
+type slackSender struct {
+	url    string
+	client *http.Client
+}
+
+func (s *slackSender) Send(ctx context.Context, _ Event, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.url, "application/json", body, nil)
+}
+
+type webhookSender struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *webhookSender) Send(ctx context.Context, event Event, text string) error {
+	body, err := json.Marshal(struct {
+		Event
+		Summary string `json:"summary"`
+		Text    string `json:"text"`
+	}{Event: event, Summary: event.Summary(), Text: text})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.url, "application/json", body, s.headers)
+}
+
+type ntfySender struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *ntfySender) Send(ctx context.Context, event Event, text string) error {
+	headers := map[string]string{
+		"Title": fmt.Sprintf("kedge: %s", event.Target()),
+		"Tags":  string(event.Type),
+	}
+	if event.Type == EventDeploymentFailed || event.Type == EventControllerStopped || event.Type == EventCommitRejected {
+		headers["Priority"] = "high"
+	}
+	for k, v := range s.headers {
+		headers[k] = v
+	}
+	return post(ctx, s.client, s.url, "text/plain; charset=utf-8", []byte(text), headers)
+}
+
+func post(ctx context.Context, client *http.Client, url, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type smtpSender struct {
+	host     string
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func newSMTPSender(cfg *config.SMTP) (*smtpSender, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("smtp host, from and to are required for smtp notifications")
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+
+	var password string
+	if cfg.PasswordEnv != "" {
+		password = os.Getenv(cfg.PasswordEnv)
+		if password == "" {
+			return nil, fmt.Errorf("environment variable %s is not set or empty", cfg.PasswordEnv)
+		}
+	}
+
+	return &smtpSender{
+		host:     cfg.Host,
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		username: cfg.Username,
+		password: password,
+		from:     cfg.From,
+		to:       cfg.To,
+	}, nil
+}
+
+func (s *smtpSender) Send(ctx context.Context, event Event, text string) error {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if s.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, rcpt := range s.to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(event, text)); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func (s *smtpSender) message(event Event, text string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&buf, "Subject: [kedge] %s: %s\r\n", event.Target(), event.Summary())
+	fmt.Fprintf(&buf, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -9,6 +9,7 @@ import (
 
 	z "github.com/Oudwins/zog"
 
+	"github.com/LoriKarikari/kedge/internal/bus"
 	"github.com/LoriKarikari/kedge/internal/docker"
 	"github.com/compose-spec/compose-go/v2/types"
 )
@@ -21,11 +22,13 @@ const (
 	ModeManual Mode = "manual"
 )
 
-var modeSchema = z.String().OneOf([]string{
+var Modes = []string{
 	string(ModeAuto),
 	string(ModeNotify),
 	string(ModeManual),
-})
+}
+
+var modeSchema = z.String().OneOf(Modes)
 
 var ErrInvalidMode = errors.New("invalid reconcile mode")
 
@@ -38,44 +41,74 @@ func ParseMode(s string) (Mode, error) {
 
 var errProjectNil = errors.New("project is nil")
 
+const defaultInterval = 30 * time.Second
+
 type Config struct {
 	Mode     Mode
 	Interval time.Duration
 }
 
+func (c Config) withDefaults() Config {
+	if c.Mode == "" {
+		c.Mode = ModeAuto
+	}
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	return c
+}
+
 type Result struct {
-	Reconciled bool
-	Changes    []docker.ServiceDiff
-	Error      error
+	Reconciled    bool
+	NeedsApproval bool
+	Changes       []docker.ServiceDiff
+	Error         error
 }
 
 type Reconciler struct {
-	client *docker.Client
-	config Config
-	logger *slog.Logger
+	client   *docker.Client
+	logger   *slog.Logger
+	bus      *bus.Bus
+	repoName string
+	appName  string
 
 	mu      sync.RWMutex
+	config  Config
 	project *types.Project
 	commit  string
+	reset   chan struct{}
 }
 
-func New(client *docker.Client, project *types.Project, cfg Config, logger *slog.Logger) *Reconciler {
-	if logger == nil {
-		logger = slog.Default()
-	}
-	if cfg.Mode == "" {
-		cfg.Mode = ModeAuto
+type Option func(*Reconciler)
+
+func WithEventBus(b *bus.Bus, repoName string) Option {
+	return func(r *Reconciler) {
+		r.bus = b
+		r.repoName = repoName
 	}
-	if cfg.Interval == 0 {
-		cfg.Interval = 30 * time.Second
+}
+
+func WithAppName(name string) Option {
+	return func(r *Reconciler) {
+		r.appName = name
 	}
+}
 
-	return &Reconciler{
+func New(client *docker.Client, project *types.Project, cfg Config, logger *slog.Logger, opts ...Option) *Reconciler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	r := &Reconciler{
 		client:  client,
 		project: project,
-		config:  cfg,
+		config:  cfg.withDefaults(),
 		logger:  logger.With(slog.String("component", "reconciler")),
+		reset:   make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 func (r *Reconciler) SetCommit(commit string) {
@@ -90,6 +123,27 @@ func (r *Reconciler) SetProject(project *types.Project) {
 	r.mu.Unlock()
 }
 
+func (r *Reconciler) Config() Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+func (r *Reconciler) SetConfig(cfg Config) {
+	cfg = cfg.withDefaults()
+	r.mu.Lock()
+	changed := r.config.Interval != cfg.Interval
+	r.config = cfg
+	r.mu.Unlock()
+	if !changed {
+		return
+	}
+	select {
+	case r.reset <- struct{}{}:
+	default:
+	}
+}
+
 func (r *Reconciler) getProjectAndCommit() (*types.Project, string) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -113,20 +167,41 @@ func (r *Reconciler) Reconcile(ctx context.Context) *Result {
 	}
 
 	r.logger.Info("drift detected", slog.String("summary", diff.Summary))
+	mode := r.Config().Mode
+	r.publishDrift(diff, mode)
 
-	if r.config.Mode == ModeNotify {
+	if mode == ModeNotify {
 		r.logger.Info("notify mode: skipping remediation")
-		return &Result{Reconciled: false, Changes: diff.Changes}
+		return &Result{Reconciled: false, NeedsApproval: true, Changes: diff.Changes}
 	}
 
-	if r.config.Mode == ModeManual {
-		r.logger.Info("manual mode: waiting for sync command")
-		return &Result{Reconciled: false, Changes: diff.Changes}
+	if mode == ModeManual {
+		r.logger.Info("manual mode: waiting for approval")
+		return &Result{Reconciled: false, NeedsApproval: true, Changes: diff.Changes}
 	}
 
 	return r.apply(ctx, diff.Changes)
 }
 
+func (r *Reconciler) publishDrift(diff *docker.DiffResult, mode Mode) {
+	_, commit := r.getProjectAndCommit()
+	changes := make([]map[string]any, 0, len(diff.Changes))
+	for _, change := range diff.Changes {
+		changes = append(changes, map[string]any{
+			"service": change.Service,
+			"action":  string(change.Action),
+			"reason":  change.Reason,
+		})
+	}
+	r.bus.Publish(bus.Event{
+		Type:   bus.TypeDriftDetected,
+		Repo:   r.repoName,
+		App:    r.appName,
+		Commit: commit,
+		Data:   map[string]any{"summary": diff.Summary, "mode": string(mode), "changes": changes},
+	})
+}
+
 func (r *Reconciler) Sync(ctx context.Context) *Result {
 	r.logger.Info("force sync requested")
 
@@ -166,13 +241,15 @@ func (r *Reconciler) apply(ctx context.Context, changes []docker.ServiceDiff) *R
 }
 
 func (r *Reconciler) Watch(ctx context.Context, results chan<- *Result) {
-	ticker := time.NewTicker(r.config.Interval)
+	ticker := time.NewTicker(r.Config().Interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-r.reset:
+			ticker.Reset(r.Config().Interval)
 		case <-ticker.C:
 			result := r.Reconcile(ctx)
 			select {
@@ -45,6 +45,36 @@ func TestReconcilerModes(t *testing.T) {
 	}
 }
 
+func TestReconcilerSetConfig(t *testing.T) {
+	client := docker.NewTestClient(t, "kedge-test-set-config")
+
+	r := New(client, nil, Config{Mode: ModeAuto, Interval: time.Hour}, nil)
+
+	results := make(chan *Result, 1)
+	go r.Watch(t.Context(), results)
+
+	r.SetConfig(Config{Mode: ModeManual, Interval: 20 * time.Millisecond})
+
+	cfg := r.Config()
+	if cfg.Mode != ModeManual {
+		t.Errorf("got mode %q, want %q", cfg.Mode, ModeManual)
+	}
+
+	select {
+	case result := <-results:
+		if result.Error == nil {
+			t.Error("expected error for reconcile without a project")
+		}
+	case <-time.After(time.Second):
+		t.Error("Watch did not pick up the new interval")
+	}
+
+	r.SetConfig(Config{})
+	if cfg := r.Config(); cfg.Mode != ModeAuto || cfg.Interval != 30*time.Second {
+		t.Errorf("got %+v, want defaults", cfg)
+	}
+}
+
 func TestIntegrationReconcileAutoMode(t *testing.T) {
 	if testing.Short() {
 		t.Skip(docker.SkipIntegrationMsg)
@@ -66,7 +96,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := docker.LoadProject(ctx, composePath, projectName)
+	project, err := docker.LoadProject(ctx, []string{composePath}, projectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -114,7 +144,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := docker.LoadProject(ctx, composePath, projectName)
+	project, err := docker.LoadProject(ctx, []string{composePath}, projectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -130,6 +160,10 @@ services:
 		t.Error("expected reconciled=false for notify mode")
 	}
 
+	if !result.NeedsApproval {
+		t.Error("expected needs approval for notify mode")
+	}
+
 	if len(result.Changes) == 0 {
 		t.Error("expected changes to be reported")
 	}
@@ -165,7 +199,7 @@ services:
 		t.Fatal(err)
 	}
 
-	project, err := docker.LoadProject(ctx, composePath, projectName)
+	project, err := docker.LoadProject(ctx, []string{composePath}, projectName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/logging"
+	"github.com/LoriKarikari/kedge/internal/notify"
+	"github.com/LoriKarikari/kedge/internal/reconcile"
+	"github.com/LoriKarikari/kedge/internal/telemetry"
+	"github.com/samber/lo"
+)
+
+const (
+	ID      = "https://lorikarikari.github.io/kedge/kedge.schema.json"
+	Version = "https://json-schema.org/draft/2020-12/schema"
+
+	durationPattern = `^(0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+)
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+}
+
+var enums = map[string][]string{
+	"reconciliation.mode":             reconcile.Modes,
+	"logging.level":                   logging.Levels,
+	"logging.format":                  logging.Formats,
+	"telemetry.metrics.otlp.protocol": telemetry.Protocols,
+	"notifications[].type":            notify.TargetTypes,
+	"notifications[].events[]":        notify.EventTypes,
+	"notifications[].dedupe_events[]": notify.EventTypes,
+}
+
+type bounds struct {
+	min, max *float64
+}
+
+var ranges = map[string]bounds{
+	"server.port":                    {lo.ToPtr(0.0), lo.ToPtr(65535.0)},
+	"notifications[].smtp.port":      {lo.ToPtr(0.0), lo.ToPtr(65535.0)},
+	"notifications[].rate_limit":     {min: lo.ToPtr(0.0)},
+	"notifications[].retries":        {min: lo.ToPtr(0.0)},
+	"telemetry.tracing.sample_ratio": {lo.ToPtr(0.0), lo.ToPtr(1.0)},
+}
+
+var generate = sync.OnceValues(func() ([]byte, error) {
+	root := build(reflect.ValueOf(config.Default()).Elem(), "")
+	root.Schema = Version
+	root.ID = ID
+	root.Title = "kedge configuration"
+	root.Description = "Schema for kedge.yaml and the global kedge config file"
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+})
+
+func Generate() ([]byte, error) {
+	return generate()
+}
+
+func build(v reflect.Value, path string) *Schema {
+	t := v.Type()
+	if t == durationType {
+		s := &Schema{
+			Type:        "string",
+			Pattern:     durationPattern,
+			Description: "Duration such as 30s, 5m or 1h30m",
+		}
+		if d := time.Duration(v.Int()); d != 0 {
+			s.Default = d.String()
+		}
+		return s
+	}
+
+	var s *Schema
+	switch t.Kind() {
+	case reflect.Struct:
+		s = &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		for i := range t.NumField() {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			s.Properties[name] = build(v.Field(i), join(path, name))
+		}
+		return s
+	case reflect.Slice:
+		s = &Schema{Type: "array", Items: build(reflect.New(t.Elem()).Elem(), path+"[]")}
+	case reflect.Map:
+		s = &Schema{Type: "object", AdditionalProperties: build(reflect.New(t.Elem()).Elem(), path+".*")}
+	case reflect.String:
+		s = &Schema{Type: "string"}
+	case reflect.Bool:
+		s = &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		s = &Schema{Type: "integer"}
+	case reflect.Float64:
+		s = &Schema{Type: "number"}
+	default:
+		s = &Schema{}
+	}
+
+	if enum, ok := enums[path]; ok {
+		s.Enum = enum
+	}
+	if r, ok := ranges[path]; ok {
+		s.Minimum, s.Maximum = r.min, r.max
+	}
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Map && !v.IsZero() {
+		s.Default = v.Interface()
+	}
+	return s
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
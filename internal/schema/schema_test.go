@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+const golden = "../../docs/docs/kedge.schema.json"
+
+var update = flag.Bool("update", false, "rewrite the checked-in schema")
+
+func TestSchemaUpToDate(t *testing.T) {
+	got, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(golden, got, 0o600); err != nil {
+			t.Fatalf("write %s: %v", golden, err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read %s: %v", golden, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date with config.Config; run go test ./internal/schema -update", golden)
+	}
+}
+
+func TestSchemaContents(t *testing.T) {
+	data, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var root Schema
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	mode := root.Properties["reconciliation"].Properties["mode"]
+	if len(mode.Enum) == 0 || mode.Default != "auto" {
+		t.Errorf("reconciliation.mode = %+v, want enum with default auto", mode)
+	}
+	if format := root.Properties["logging"].Properties["format"]; len(format.Enum) != 2 {
+		t.Errorf("logging.format enum = %v", format.Enum)
+	}
+
+	interval := root.Properties["git"].Properties["poll_interval"]
+	if interval.Type != "string" || interval.Pattern == "" || interval.Default != "1m0s" {
+		t.Errorf("git.poll_interval = %+v, want duration string", interval)
+	}
+
+	events := root.Properties["notifications"].Items.Properties["events"]
+	if events.Items == nil || len(events.Items.Enum) == 0 {
+		t.Errorf("notifications[].events = %+v, want enum items", events)
+	}
+	if root.AdditionalProperties != false {
+		t.Errorf("additionalProperties = %v, want false", root.AdditionalProperties)
+	}
+}
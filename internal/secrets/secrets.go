@@ -0,0 +1,176 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	keySize   = 32
+	nonceSize = 24
+	version   = "v1"
+)
+
+var (
+	ErrNoKeyFile     = errors.New("secret key file not found")
+	ErrUnknownKey    = errors.New("secret was encrypted with a key that is not in the key file")
+	ErrMalformed     = errors.New("malformed encrypted secret")
+	ErrDecryptFailed = errors.New("secret could not be decrypted")
+)
+
+type key struct {
+	id     string
+	secret [keySize]byte
+}
+
+// Keyring holds the keys in a key file. The first key encrypts; every key
+// can decrypt, so secrets stay readable while a rotation is in progress.
+type Keyring struct {
+	keys []key
+}
+
+func Load(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoKeyFile, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read secret key file: %w", err)
+	}
+
+	k := &Keyring{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(text)
+		if err != nil || len(raw) != keySize {
+			return nil, fmt.Errorf("%s:%d: invalid key", path, line)
+		}
+		k.keys = append(k.keys, newKey([keySize]byte(raw)))
+	}
+	if len(k.keys) == 0 {
+		return nil, fmt.Errorf("%s: no keys", path)
+	}
+	return k, nil
+}
+
+// LoadOrCreate loads the key file, generating it with a single key first if
+// it does not exist yet.
+func LoadOrCreate(path string) (*Keyring, error) {
+	k, err := Load(path)
+	if !errors.Is(err, ErrNoKeyFile) {
+		return k, err
+	}
+	k = &Keyring{}
+	if err := k.Rotate(); err != nil {
+		return nil, err
+	}
+	if err := k.Save(path); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func newKey(secret [keySize]byte) key {
+	sum := sha256.Sum256(secret[:])
+	return key{id: hex.EncodeToString(sum[:4]), secret: secret}
+}
+
+// Rotate generates a new primary key. The previous keys are kept until Prune.
+func (k *Keyring) Rotate() error {
+	var secret [keySize]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		return err
+	}
+	k.keys = append([]key{newKey(secret)}, k.keys...)
+	return nil
+}
+
+// Prune drops every key except the primary one.
+func (k *Keyring) Prune() {
+	k.keys = k.keys[:1]
+}
+
+func (k *Keyring) KeyID() string {
+	return k.keys[0].id
+}
+
+// Save writes the key file atomically with owner-only permissions.
+func (k *Keyring) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("# kedge secret keys; the first key encrypts. Keep this file private and backed up.\n")
+	for _, key := range k.keys {
+		buf.WriteString(base64.StdEncoding.EncodeToString(key.secret[:]) + "\n")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".kedge-key-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Encrypt seals plaintext with the primary key as "v1:<key id>:<base64>".
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	primary := k.keys[0]
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+	sealed := secretbox.Seal(nonce[:], plaintext, &nonce, &primary.secret)
+	return version + ":" + primary.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) Decrypt(ciphertext string) ([]byte, error) {
+	parts := strings.Split(ciphertext, ":")
+	if len(parts) != 3 || parts[0] != version {
+		return nil, ErrMalformed
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < nonceSize+secretbox.Overhead {
+		return nil, ErrMalformed
+	}
+
+	for _, key := range k.keys {
+		if key.id != parts[1] {
+			continue
+		}
+		nonce := [nonceSize]byte(sealed[:nonceSize])
+		plaintext, ok := secretbox.Open(nil, sealed[nonceSize:], &nonce, &key.secret)
+		if !ok {
+			return nil, ErrDecryptFailed
+		}
+		return plaintext, nil
+	}
+	return nil, fmt.Errorf("%w (key %s)", ErrUnknownKey, parts[1])
+}
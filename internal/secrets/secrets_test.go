@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadOrCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "secret.key")
+
+	k, err := LoadOrCreate(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreate() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("key file mode = %04o, want 0600", perm)
+	}
+
+	again, err := LoadOrCreate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.KeyID() != k.KeyID() {
+		t.Errorf("reloaded key ID = %s, want %s", again.KeyID(), k.KeyID())
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, ErrNoKeyFile) {
+		t.Errorf("Load() error = %v, want ErrNoKeyFile", err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := LoadOrCreate(filepath.Join(t.TempDir(), "secret.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := k.Encrypt([]byte("ghp_secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(ciphertext, "ghp_secret") || !strings.HasPrefix(ciphertext, "v1:"+k.KeyID()+":") {
+		t.Errorf("Encrypt() = %q", ciphertext)
+	}
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil || string(plaintext) != "ghp_secret" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+
+	tampered := ciphertext[:len(ciphertext)-4] + "AAA="
+	if _, err := k.Decrypt(tampered); !errors.Is(err, ErrDecryptFailed) && !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decrypt(tampered) error = %v", err)
+	}
+	if _, err := k.Decrypt("plaintext"); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decrypt(plaintext) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.key")
+	k, err := LoadOrCreate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := k.Encrypt([]byte("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldID := k.KeyID()
+
+	if err := k.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	k, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.KeyID() == oldID {
+		t.Fatal("Rotate() kept the old primary key")
+	}
+	if plaintext, err := k.Decrypt(old); err != nil || string(plaintext) != "token" {
+		t.Fatalf("Decrypt(old) during rotation = %q, %v", plaintext, err)
+	}
+
+	k.Prune()
+	if _, err := k.Decrypt(old); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt(old) after Prune error = %v, want ErrUnknownKey", err)
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/samber/lo"
+
+	"github.com/LoriKarikari/kedge/internal/controller"
+	"github.com/LoriKarikari/kedge/internal/manager"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+const defaultReviewer = "api"
+
+type Reviewer interface {
+	PendingApprovals(ctx context.Context, repoName, app string) ([]*state.Deployment, error)
+	Approve(ctx context.Context, repoName, app string, id int64, approver string) (*state.Deployment, error)
+	Reject(ctx context.Context, repoName, app string, id int64, reviewer string) (*state.Deployment, error)
+}
+
+type Deployment struct {
+	ID         int64      `json:"id"`
+	Repo       string     `json:"repo"`
+	App        string     `json:"app,omitempty"`
+	Commit     string     `json:"commit"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
+	Diff       string     `json:"diff,omitempty"`
+	DeployedAt time.Time  `json:"deployed_at"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+type RepoInput struct {
+	Repo string `path:"repo" doc:"Repository name"`
+	App  string `query:"app" doc:"App name, required for repositories with multiple apps"`
+}
+
+type ReviewInput struct {
+	Repo string      `path:"repo" doc:"Repository name"`
+	App  string      `query:"app" doc:"App name, required for repositories with multiple apps"`
+	ID   int64       `path:"id" doc:"Deployment ID"`
+	Body *ReviewBody `required:"false"`
+}
+
+type ReviewBody struct {
+	Reviewer string `json:"reviewer,omitempty" doc:"Identity recorded as the reviewer when the server has no authenticator; authenticated requests are attributed to the token"`
+}
+
+func (i *ReviewInput) reviewer() string {
+	if i.Body == nil || i.Body.Reviewer == "" {
+		return defaultReviewer
+	}
+	return i.Body.Reviewer
+}
+
+type ApprovalsOutput struct {
+	Body struct {
+		Deployments []Deployment `json:"deployments"`
+	}
+}
+
+type DeploymentOutput struct {
+	Body Deployment
+}
+
+func (s *Server) registerApprovals(api huma.API) {
+	huma.Register(api, secured(huma.Operation{
+		OperationID: "list-approvals",
+		Method:      http.MethodGet,
+		Path:        "/repos/{repo}/approvals",
+		Summary:     "List deployments awaiting approval",
+	}, state.RoleViewer), s.handleListApprovals)
+
+	huma.Register(api, secured(huma.Operation{
+		OperationID: "approve-deployment",
+		Method:      http.MethodPost,
+		Path:        "/repos/{repo}/deployments/{id}/approve",
+		Summary:     "Approve and apply a pending deployment",
+	}, state.RoleOperator), s.handleApprove)
+
+	huma.Register(api, secured(huma.Operation{
+		OperationID: "reject-deployment",
+		Method:      http.MethodPost,
+		Path:        "/repos/{repo}/deployments/{id}/reject",
+		Summary:     "Reject a pending deployment",
+	}, state.RoleOperator), s.handleReject)
+}
+
+func (s *Server) handleListApprovals(ctx context.Context, input *RepoInput) (*ApprovalsOutput, error) {
+	deployments, err := s.reviewer.PendingApprovals(ctx, input.Repo, input.App)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	output := &ApprovalsOutput{}
+	output.Body.Deployments = lo.Map(deployments, func(d *state.Deployment, _ int) Deployment {
+		return toDeployment(d)
+	})
+	return output, nil
+}
+
+func (s *Server) handleApprove(ctx context.Context, input *ReviewInput) (*DeploymentOutput, error) {
+	d, err := s.reviewer.Approve(ctx, input.Repo, input.App, input.ID, actor(ctx, input.reviewer()))
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return &DeploymentOutput{Body: toDeployment(d)}, nil
+}
+
+func (s *Server) handleReject(ctx context.Context, input *ReviewInput) (*DeploymentOutput, error) {
+	d, err := s.reviewer.Reject(ctx, input.Repo, input.App, input.ID, actor(ctx, input.reviewer()))
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return &DeploymentOutput{Body: toDeployment(d)}, nil
+}
+
+func toDeployment(d *state.Deployment) Deployment {
+	return Deployment{
+		ID:         d.ID,
+		Repo:       d.RepoName,
+		App:        d.App,
+		Commit:     d.CommitHash,
+		Status:     string(d.Status),
+		Message:    d.Message,
+		Diff:       d.Diff,
+		DeployedAt: d.DeployedAt,
+		ReviewedBy: d.ReviewedBy,
+		ReviewedAt: lo.Ternary(d.ReviewedAt.IsZero(), nil, &d.ReviewedAt),
+	}
+}
+
+func apiError(err error) error {
+	switch {
+	case errors.Is(err, state.ErrNotFound), errors.Is(err, manager.ErrRepoNotRunning):
+		return huma.Error404NotFound(err.Error())
+	case errors.Is(err, manager.ErrAppRequired):
+		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, state.ErrNotAwaiting), errors.Is(err, controller.ErrStaleApproval):
+		return huma.Error409Conflict(err.Error())
+	default:
+		return huma.Error500InternalServerError(err.Error())
+	}
+}
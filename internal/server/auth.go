@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+const (
+	roleMetadata   = "role"
+	securityScheme = "bearer"
+)
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errForbidden    = errors.New("token does not grant access to this resource")
+)
+
+type Authenticator interface {
+	AuthenticateToken(ctx context.Context, secret string) (*state.Token, error)
+}
+
+type tokenKey struct{}
+
+func tokenFromContext(ctx context.Context) *state.Token {
+	token, _ := ctx.Value(tokenKey{}).(*state.Token)
+	return token
+}
+
+func actor(ctx context.Context, fallback string) string {
+	if token := tokenFromContext(ctx); token != nil {
+		return token.Identity()
+	}
+	return fallback
+}
+
+func secured(op huma.Operation, role state.Role) huma.Operation {
+	if op.Metadata == nil {
+		op.Metadata = map[string]any{}
+	}
+	op.Metadata[roleMetadata] = role
+	op.Security = []map[string][]string{{securityScheme: {}}}
+	return op
+}
+
+func (s *Server) authMiddleware(api huma.API) func(huma.Context, func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		role, ok := ctx.Operation().Metadata[roleMetadata].(state.Role)
+		if !ok || s.auth == nil {
+			next(ctx)
+			return
+		}
+
+		repo := ctx.Param("repo")
+		if repo == "" {
+			repo = ctx.Query("repo")
+		}
+
+		token, status, err := s.authorize(ctx.Context(), ctx.Header("Authorization"), role, []string{repo})
+		if err != nil {
+			if status == http.StatusUnauthorized {
+				ctx.SetHeader("WWW-Authenticate", "Bearer")
+			}
+			_ = huma.WriteErr(api, ctx, status, err.Error())
+			return
+		}
+		next(huma.WithValue(ctx, tokenKey{}, token))
+	}
+}
+
+func (s *Server) requireRole(role state.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next(w, r)
+			return
+		}
+
+		repos := r.URL.Query()["repo"]
+		if len(repos) == 0 {
+			repos = []string{""}
+		}
+
+		token, status, err := s.authorize(r.Context(), r.Header.Get("Authorization"), role, repos)
+		if err != nil {
+			if status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)))
+	}
+}
+
+func (s *Server) authorize(ctx context.Context, header string, role state.Role, repos []string) (*state.Token, int, error) {
+	secret, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || secret == "" {
+		return nil, http.StatusUnauthorized, errMissingToken
+	}
+
+	token, err := s.auth.AuthenticateToken(ctx, strings.TrimSpace(secret))
+	if errors.Is(err, state.ErrInvalidToken) {
+		return nil, http.StatusUnauthorized, err
+	}
+	if err != nil {
+		s.logger.Error("token authentication failed", slog.Any("error", err))
+		return nil, http.StatusInternalServerError, errors.New("authentication failed")
+	}
+
+	for _, repo := range repos {
+		if !token.Permits(role, repo) {
+			return nil, http.StatusForbidden, errForbidden
+		}
+	}
+	return token, http.StatusOK, nil
+}
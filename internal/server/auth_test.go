@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+type fakeAuthenticator map[string]*state.Token
+
+func (f fakeAuthenticator) AuthenticateToken(_ context.Context, secret string) (*state.Token, error) {
+	if token, ok := f[secret]; ok {
+		return token, nil
+	}
+	return nil, state.ErrInvalidToken
+}
+
+type fakeReviewer struct {
+	reviewer string
+}
+
+func (r *fakeReviewer) PendingApprovals(context.Context, string, string) ([]*state.Deployment, error) {
+	return nil, nil
+}
+
+func (r *fakeReviewer) Approve(_ context.Context, repoName, app string, id int64, approver string) (*state.Deployment, error) {
+	r.reviewer = approver
+	return &state.Deployment{ID: id, RepoName: repoName, App: app, Status: state.StatusSuccess, ReviewedBy: approver}, nil
+}
+
+func (r *fakeReviewer) Reject(_ context.Context, repoName, app string, id int64, reviewer string) (*state.Deployment, error) {
+	r.reviewer = reviewer
+	return &state.Deployment{ID: id, RepoName: repoName, App: app, Status: state.StatusRejected, ReviewedBy: reviewer}, nil
+}
+
+type fakeEventLog struct{}
+
+func (fakeEventLog) ListEvents(context.Context, state.EventFilter) ([]*state.Event, error) {
+	return nil, nil
+}
+
+var testTokens = fakeAuthenticator{
+	"viewer":   {Name: "dashboard", Role: state.RoleViewer},
+	"operator": {Name: "deployer", Role: state.RoleOperator},
+	"web-only": {Name: "web-ci", Role: state.RoleOperator, RepoName: "web"},
+}
+
+func newTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	return New(0, nil, nil, slog.New(slog.DiscardHandler), opts...)
+}
+
+func serve(s *Server, method, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	s := newTestServer(t, WithAuthenticator(testTokens), WithReviewer(&fakeReviewer{}), WithEventLog(fakeEventLog{}))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		token  string
+		want   int
+	}{
+		{"health is public", http.MethodGet, "/health", "", http.StatusOK},
+		{"missing token", http.MethodGet, "/repos/web/approvals", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/repos/web/approvals", "nope", http.StatusUnauthorized},
+		{"viewer lists", http.MethodGet, "/repos/web/approvals", "viewer", http.StatusOK},
+		{"viewer cannot approve", http.MethodPost, "/repos/web/deployments/1/approve", "viewer", http.StatusForbidden},
+		{"operator approves", http.MethodPost, "/repos/web/deployments/1/approve", "operator", http.StatusOK},
+		{"scoped token in its repo", http.MethodPost, "/repos/web/deployments/1/reject", "web-only", http.StatusOK},
+		{"scoped token in another repo", http.MethodPost, "/repos/api/deployments/1/approve", "web-only", http.StatusForbidden},
+		{"scoped token lists its repo events", http.MethodGet, "/events?repo=web", "web-only", http.StatusOK},
+		{"scoped token lists all events", http.MethodGet, "/events", "web-only", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, tt.method, tt.target, tt.token, "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestReviewActor(t *testing.T) {
+	reviewer := &fakeReviewer{}
+	s := newTestServer(t, WithAuthenticator(testTokens), WithReviewer(reviewer))
+
+	for _, action := range []string{"approve", "reject"} {
+		rec := serve(s, http.MethodPost, "/repos/web/deployments/7/"+action, "operator", `{"reviewer":"mallory"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s status = %d: %s", action, rec.Code, rec.Body)
+		}
+		if reviewer.reviewer != "token:deployer" {
+			t.Errorf("%s reviewer = %q, want the token identity", action, reviewer.reviewer)
+		}
+	}
+
+	open := newTestServer(t, WithReviewer(reviewer))
+	if rec := serve(open, http.MethodPost, "/repos/web/deployments/7/approve", "", `{"reviewer":"alice"}`); rec.Code != http.StatusOK || reviewer.reviewer != "alice" {
+		t.Errorf("unauthenticated approve = %d by %q, want 200 by alice", rec.Code, reviewer.reviewer)
+	}
+	if rec := serve(open, http.MethodPost, "/repos/web/deployments/7/reject", "", ""); rec.Code != http.StatusOK || reviewer.reviewer != defaultReviewer {
+		t.Errorf("anonymous reject = %d by %q, want 200 by %s", rec.Code, reviewer.reviewer, defaultReviewer)
+	}
+}
+
+func TestStreamAuth(t *testing.T) {
+	s := newTestServer(t, WithAuthenticator(testTokens), WithEventBus(bus.New(0)))
+	ts := httptest.NewServer(s.server.Handler)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		want   int
+	}{
+		{"missing token", "/events/stream", "", http.StatusUnauthorized},
+		{"invalid token", "/events/stream", "nope", http.StatusUnauthorized},
+		{"unscoped token", "/events/stream", "viewer", http.StatusOK},
+		{"scoped token without repo", "/events/stream", "web-only", http.StatusForbidden},
+		{"scoped token in another repo", "/events/stream?repo=web&repo=api", "web-only", http.StatusForbidden},
+		{"scoped token in its repo", "/events/stream?repo=web", "web-only", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/samber/lo"
+
+	"github.com/LoriKarikari/kedge/internal/state"
+)
+
+type EventLog interface {
+	ListEvents(ctx context.Context, f state.EventFilter) ([]*state.Event, error)
+}
+
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Repo      string          `json:"repo,omitempty"`
+	App       string          `json:"app,omitempty"`
+	Service   string          `json:"service,omitempty"`
+	Commit    string          `json:"commit,omitempty"`
+	Actor     string          `json:"actor,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type EventsInput struct {
+	Repo  string   `query:"repo" doc:"Only return events for this repository"`
+	App   string   `query:"app" doc:"Only return events for this app"`
+	Type  []string `query:"type" doc:"Only return events of these types or type prefixes"`
+	Since string   `query:"since" doc:"Duration (e.g. 1h) or RFC3339 timestamp"`
+	Limit int      `query:"limit" minimum:"0" maximum:"1000" doc:"Maximum number of events"`
+}
+
+type EventsOutput struct {
+	Body struct {
+		Events []Event `json:"events"`
+	}
+}
+
+func (s *Server) registerEvents(api huma.API) {
+	huma.Register(api, secured(huma.Operation{
+		OperationID: "list-events",
+		Method:      http.MethodGet,
+		Path:        "/events",
+		Summary:     "List recorded events",
+	}, state.RoleViewer), s.handleListEvents)
+}
+
+func (s *Server) handleListEvents(ctx context.Context, input *EventsInput) (*EventsOutput, error) {
+	since, err := state.ParseSince(input.Since, time.Now())
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	events, err := s.events.ListEvents(ctx, state.EventFilter{
+		RepoName: input.Repo,
+		App:      input.App,
+		Types:    input.Type,
+		Since:    since,
+		Limit:    input.Limit,
+	})
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	output := &EventsOutput{}
+	output.Body.Events = lo.Map(events, func(e *state.Event, _ int) Event {
+		return toEvent(e)
+	})
+	return output, nil
+}
+
+func toEvent(e *state.Event) Event {
+	return Event{
+		ID:        e.ID,
+		Type:      string(e.Type),
+		Repo:      e.RepoName,
+		App:       e.App,
+		Service:   e.Service,
+		Commit:    e.Commit,
+		Actor:     e.Actor,
+		Payload:   e.Payload,
+		CreatedAt: e.CreatedAt,
+	}
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/LoriKarikari/kedge/internal/schema"
+)
+
+type SchemaOutput struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+func (s *Server) registerSchema(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "config-schema",
+		Method:      http.MethodGet,
+		Path:        "/schema",
+		Summary:     "JSON Schema for kedge.yaml",
+	}, s.handleSchema)
+}
+
+func (s *Server) handleSchema(ctx context.Context, input *struct{}) (*SchemaOutput, error) {
+	data, err := schema.Generate()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("generate schema", err)
+	}
+	return &SchemaOutput{ContentType: "application/schema+json", Body: data}, nil
+}
@@ -2,16 +2,21 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humago"
 
+	"github.com/LoriKarikari/kedge/internal/bus"
+	"github.com/LoriKarikari/kedge/internal/state"
 	"github.com/LoriKarikari/kedge/internal/telemetry"
 )
 
@@ -21,11 +26,68 @@ type ReadinessChecker interface {
 
 type Server struct {
 	server    *http.Server
+	port      int
+	address   string
+	socket    string
+	certFile  string
+	keyFile   string
+	clientCA  string
+	tls       *certReloader
 	checker   ReadinessChecker
+	reviewer  Reviewer
+	events    EventLog
+	auth      Authenticator
+	bus       *bus.Bus
 	telemetry *telemetry.Provider
 	logger    *slog.Logger
 }
 
+type Option func(*Server)
+
+func WithReviewer(r Reviewer) Option {
+	return func(s *Server) {
+		s.reviewer = r
+	}
+}
+
+func WithEventLog(l EventLog) Option {
+	return func(s *Server) {
+		s.events = l
+	}
+}
+
+func WithAuthenticator(a Authenticator) Option {
+	return func(s *Server) {
+		s.auth = a
+	}
+}
+
+func WithAddress(address string) Option {
+	return func(s *Server) {
+		s.address = address
+	}
+}
+
+func WithUnixSocket(path string) Option {
+	return func(s *Server) {
+		s.socket = path
+	}
+}
+
+func WithTLS(certFile, keyFile, clientCAFile string) Option {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+		s.clientCA = clientCAFile
+	}
+}
+
+func WithEventBus(b *bus.Bus) Option {
+	return func(s *Server) {
+		s.bus = b
+	}
+}
+
 type HealthOutput struct {
 	Body struct {
 		Status string `json:"status"`
@@ -39,27 +101,37 @@ type ReadyOutput struct {
 	}
 }
 
-func New(port int, checker ReadinessChecker, tp *telemetry.Provider, logger *slog.Logger) *Server {
+func New(port int, checker ReadinessChecker, tp *telemetry.Provider, logger *slog.Logger, opts ...Option) *Server {
 	mux := http.NewServeMux()
-	api := humago.New(mux, huma.DefaultConfig("Kedge API", "1.0.0"))
+	apiCfg := huma.DefaultConfig("Kedge API", "1.0.0")
+	apiCfg.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
+		securityScheme: {Type: "http", Scheme: "bearer"},
+	}
+	api := humago.New(mux, apiCfg)
 
 	if logger == nil {
 		logger = slog.Default()
 	}
 
 	s := &Server{
+		port: port,
 		server: &http.Server{
-			Addr:              fmt.Sprintf(":%d", port),
 			Handler:           mux,
 			ReadHeaderTimeout: 10 * time.Second,
 			ReadTimeout:       15 * time.Second,
 			WriteTimeout:      15 * time.Second,
 			IdleTimeout:       120 * time.Second,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
 		},
 		checker:   checker,
 		telemetry: tp,
 		logger:    logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	api.UseMiddleware(s.authMiddleware(api))
 
 	huma.Register(api, huma.Operation{
 		OperationID: "health",
@@ -75,7 +147,21 @@ func New(port int, checker ReadinessChecker, tp *telemetry.Provider, logger *slo
 		Summary:     "Readiness check",
 	}, s.handleReady)
 
-	if tp != nil {
+	s.registerSchema(api)
+
+	if s.reviewer != nil {
+		s.registerApprovals(api)
+	}
+
+	if s.events != nil {
+		s.registerEvents(api)
+	}
+
+	if s.bus != nil {
+		mux.HandleFunc("GET /events/stream", s.requireRole(state.RoleViewer, s.handleStream))
+	}
+
+	if tp != nil && tp.ServesPrometheus() {
 		mux.Handle("/metrics", tp.Handler())
 	}
 
@@ -102,12 +188,30 @@ func (s *Server) handleReady(ctx context.Context, input *struct{}) (*ReadyOutput
 	return output, nil
 }
 
+func (s *Server) Addr() string {
+	if s.socket != "" {
+		return "unix:" + s.socket
+	}
+	return net.JoinHostPort(s.address, strconv.Itoa(s.port))
+}
+
 func (s *Server) Start(ctx context.Context) error {
-	lc := &net.ListenConfig{}
-	ln, err := lc.Listen(ctx, "tcp", s.server.Addr)
+	if s.certFile != "" || s.keyFile != "" {
+		reloader, err := newCertReloader(s.certFile, s.keyFile, s.clientCA, s.logger)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		s.tls = reloader
+	}
+
+	ln, err := s.listen(ctx)
 	if err != nil {
 		return err
 	}
+	if s.tls != nil {
+		ln = tls.NewListener(ln, s.tls.TLSConfig())
+	}
+
 	go func() {
 		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			s.logger.Error("server error", slog.Any("error", err))
@@ -116,6 +220,26 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+func (s *Server) listen(ctx context.Context) (net.Listener, error) {
+	lc := &net.ListenConfig{}
+	if s.socket == "" {
+		return lc.Listen(ctx, "tcp", s.Addr())
+	}
+
+	if err := os.Remove(s.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	ln, err := lc.Listen(ctx, "unix", s.socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(s.socket, 0o660); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	return ln, nil
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/LoriKarikari/kedge/internal/bus"
+)
+
+type frame struct {
+	id    string
+	event string
+	data  string
+	retry string
+}
+
+type streamReader struct {
+	t *testing.T
+	r *bufio.Reader
+}
+
+func openStream(t *testing.T, url string, header http.Header) *streamReader {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	s := &streamReader{t: t, r: bufio.NewReader(resp.Body)}
+	if f := s.next(); f.retry != "3000" {
+		t.Fatalf("first frame = %+v, want a retry hint", f)
+	}
+	return s
+}
+
+func (s *streamReader) next() frame {
+	s.t.Helper()
+	var f frame
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			s.t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return f
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			f.id = value
+		case "event":
+			f.event = value
+		case "data":
+			f.data = value
+		case "retry":
+			f.retry = value
+		default:
+			s.t.Fatalf("unexpected stream line %q", line)
+		}
+	}
+}
+
+func (s *streamReader) event() bus.Event {
+	s.t.Helper()
+	f := s.next()
+	var e bus.Event
+	if err := json.Unmarshal([]byte(f.data), &e); err != nil {
+		s.t.Fatalf("decode data %q: %v", f.data, err)
+	}
+	if f.id != strconv.FormatUint(e.ID, 10) || f.event != e.Type {
+		s.t.Errorf("frame id/event = %s/%s, data id/type = %d/%s", f.id, f.event, e.ID, e.Type)
+	}
+	return e
+}
+
+func newStreamServer(t *testing.T) (*httptest.Server, *bus.Bus) {
+	t.Helper()
+	b := bus.New(0)
+	ts := httptest.NewServer(newTestServer(t, WithEventBus(b)).server.Handler)
+	t.Cleanup(ts.Close)
+	return ts, b
+}
+
+func TestStreamFraming(t *testing.T) {
+	ts, b := newStreamServer(t)
+	s := openStream(t, ts.URL+"/events/stream", nil)
+
+	b.Publish(bus.Event{Type: bus.TypeGitCommit, Repo: "web", Commit: "abc123", Data: map[string]any{"message": "fix"}})
+
+	f := s.next()
+	if f.id != "1" || f.event != bus.TypeGitCommit {
+		t.Fatalf("frame = %+v, want id 1 of type %s", f, bus.TypeGitCommit)
+	}
+	var e bus.Event
+	if err := json.Unmarshal([]byte(f.data), &e); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if e.ID != 1 || e.Repo != "web" || e.Commit != "abc123" || e.Data["message"] != "fix" || e.Time.IsZero() {
+		t.Errorf("data = %+v", e)
+	}
+}
+
+func TestStreamFilter(t *testing.T) {
+	ts, b := newStreamServer(t)
+	s := openStream(t, ts.URL+"/events/stream?repo=web&type=deploy.service&type=reconcile.started", nil)
+
+	b.Publish(bus.Event{Type: bus.TypeServiceDeployStarted, Repo: "api"})
+	b.Publish(bus.Event{Type: bus.TypeGitCommit, Repo: "web"})
+	b.Publish(bus.Event{Type: bus.TypeReconcileFinished, Repo: "web"})
+	b.Publish(bus.Event{Type: "deploy.services", Repo: "web"})
+	b.Publish(bus.Event{Type: bus.TypeReconcileStarted, Repo: "web"})
+	b.Publish(bus.Event{Type: bus.TypeServiceDeployFinished, Repo: "web"})
+
+	for _, want := range []uint64{5, 6} {
+		if e := s.event(); e.ID != want {
+			t.Errorf("event = %d (%s in %s), want %d", e.ID, e.Type, e.Repo, want)
+		}
+	}
+}
+
+func TestStreamResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header http.Header
+		want   []uint64
+	}{
+		{"header", "/events/stream?repo=web", http.Header{"Last-Event-ID": {"1"}}, []uint64{3, 4, 5}},
+		{"query", "/events/stream?last_event_id=2", nil, []uint64{3, 4, 5}},
+		{"header wins over query", "/events/stream?last_event_id=1", http.Header{"Last-Event-ID": {"3"}}, []uint64{4, 5}},
+		{"no id skips history", "/events/stream", nil, []uint64{5}},
+		{"id from a previous run skips history", "/events/stream", http.Header{"Last-Event-ID": {"99"}}, []uint64{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, b := newStreamServer(t)
+			for _, repo := range []string{"web", "api", "web", "web"} {
+				b.Publish(bus.Event{Type: bus.TypeGitCommit, Repo: repo})
+			}
+
+			s := openStream(t, ts.URL+tt.target, tt.header)
+			b.Publish(bus.Event{Type: bus.TypeReconcileStarted, Repo: "web"})
+			for _, want := range tt.want {
+				if e := s.event(); e.ID != want {
+					t.Fatalf("event = %d, want %d", e.ID, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamInvalidLastEventID(t *testing.T) {
+	ts, _ := newStreamServer(t)
+	for _, target := range []string{"/events/stream?last_event_id=abc", "/events/stream?last_event_id=-1"} {
+		resp, err := http.Get(ts.URL + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", target, resp.StatusCode)
+		}
+	}
+}
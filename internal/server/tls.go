@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const certCheckInterval = 5 * time.Second
+
+var errNoClientCAs = errors.New("client CA file contains no certificates")
+
+type certReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	logger       *slog.Logger
+
+	mu        sync.Mutex
+	config    *tls.Config
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile, clientCAFile string, logger *slog.Logger) (*certReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls requires both a certificate and a key file")
+	}
+	r := &certReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		logger:       logger,
+	}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time, 3)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (r *certReloader) load(modTimes map[string]time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errNoClientCAs
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.config = config
+	r.modTimes = modTimes
+	return nil
+}
+
+func (r *certReloader) changed(modTimes map[string]time.Time) bool {
+	for file, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *certReloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) < certCheckInterval {
+		return r.config
+	}
+	r.checkedAt = time.Now()
+
+	modTimes, err := r.stat()
+	if err != nil {
+		r.logger.Warn("failed to check tls files, keeping current certificate", slog.Any("error", err))
+		return r.config
+	}
+	if !r.changed(modTimes) {
+		return r.config
+	}
+
+	if err := r.load(modTimes); err != nil {
+		r.logger.Warn("failed to reload tls files, keeping current certificate", slog.Any("error", err))
+		return r.config
+	}
+	r.logger.Info("reloaded tls certificate", slog.String("cert", r.certFile))
+	return r.config
+}
+
+func (r *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kedge test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func startTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "kedge.sock")
+	s := newTestServer(t, append(opts, WithUnixSocket(socket))...)
+	if err := s.Start(t.Context()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+	return s
+}
+
+func socketClient(socket string, tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+func get(client *http.Client, url string) (*http.Response, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "kedge.sock")
+	writeFile(t, socket, nil)
+
+	s := newTestServer(t, WithUnixSocket(socket))
+	if err := s.Start(t.Context()); err != nil {
+		t.Fatalf("Start() over a stale socket error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+	if got := s.Addr(); got != "unix:"+socket {
+		t.Errorf("Addr() = %q, want unix:%s", got, socket)
+	}
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0o660 {
+		t.Errorf("socket mode = %v, want a 0660 socket", info.Mode())
+	}
+
+	resp, err := get(socketClient(socket, nil), "http://kedge/health")
+	if err != nil {
+		t.Fatalf("GET /health error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	certPEM, keyPEM := ca.issue(t, "kedge", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	s := startTestServer(t, WithTLS(certFile, keyFile, ""))
+	client := socketClient(s.socket, &tls.Config{RootCAs: ca.pool(), ServerName: "localhost", MinVersion: tls.VersionTLS12})
+
+	resp, err := get(client, "https://localhost/health")
+	if err != nil {
+		t.Fatalf("GET /health error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+		t.Fatalf("status = %d, tls = %v, want 200 over tls", resp.StatusCode, resp.TLS != nil)
+	}
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "kedge" {
+		t.Errorf("server certificate = %q, want kedge", cn)
+	}
+
+	if resp, err := get(socketClient(s.socket, nil), "http://localhost/health"); err == nil && resp.StatusCode == http.StatusOK {
+		t.Error("plain http request succeeded on a tls listener")
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	certPEM, keyPEM := ca.issue(t, "before", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	s := startTestServer(t, WithTLS(certFile, keyFile, ""))
+	client := socketClient(s.socket, &tls.Config{RootCAs: ca.pool(), ServerName: "localhost", MinVersion: tls.VersionTLS12})
+
+	serverName := func() string {
+		t.Helper()
+		resp, err := get(client, "https://localhost/health")
+		if err != nil {
+			t.Fatalf("GET /health error = %v", err)
+		}
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	if got := serverName(); got != "before" {
+		t.Fatalf("server certificate = %q, want before", got)
+	}
+
+	certPEM, keyPEM = ca.issue(t, "after", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := serverName(); got != "before" {
+		t.Errorf("server certificate within the check interval = %q, want before", got)
+	}
+
+	s.tls.mu.Lock()
+	s.tls.checkedAt = time.Time{}
+	s.tls.mu.Unlock()
+	if got := serverName(); got != "after" {
+		t.Errorf("server certificate after reload = %q, want after", got)
+	}
+
+	writeFile(t, keyFile, []byte("not a key"))
+	broken := later.Add(time.Minute)
+	if err := os.Chtimes(keyFile, broken, broken); err != nil {
+		t.Fatal(err)
+	}
+	s.tls.mu.Lock()
+	s.tls.checkedAt = time.Time{}
+	s.tls.mu.Unlock()
+	if got := serverName(); got != "after" {
+		t.Errorf("server certificate after a broken reload = %q, want after", got)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	certPEM, keyPEM := ca.issue(t, "kedge", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, ca.pem)
+
+	s := startTestServer(t, WithTLS(certFile, keyFile, caFile))
+
+	clientCertPEM, clientKeyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newTestCA(t)
+	otherCertPEM, otherKeyPEM := other.issue(t, "stranger", x509.ExtKeyUsageClientAuth)
+	otherCert, err := tls.X509KeyPair(otherCertPEM, otherKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		certs []tls.Certificate
+		ok    bool
+	}{
+		{"trusted client certificate", []tls.Certificate{clientCert}, true},
+		{"no client certificate", nil, false},
+		{"untrusted client certificate", []tls.Certificate{otherCert}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := socketClient(s.socket, &tls.Config{
+				RootCAs:      ca.pool(),
+				ServerName:   "localhost",
+				Certificates: tt.certs,
+				MinVersion:   tls.VersionTLS12,
+			})
+			resp, err := get(client, "https://localhost/health")
+			if tt.ok {
+				if err != nil || resp.StatusCode != http.StatusOK {
+					t.Errorf("GET /health = %v, %v, want 200", resp, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("GET /health succeeded with status %d, want a handshake failure", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestTLSStartErrors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "kedge", x509.ExtKeyUsageServerAuth)
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	emptyCA := filepath.Join(dir, "empty.crt")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, emptyCA, []byte("no certificates here"))
+
+	tests := []struct {
+		name              string
+		cert, key, client string
+	}{
+		{"key without certificate", "", keyFile, ""},
+		{"missing key file", certFile, filepath.Join(dir, "missing.key"), ""},
+		{"mismatched pair", certFile, certFile, ""},
+		{"empty client CA", certFile, keyFile, emptyCA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, WithUnixSocket(filepath.Join(t.TempDir(), "kedge.sock")), WithTLS(tt.cert, tt.key, tt.client))
+			if err := s.Start(t.Context()); err == nil {
+				_ = s.Shutdown(context.Background())
+				t.Error("Start() error = nil, want a tls error")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/samber/lo"
+)
+
+var dotenvAgeKey = regexp.MustCompile(`^sops_age__list_(\d+)__map_(enc|recipient)$`)
+
+// Variable is a key/value pair of a dotenv file.
+type Variable struct {
+	Key   string
+	Value string
+}
+
+type dotenvEntry struct {
+	Variable
+	comment bool
+}
+
+func isDotenv(data []byte) bool {
+	for line := range bytes.SplitSeq(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("sops_mac=")) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDotenv(data []byte) ([]dotenvEntry, *metadata, error) {
+	var entries []dotenvEntry
+	meta := &metadata{}
+	stanzas := map[int]*ageStanza{}
+	for line := range strings.SplitSeq(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			entries = append(entries, dotenvEntry{Variable: Variable{Value: comment}, comment: true})
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: invalid dotenv line %q", ErrMalformed, line)
+		}
+		value = strings.ReplaceAll(value, `\n`, "\n")
+
+		if m := dotenvAgeKey.FindStringSubmatch(key); m != nil {
+			i, _ := strconv.Atoi(m[1])
+			if stanzas[i] == nil {
+				stanzas[i] = &ageStanza{}
+			}
+			if m[2] == "enc" {
+				stanzas[i].enc = value
+			} else {
+				stanzas[i].recipient = value
+			}
+			continue
+		}
+		switch key {
+		case "sops_lastmodified":
+			meta.lastModified = value
+		case "sops_mac":
+			meta.mac = value
+		case "sops_mac_only_encrypted":
+			meta.macOnlyEncrypted = value == "true"
+		default:
+			if !strings.HasPrefix(key, "sops_") {
+				entries = append(entries, dotenvEntry{Variable: Variable{Key: key, Value: value}})
+			}
+		}
+	}
+
+	for _, i := range slices.Sorted(maps.Keys(stanzas)) {
+		meta.age = append(meta.age, *stanzas[i])
+	}
+	return entries, meta, nil
+}
+
+func decryptDotenv(data []byte, identities []age.Identity) ([]dotenvEntry, error) {
+	entries, meta, err := parseDotenv(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := meta.dataKey(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha512.New()
+	for i, e := range entries {
+		if !isEncrypted(e.Value) {
+			if !meta.macOnlyEncrypted {
+				hashValue(h, e.Value)
+			}
+			continue
+		}
+		v, _, err := decryptValue(e.Value, key, e.Key+":")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", lo.Ternary(e.comment, "comment", e.Key), err)
+		}
+		hashValue(h, v)
+		entries[i].Value = fmt.Sprint(v)
+	}
+	if err := meta.verify(key, h); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DecryptDotenv returns the variables of a SOPS-encrypted dotenv file in
+// file order.
+func DecryptDotenv(data []byte, identities []age.Identity) ([]Variable, error) {
+	entries, err := decryptDotenv(data, identities)
+	if err != nil {
+		return nil, err
+	}
+	var vars []Variable
+	for _, e := range entries {
+		if !e.comment {
+			vars = append(vars, e.Variable)
+		}
+	}
+	return vars, nil
+}
+
+func formatDotenv(entries []dotenvEntry) []byte {
+	var b strings.Builder
+	for _, e := range entries {
+		if e.comment {
+			b.WriteString("#" + e.Value + "\n")
+			continue
+		}
+		b.WriteString(e.Key + "=" + strings.ReplaceAll(e.Value, "\n", `\n`) + "\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,51 @@
+package sops
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+)
+
+var ErrNoKeys = errors.New("no age keys configured")
+
+// LoadIdentities reads age identities the way the sops CLI does: from
+// SOPS_AGE_KEY, then SOPS_AGE_KEY_FILE or the default keys.txt.
+func LoadIdentities() ([]age.Identity, error) {
+	var identities []age.Identity
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		parsed, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("parse SOPS_AGE_KEY: %w", err)
+		}
+		identities = append(identities, parsed...)
+	}
+
+	path := os.Getenv("SOPS_AGE_KEY_FILE")
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return identities, nil
+		}
+		path = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // key file path is operator-provided
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if len(identities) == 0 {
+			return nil, fmt.Errorf("%w: set SOPS_AGE_KEY_FILE or create %s", ErrNoKeys, path)
+		}
+		return identities, nil
+	case err != nil:
+		return nil, fmt.Errorf("read age key file: %w", err)
+	}
+	parsed, err := age.ParseIdentities(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return append(identities, parsed...), nil
+}
@@ -0,0 +1,245 @@
+// Package sops decrypts files encrypted with SOPS using age recipients.
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/samber/lo"
+)
+
+type Format int
+
+const (
+	FormatNone Format = iota
+	FormatDotenv
+	FormatYAML
+	FormatJSON
+	FormatBinary
+)
+
+var (
+	ErrNoIdentity  = errors.New("no age identity can decrypt the data key")
+	ErrNoRecipient = errors.New("file has no age recipients")
+	ErrMACMismatch = errors.New("MAC mismatch: the file was changed after it was encrypted")
+	ErrMalformed   = errors.New("malformed SOPS file")
+)
+
+var encValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:(.*)\]$`)
+
+type metadata struct {
+	age              []ageStanza
+	lastModified     string
+	mac              string
+	macOnlyEncrypted bool
+}
+
+type ageStanza struct {
+	recipient string
+	enc       string
+}
+
+// Detect reports the format of a SOPS-encrypted file, or FormatNone if the
+// data is not SOPS-encrypted.
+func Detect(path string, data []byte) Format {
+	if isDotenv(data) {
+		return FormatDotenv
+	}
+	doc, err := parseTree(data)
+	if err != nil {
+		return FormatNone
+	}
+	if _, ok := mappingValue(doc, "sops"); !ok {
+		return FormatNone
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	}
+	if _, ok := mappingValue(doc, "data"); ok && len(doc.Content[0].Content) == 4 {
+		return FormatBinary
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// Decrypt returns the plaintext of a SOPS-encrypted file in the same format
+// `sops --decrypt` prints it.
+func Decrypt(path string, data []byte, identities []age.Identity) ([]byte, error) {
+	switch Detect(path, data) {
+	case FormatDotenv:
+		entries, err := decryptDotenv(data, identities)
+		if err != nil {
+			return nil, err
+		}
+		return formatDotenv(entries), nil
+	case FormatYAML:
+		return decryptTree(data, identities, false)
+	case FormatJSON:
+		return decryptTree(data, identities, true)
+	case FormatBinary:
+		return decryptBinary(data, identities)
+	default:
+		return nil, fmt.Errorf("%w: no sops metadata", ErrMalformed)
+	}
+}
+
+func (m *metadata) dataKey(identities []age.Identity) ([]byte, error) {
+	if len(m.age) == 0 {
+		return nil, ErrNoRecipient
+	}
+	var lastErr error
+	for _, stanza := range m.age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(stanza.enc)), identities...)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		key, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%w: data key is %d bytes", ErrMalformed, len(key))
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %w", ErrNoIdentity, lastErr)
+}
+
+// verify compares the hash of the walked values with the encrypted MAC.
+func (m *metadata) verify(key []byte, h hash.Hash) error {
+	lastModified := m.lastModified
+	if t, err := time.Parse(time.RFC3339, lastModified); err == nil {
+		lastModified = t.Format(time.RFC3339)
+	}
+	mac, _, err := decryptValue(m.mac, key, lastModified)
+	if err != nil {
+		return fmt.Errorf("decrypt MAC: %w", err)
+	}
+	if !strings.EqualFold(fmt.Sprint(mac), hex.EncodeToString(h.Sum(nil))) {
+		return ErrMACMismatch
+	}
+	return nil
+}
+
+func isEncrypted(value string) bool {
+	return encValue.MatchString(value)
+}
+
+// decryptValue decrypts a single ENC[...] value, returning it typed along
+// with its SOPS type name.
+func decryptValue(value string, key []byte, aad string) (any, string, error) {
+	match := encValue.FindStringSubmatch(value)
+	if match == nil {
+		return nil, "", fmt.Errorf("%w: value is not encrypted", ErrMalformed)
+	}
+	data, err1 := base64.StdEncoding.DecodeString(match[1])
+	iv, err2 := base64.StdEncoding.DecodeString(match[2])
+	tag, err3 := base64.StdEncoding.DecodeString(match[3])
+	if err := errors.Join(err1, err2, err3); err != nil || len(iv) == 0 {
+		return nil, "", fmt.Errorf("%w: invalid encrypted value", ErrMalformed)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(aad))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: could not decrypt value", ErrMalformed)
+	}
+
+	typ := match[4]
+	text := string(plaintext)
+	switch typ {
+	case "str", "comment":
+		return text, typ, nil
+	case "bytes":
+		return plaintext, typ, nil
+	case "int":
+		n, err := strconv.Atoi(text)
+		return n, typ, err
+	case "float":
+		f, err := strconv.ParseFloat(text, 64)
+		return f, typ, err
+	case "bool":
+		b, err := strconv.ParseBool(text)
+		return b, typ, err
+	default:
+		return nil, "", fmt.Errorf("%w: unknown value type %q", ErrMalformed, typ)
+	}
+}
+
+// hashValue feeds a value to the MAC the way SOPS serializes it.
+func hashValue(h hash.Hash, value any) {
+	switch v := value.(type) {
+	case string:
+		h.Write([]byte(v))
+	case []byte:
+		h.Write(v)
+	case int:
+		h.Write([]byte(strconv.Itoa(v)))
+	case float64:
+		h.Write([]byte(strconv.FormatFloat(v, 'f', -1, 64)))
+	case bool:
+		h.Write([]byte(lo.Ternary(v, "True", "False")))
+	}
+}
+
+func decryptBinary(data []byte, identities []age.Identity) ([]byte, error) {
+	root, err := parseTree(data)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, ok := mappingValue(root, "data")
+	if !ok {
+		return nil, fmt.Errorf("%w: no data", ErrMalformed)
+	}
+	meta, err := treeMetadata(root)
+	if err != nil {
+		return nil, err
+	}
+	key, err := meta.dataKey(identities)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := decryptValue(ciphertext.Value, key, "data:")
+	if err != nil {
+		return nil, err
+	}
+	h := sha512.New()
+	hashValue(h, value)
+	if err := meta.verify(key, h); err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	default:
+		return []byte(fmt.Sprint(v)), nil
+	}
+}
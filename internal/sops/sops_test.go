@@ -0,0 +1,249 @@
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+const testLastModified = "2026-01-02T03:04:05Z"
+
+// encrypter produces files the way `sops --encrypt --age` does.
+type encrypter struct {
+	t        *testing.T
+	identity *age.X25519Identity
+	key      []byte
+	enc      string
+	values   []string
+}
+
+func newEncrypter(t *testing.T) *encrypter {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &encrypter{t: t, identity: identity, key: key, enc: buf.String()}
+}
+
+func (e *encrypter) seal(plaintext, typ, aad string) string {
+	e.t.Helper()
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	iv := make([]byte, 32)
+	if _, err := rand.Read(iv); err != nil {
+		e.t.Fatal(err)
+	}
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), []byte(aad))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(iv), base64.StdEncoding.EncodeToString(tag), typ)
+}
+
+// value encrypts a value and records it for the MAC.
+func (e *encrypter) value(plaintext, typ, aad string) string {
+	e.values = append(e.values, plaintext)
+	return e.seal(plaintext, typ, aad)
+}
+
+func (e *encrypter) plain(plaintext string) string {
+	e.values = append(e.values, plaintext)
+	return plaintext
+}
+
+func (e *encrypter) mac() string {
+	h := sha512.New()
+	for _, v := range e.values {
+		h.Write([]byte(v))
+	}
+	return e.seal(strings.ToUpper(hex.EncodeToString(h.Sum(nil))), "str", testLastModified)
+}
+
+func (e *encrypter) yamlMetadata() string {
+	meta := map[string]any{
+		"sops": map[string]any{
+			"age":          []map[string]string{{"recipient": e.identity.Recipient().String(), "enc": e.enc}},
+			"lastmodified": testLastModified,
+			"mac":          e.mac(),
+			"version":      "3.9.4",
+		},
+	}
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return string(data)
+}
+
+func (e *encrypter) dotenv(lines ...string) []byte {
+	lines = append(lines,
+		"sops_age__list_0__map_enc="+strings.ReplaceAll(e.enc, "\n", `\n`),
+		"sops_age__list_0__map_recipient="+e.identity.Recipient().String(),
+		"sops_lastmodified="+testLastModified,
+		"sops_mac="+e.mac(),
+		"sops_version=3.9.4",
+	)
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func TestDecryptDotenv(t *testing.T) {
+	e := newEncrypter(t)
+	data := e.dotenv(
+		"#"+e.value(" database settings", "comment", ":"),
+		"DB_PASSWORD="+e.value("s3cr=t", "str", "DB_PASSWORD:"),
+		"CERT="+strings.ReplaceAll(e.value("line1\nline2", "str", "CERT:"), "\n", `\n`),
+	)
+
+	if got := Detect(".env", data); got != FormatDotenv {
+		t.Fatalf("Detect() = %v, want FormatDotenv", got)
+	}
+	vars, err := DecryptDotenv(data, []age.Identity{e.identity})
+	if err != nil {
+		t.Fatalf("DecryptDotenv() error = %v", err)
+	}
+	want := []Variable{{"DB_PASSWORD", "s3cr=t"}, {"CERT", "line1\nline2"}}
+	if fmt.Sprint(vars) != fmt.Sprint(want) {
+		t.Errorf("DecryptDotenv() = %q, want %q", vars, want)
+	}
+
+	plaintext, err := Decrypt(".env", data, []age.Identity{e.identity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# database settings\nDB_PASSWORD=s3cr=t\nCERT=line1\\nline2\n"; string(plaintext) != want {
+		t.Errorf("Decrypt() = %q, want %q", plaintext, want)
+	}
+}
+
+func TestDecryptYAML(t *testing.T) {
+	e := newEncrypter(t)
+	var b strings.Builder
+	b.WriteString("db:\n")
+	b.WriteString("    password: " + e.value("hunter2", "str", "db:password:") + "\n")
+	b.WriteString("    port: " + e.value("5432", "int", "db:port:") + "\n")
+	b.WriteString("    tls: " + e.value("True", "bool", "db:tls:") + "\n")
+	b.WriteString("hosts:\n")
+	b.WriteString("    - " + e.value("a.example.com", "str", "hosts:") + "\n")
+	b.WriteString("    - " + e.value("b.example.com", "str", "hosts:") + "\n")
+	data := []byte(b.String() + e.yamlMetadata())
+
+	if got := Detect("secrets.yaml", data); got != FormatYAML {
+		t.Fatalf("Detect() = %v, want FormatYAML", got)
+	}
+	plaintext, err := Decrypt("secrets.yaml", data, []age.Identity{e.identity})
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	var got struct {
+		DB struct {
+			Password string `yaml:"password"`
+			Port     int    `yaml:"port"`
+			TLS      bool   `yaml:"tls"`
+		} `yaml:"db"`
+		Hosts []string       `yaml:"hosts"`
+		Sops  map[string]any `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(plaintext, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.DB.Password != "hunter2" || got.DB.Port != 5432 || !got.DB.TLS || len(got.Hosts) != 2 || got.Hosts[1] != "b.example.com" || got.Sops != nil {
+		t.Errorf("Decrypt() = %s", plaintext)
+	}
+}
+
+func (e *encrypter) jsonMetadata() string {
+	return fmt.Sprintf(`{"age": [{"recipient": %q, "enc": %q}], "lastmodified": %q, "mac": %q, "version": "3.9.4"}`,
+		e.identity.Recipient().String(), e.enc, testLastModified, e.mac())
+}
+
+func TestDecryptJSON(t *testing.T) {
+	e := newEncrypter(t)
+	token := e.value("abc", "str", "token:")
+	replicas := e.plain("3")
+	data := []byte(fmt.Sprintf(`{"token": %q, "replicas": %s, "sops": %s}`, token, replicas, e.jsonMetadata()))
+
+	plaintext, err := Decrypt("secrets.json", data, []age.Identity{e.identity})
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if want := "{\n\t\"token\": \"abc\",\n\t\"replicas\": 3\n}\n"; string(plaintext) != want {
+		t.Errorf("Decrypt() = %q, want %q", plaintext, want)
+	}
+}
+
+func TestDecryptBinary(t *testing.T) {
+	e := newEncrypter(t)
+	data := []byte(fmt.Sprintf(`{"data": %q, "sops": %s}`, e.value("KEY=value\n", "str", "data:"), e.jsonMetadata()))
+
+	if got := Detect(".env.production", data); got != FormatBinary {
+		t.Fatalf("Detect() = %v, want FormatBinary", got)
+	}
+	plaintext, err := Decrypt(".env.production", data, []age.Identity{e.identity})
+	if err != nil || string(plaintext) != "KEY=value\n" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	e := newEncrypter(t)
+	data := e.dotenv("A="+e.value("1", "str", "A:"), "B="+e.plain("2"))
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptDotenv(data, []age.Identity{other}); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("DecryptDotenv(wrong identity) error = %v, want ErrNoIdentity", err)
+	}
+
+	tampered := []byte(strings.Replace(string(data), "B=2", "B=3", 1))
+	if _, err := DecryptDotenv(tampered, []age.Identity{e.identity}); !errors.Is(err, ErrMACMismatch) {
+		t.Errorf("DecryptDotenv(tampered) error = %v, want ErrMACMismatch", err)
+	}
+
+	for name, plain := range map[string]string{
+		".env":         "A=1\nB=2\n",
+		"compose.yaml": "services:\n  web:\n    image: nginx\n",
+		"secret.txt":   "hunter2",
+	} {
+		if got := Detect(name, []byte(plain)); got != FormatNone {
+			t.Errorf("Detect(%s) = %v, want FormatNone", name, got)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+)
+
+func parseTree(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w: not a mapping", ErrMalformed)
+	}
+	return &doc, nil
+}
+
+func mappingValue(doc *yaml.Node, key string) (*yaml.Node, bool) {
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			return root.Content[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func treeMetadata(doc *yaml.Node) (*metadata, error) {
+	node, ok := mappingValue(doc, "sops")
+	if !ok {
+		return nil, fmt.Errorf("%w: no sops metadata", ErrMalformed)
+	}
+	var raw struct {
+		Age []struct {
+			Recipient string `yaml:"recipient"`
+			Enc       string `yaml:"enc"`
+		} `yaml:"age"`
+		LastModified     string `yaml:"lastmodified"`
+		MAC              string `yaml:"mac"`
+		MACOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	m := &metadata{lastModified: raw.LastModified, mac: raw.MAC, macOnlyEncrypted: raw.MACOnlyEncrypted}
+	for _, a := range raw.Age {
+		m.age = append(m.age, ageStanza{recipient: a.Recipient, enc: a.Enc})
+	}
+	return m, nil
+}
+
+func decryptTree(data []byte, identities []age.Identity, asJSON bool) ([]byte, error) {
+	doc, err := parseTree(data)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := treeMetadata(doc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := meta.dataKey(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sops" {
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			break
+		}
+	}
+
+	w := &walker{key: key, h: sha512.New(), macOnly: meta.macOnlyEncrypted}
+	if doc.HeadComment, err = w.comments(doc.HeadComment, nil); err != nil {
+		return nil, err
+	}
+	if err := w.value(root, nil); err != nil {
+		return nil, err
+	}
+	if err := meta.verify(key, w.h); err != nil {
+		return nil, err
+	}
+
+	if asJSON {
+		compact, err := appendJSON(nil, root)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, compact, "", "\t"); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	}
+	return yaml.Marshal(doc)
+}
+
+// walker decrypts a tree in place in the order SOPS computes the MAC.
+type walker struct {
+	key     []byte
+	h       hash.Hash
+	macOnly bool
+}
+
+func additionalData(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+func (w *walker) value(n *yaml.Node, path []string) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			var err error
+			if k.HeadComment, err = w.comments(k.HeadComment, path); err != nil {
+				return err
+			}
+			if err := w.value(v, append(path[:len(path):len(path)], k.Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			var err error
+			if item.HeadComment, err = w.comments(item.HeadComment, path); err != nil {
+				return err
+			}
+			if err := w.value(item, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return w.scalar(n, path)
+	}
+	return nil
+}
+
+func (w *walker) scalar(n *yaml.Node, path []string) error {
+	if n.Tag != "!!str" || !isEncrypted(n.Value) {
+		if w.macOnly || n.Tag == "!!null" {
+			return nil
+		}
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		hashValue(w.h, v)
+		return nil
+	}
+
+	v, typ, err := decryptValue(n.Value, w.key, additionalData(path))
+	if err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	}
+	hashValue(w.h, v)
+	n.Style = 0
+	switch typ {
+	case "int":
+		n.Tag, n.Value = "!!int", strconv.Itoa(v.(int))
+	case "float":
+		n.Tag, n.Value = "!!float", strconv.FormatFloat(v.(float64), 'f', -1, 64)
+	case "bool":
+		n.Tag, n.Value = "!!bool", strconv.FormatBool(v.(bool))
+	case "bytes":
+		n.Tag, n.Value = "!!str", string(v.([]byte))
+	default:
+		n.Tag, n.Value = "!!str", v.(string)
+	}
+	return nil
+}
+
+// comments decrypts the "#"-prefixed lines of a yaml comment block.
+func (w *walker) comments(text string, path []string) (string, error) {
+	if text == "" {
+		return text, nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		comment, ok := strings.CutPrefix(line, "#")
+		if !ok {
+			continue
+		}
+		if !isEncrypted(comment) {
+			if !w.macOnly {
+				hashValue(w.h, comment)
+			}
+			continue
+		}
+		v, _, err := decryptValue(comment, w.key, additionalData(path))
+		if err != nil {
+			return "", err
+		}
+		hashValue(w.h, v)
+		lines[i] = "#" + fmt.Sprint(v)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func appendJSON(b []byte, n *yaml.Node) ([]byte, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		b = append(b, '{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			b = append(append(b, key...), ':')
+			if b, err = appendJSON(b, n.Content[i+1]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	case yaml.SequenceNode:
+		b = append(b, '[')
+		for i, item := range n.Content {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendJSON(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case yaml.AliasNode:
+		return appendJSON(b, n.Alias)
+	default:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, data...), nil
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidEnvKey = errors.New("invalid variable name")
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EnvVar is a variable interpolated into a repository's compose files.
+// Secret values are stored encrypted; Value then holds the ciphertext.
+type EnvVar struct {
+	RepoName  string
+	Key       string
+	Value     string
+	Secret    bool
+	UpdatedAt time.Time
+}
+
+func ValidEnvKey(key string) bool {
+	return envKeyPattern.MatchString(key)
+}
+
+func (s *Store) SetEnv(ctx context.Context, repoName, key, value string, secret bool) error {
+	if !ValidEnvKey(key) {
+		return ErrInvalidEnvKey
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO repo_env (repo_name, key, value, secret, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (repo_name, key) DO UPDATE SET value = excluded.value, secret = excluded.secret, updated_at = excluded.updated_at`,
+		repoName, key, value, secret, sqliteTime(time.Now()),
+	)
+	return err
+}
+
+func (s *Store) UnsetEnv(ctx context.Context, repoName, key string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM repo_env WHERE repo_name = ? AND key = ?`, repoName, key)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) ListEnv(ctx context.Context, repoName string) ([]*EnvVar, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT repo_name, key, value, secret, updated_at FROM repo_env WHERE repo_name = ? ORDER BY key`, repoName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vars []*EnvVar
+	for rows.Next() {
+		var v EnvVar
+		if err := rows.Scan(&v.RepoName, &v.Key, &v.Value, &v.Secret, &v.UpdatedAt); err != nil {
+			return nil, err
+		}
+		vars = append(vars, &v)
+	}
+	return vars, rows.Err()
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetEnv(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	if err := store.SetEnv(ctx, testRepoName, "TAG", "1.0", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetEnv(ctx, testRepoName, "DB_PASSWORD", "v1:abcd:sealed", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetEnv(ctx, testRepoName, "TAG", "1.1", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetEnv(ctx, testRepoName, "1BAD", "x", false); !errors.Is(err, ErrInvalidEnvKey) {
+		t.Errorf("SetEnv(1BAD) error = %v, want ErrInvalidEnvKey", err)
+	}
+
+	vars, err := store.ListEnv(ctx, testRepoName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 2 || vars[0].Key != "DB_PASSWORD" || !vars[0].Secret || vars[1].Value != "1.1" || vars[1].Secret {
+		t.Errorf("ListEnv() = %+v", vars)
+	}
+
+	if err := store.UnsetEnv(ctx, testRepoName, "TAG"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UnsetEnv(ctx, testRepoName, "TAG"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UnsetEnv() error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.DeleteRepo(ctx, testRepoName); err != nil {
+		t.Fatal(err)
+	}
+	if vars, err := store.ListEnv(ctx, testRepoName); err != nil || len(vars) != 0 {
+		t.Errorf("ListEnv() after DeleteRepo = %v, %v", vars, err)
+	}
+}
@@ -0,0 +1,218 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	z "github.com/Oudwins/zog"
+)
+
+var ErrInvalidEventType = errors.New("invalid event type")
+
+type EventType string
+
+const (
+	EventCommitDetected     EventType = "commit_detected"
+	EventDriftDetected      EventType = "drift_detected"
+	EventDriftRemediated    EventType = "drift_remediated"
+	EventSync               EventType = "sync"
+	EventRollback           EventType = "rollback"
+	EventRepoAdded          EventType = "repo_added"
+	EventRepoRemoved        EventType = "repo_removed"
+	EventDeploymentApproved EventType = "deployment_approved"
+	EventDeploymentRejected EventType = "deployment_rejected"
+	EventTokenCreated       EventType = "token_created"
+	EventTokenRevoked       EventType = "token_revoked"
+	EventConfigReloaded     EventType = "config_reloaded"
+	EventConfigRejected     EventType = "config_rejected"
+	EventSecretsKeyRotated  EventType = "secrets_key_rotated"
+	EventEnvSet             EventType = "env_set"
+	EventEnvUnset           EventType = "env_unset"
+	EventPreviewCreated     EventType = "preview_created"
+	EventPreviewRemoved     EventType = "preview_removed"
+)
+
+var eventTypeSchema = z.String().OneOf([]string{
+	string(EventCommitDetected),
+	string(EventDriftDetected),
+	string(EventDriftRemediated),
+	string(EventSync),
+	string(EventRollback),
+	string(EventRepoAdded),
+	string(EventRepoRemoved),
+	string(EventDeploymentApproved),
+	string(EventDeploymentRejected),
+	string(EventTokenCreated),
+	string(EventTokenRevoked),
+	string(EventConfigReloaded),
+	string(EventConfigRejected),
+	string(EventSecretsKeyRotated),
+	string(EventEnvSet),
+	string(EventEnvUnset),
+	string(EventPreviewCreated),
+	string(EventPreviewRemoved),
+})
+
+func (t EventType) IsValid() bool {
+	str := string(t)
+	return eventTypeSchema.Validate(&str) == nil
+}
+
+type Event struct {
+	ID        int64
+	Type      EventType
+	RepoName  string
+	App       string
+	Service   string
+	Commit    string
+	Actor     string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+type EventFilter struct {
+	RepoName string
+	App      string
+	Types    []string
+	Since    time.Time
+	AfterID  int64
+	Limit    int
+}
+
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q: expected a duration like 1h or an RFC3339 timestamp", s)
+	}
+	return t, nil
+}
+
+const eventColumns = `id, type, repo_name, app, service, commit_hash, actor, payload, created_at`
+
+func (s *Store) RecordEvent(ctx context.Context, e *Event) (*Event, error) {
+	if !e.Type.IsValid() {
+		return nil, ErrInvalidEventType
+	}
+
+	var payload any
+	if len(e.Payload) > 0 {
+		payload = string(e.Payload)
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO events (type, repo_name, app, service, commit_hash, actor, payload) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.Type, nullString(e.RepoName), nullString(e.App), nullString(e.Service), nullString(e.Commit), nullString(e.Actor), payload,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.QueryRowContext(ctx, `SELECT `+eventColumns+` FROM events WHERE id = ?`, id)
+	return scanEvent(row)
+}
+
+func (s *Store) ListEvents(ctx context.Context, f EventFilter) ([]*Event, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	var where []string
+	var args []any
+	if f.RepoName != "" {
+		where = append(where, "repo_name = ?")
+		args = append(args, f.RepoName)
+	}
+	if f.App != "" {
+		where = append(where, "app = ?")
+		args = append(args, f.App)
+	}
+	if len(f.Types) > 0 {
+		var typeConds []string
+		for _, t := range f.Types {
+			typeConds = append(typeConds, `type = ? OR type LIKE ? ESCAPE '\'`)
+			args = append(args, t, t+"\\_%")
+		}
+		where = append(where, "("+strings.Join(typeConds, " OR ")+")")
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, sqliteTime(f.Since))
+	}
+	if f.AfterID > 0 {
+		where = append(where, "id > ?")
+		args = append(args, f.AfterID)
+	}
+
+	query := `SELECT ` + eventColumns + ` FROM events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *Store) PruneEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM events WHERE created_at < ?`, sqliteTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(time.DateTime)
+}
+
+func scanEvent(row scanner) (*Event, error) {
+	var e Event
+	var repoName, app, service, commit, actor, payload sql.NullString
+	err := row.Scan(&e.ID, &e.Type, &repoName, &app, &service, &commit, &actor, &payload, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.RepoName = repoName.String
+	e.App = app.String
+	e.Service = service.String
+	e.Commit = commit.String
+	e.Actor = actor.String
+	if payload.Valid {
+		e.Payload = json.RawMessage(payload.String)
+	}
+	return &e, nil
+}
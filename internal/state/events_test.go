@@ -0,0 +1,140 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecordEvent(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	e, err := store.RecordEvent(ctx, &Event{
+		Type:     EventDriftDetected,
+		RepoName: testRepoName,
+		Service:  "web",
+		Actor:    "controller",
+		Payload:  json.RawMessage(`{"action":"update"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.ID == 0 {
+		t.Error("expected non-zero ID")
+	}
+	if e.Service != "web" {
+		t.Errorf("service: got %q, want %q", e.Service, "web")
+	}
+	if string(e.Payload) != `{"action":"update"}` {
+		t.Errorf("payload: got %s", e.Payload)
+	}
+	if e.CreatedAt.IsZero() {
+		t.Error("expected created at to be set")
+	}
+}
+
+func TestRecordEventInvalidType(t *testing.T) {
+	store := newTestStore(t)
+
+	_, err := store.RecordEvent(t.Context(), &Event{Type: "exploded"})
+	if err != ErrInvalidEventType {
+		t.Errorf("error: got %v, want ErrInvalidEventType", err)
+	}
+}
+
+func TestListEventsFilters(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	events := []*Event{
+		{Type: EventDriftDetected, RepoName: testRepoName, Service: "web"},
+		{Type: EventDriftRemediated, RepoName: testRepoName, Service: "web"},
+		{Type: EventCommitDetected, RepoName: testRepoName, Commit: "abc123"},
+		{Type: EventRepoAdded, RepoName: "other-repo", Actor: "alice"},
+	}
+	for _, e := range events {
+		if _, err := store.RecordEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   int
+	}{
+		{"all", EventFilter{}, 4},
+		{"by repo", EventFilter{RepoName: testRepoName}, 3},
+		{"by type prefix", EventFilter{Types: []string{"drift"}}, 2},
+		{"by exact type", EventFilter{Types: []string{string(EventDriftRemediated)}}, 1},
+		{"since past", EventFilter{Since: time.Now().Add(-time.Hour)}, 4},
+		{"since future", EventFilter{Since: time.Now().Add(time.Hour)}, 0},
+		{"limit", EventFilter{Limit: 2}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.ListEvents(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("count: got %d, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneEvents(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	if _, err := store.RecordEvent(ctx, &Event{Type: EventSync, RepoName: testRepoName}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := store.PruneEvents(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 {
+		t.Errorf("removed: got %d, want 0", removed)
+	}
+
+	removed, err = store.PruneEvents(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed: got %d, want 1", removed)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"1h", now.Add(-time.Hour), false},
+		{"2024-01-14T00:00:00Z", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSince(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
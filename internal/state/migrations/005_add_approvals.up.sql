@@ -0,0 +1,5 @@
+ALTER TABLE deployments ADD COLUMN diff TEXT DEFAULT NULL;
+ALTER TABLE deployments ADD COLUMN reviewed_by TEXT DEFAULT NULL;
+ALTER TABLE deployments ADD COLUMN reviewed_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_deployments_repo_status ON deployments(repo_name, status);
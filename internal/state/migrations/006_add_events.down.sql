@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_events_created;
+DROP INDEX IF EXISTS idx_events_type;
+DROP INDEX IF EXISTS idx_events_repo_created;
+DROP TABLE IF EXISTS events;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_deployments_repo_app;
+
+ALTER TABLE events DROP COLUMN app;
+ALTER TABLE deployments DROP COLUMN app;
@@ -0,0 +1,4 @@
+ALTER TABLE deployments ADD COLUMN app TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN app TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_deployments_repo_app ON deployments(repo_name, app);
@@ -0,0 +1,2 @@
+ALTER TABLE repos DROP COLUMN tag_constraint;
+ALTER TABLE repos DROP COLUMN tag_pattern;
@@ -0,0 +1,2 @@
+ALTER TABLE repos ADD COLUMN tag_pattern TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN tag_constraint TEXT DEFAULT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE repos DROP COLUMN trust_signed_tags;
+ALTER TABLE repos DROP COLUMN trust_keys_path;
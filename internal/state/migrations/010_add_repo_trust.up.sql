@@ -0,0 +1,2 @@
+ALTER TABLE repos ADD COLUMN trust_keys_path TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN trust_signed_tags INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE repos DROP COLUMN checkout_sparse_paths;
+ALTER TABLE repos DROP COLUMN checkout_depth;
+ALTER TABLE repos DROP COLUMN checkout_submodules;
@@ -0,0 +1,3 @@
+ALTER TABLE repos ADD COLUMN checkout_submodules INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE repos ADD COLUMN checkout_depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE repos ADD COLUMN checkout_sparse_paths TEXT DEFAULT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE repos DROP COLUMN auth_host_keys;
+ALTER TABLE repos DROP COLUMN auth_known_hosts_path;
+ALTER TABLE repos DROP COLUMN auth_passphrase_file;
+ALTER TABLE repos DROP COLUMN auth_passphrase_env;
@@ -0,0 +1,4 @@
+ALTER TABLE repos ADD COLUMN auth_passphrase_env TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN auth_passphrase_file TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN auth_known_hosts_path TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN auth_host_keys TEXT DEFAULT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE repos DROP COLUMN auth_credential_helper;
+ALTER TABLE repos DROP COLUMN auth_github_api_url;
+ALTER TABLE repos DROP COLUMN auth_app_key_path;
+ALTER TABLE repos DROP COLUMN auth_installation_id;
+ALTER TABLE repos DROP COLUMN auth_app_id;
+ALTER TABLE repos DROP COLUMN auth_token_file;
@@ -0,0 +1,6 @@
+ALTER TABLE repos ADD COLUMN auth_token_file TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN auth_app_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE repos ADD COLUMN auth_installation_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE repos ADD COLUMN auth_app_key_path TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN auth_github_api_url TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN auth_credential_helper TEXT DEFAULT NULL;
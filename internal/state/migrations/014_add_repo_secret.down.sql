@@ -0,0 +1 @@
+ALTER TABLE repos DROP COLUMN auth_secret;
@@ -0,0 +1 @@
+ALTER TABLE repos ADD COLUMN auth_secret TEXT DEFAULT NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS repo_env (
+    repo_name TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    secret INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (repo_name, key),
+    FOREIGN KEY (repo_name) REFERENCES repos(name) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS previews;
+
+ALTER TABLE repos DROP COLUMN preview_port_offset;
+ALTER TABLE repos DROP COLUMN preview_ttl;
+ALTER TABLE repos DROP COLUMN preview_pattern;
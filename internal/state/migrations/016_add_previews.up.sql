@@ -0,0 +1,16 @@
+ALTER TABLE repos ADD COLUMN preview_pattern TEXT DEFAULT NULL;
+ALTER TABLE repos ADD COLUMN preview_ttl INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE repos ADD COLUMN preview_port_offset INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS previews (
+    repo_name TEXT NOT NULL,
+    branch TEXT NOT NULL,
+    slot INTEGER NOT NULL,
+    commit_hash TEXT NOT NULL,
+    project_name TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP,
+    PRIMARY KEY (repo_name, branch),
+    FOREIGN KEY (repo_name) REFERENCES repos(name) ON DELETE CASCADE
+);
@@ -0,0 +1,167 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Preview is a branch deployed as its own compose project. Slot numbers are
+// unique per repository and scale the preview's port offset. UpdatedAt is
+// the last time the branch moved; RetiredAt is set once a stale preview has
+// been torn down and stays set until the branch moves again.
+type Preview struct {
+	RepoName    string
+	Branch      string
+	Slot        int
+	Commit      string
+	ProjectName string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	RetiredAt   time.Time
+}
+
+func (p *Preview) Retired() bool {
+	return !p.RetiredAt.IsZero()
+}
+
+const previewColumns = `repo_name, branch, slot, commit_hash, project_name, created_at, updated_at, retired_at`
+
+// SavePreview records the head commit of a preview branch. A new branch gets
+// the lowest free slot; a branch whose commit changed is marked updated and,
+// if it was retired, active again.
+func (s *Store) SavePreview(ctx context.Context, repoName, branch, commit string) (*Preview, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := sqliteTime(time.Now())
+	existing, err := scanPreview(tx.QueryRowContext(ctx, `SELECT `+previewColumns+` FROM previews WHERE repo_name = ? AND branch = ?`, repoName, branch))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		slot, err := freeSlot(ctx, tx, repoName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO previews (repo_name, branch, slot, commit_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			repoName, branch, slot, commit, now, now,
+		); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case existing.Commit != commit:
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE previews SET commit_hash = ?, updated_at = ?, retired_at = NULL WHERE repo_name = ? AND branch = ?`,
+			commit, now, repoName, branch,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := scanPreview(tx.QueryRowContext(ctx, `SELECT `+previewColumns+` FROM previews WHERE repo_name = ? AND branch = ?`, repoName, branch))
+	if err != nil {
+		return nil, err
+	}
+	return p, tx.Commit()
+}
+
+func freeSlot(ctx context.Context, tx *sql.Tx, repoName string) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT slot FROM previews WHERE repo_name = ?`, repoName)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	used := map[int]bool{}
+	for rows.Next() {
+		var slot int
+		if err := rows.Scan(&slot); err != nil {
+			return 0, err
+		}
+		used[slot] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	slot := 1
+	for used[slot] {
+		slot++
+	}
+	return slot, nil
+}
+
+func (s *Store) SetPreviewProject(ctx context.Context, repoName, branch, projectName string) error {
+	return s.updatePreview(ctx, `UPDATE previews SET project_name = ? WHERE repo_name = ? AND branch = ?`, projectName, repoName, branch)
+}
+
+func (s *Store) RetirePreview(ctx context.Context, repoName, branch string) error {
+	return s.updatePreview(ctx, `UPDATE previews SET retired_at = ? WHERE repo_name = ? AND branch = ?`, sqliteTime(time.Now()), repoName, branch)
+}
+
+func (s *Store) DeletePreview(ctx context.Context, repoName, branch string) error {
+	return s.updatePreview(ctx, `DELETE FROM previews WHERE repo_name = ? AND branch = ?`, repoName, branch)
+}
+
+func (s *Store) updatePreview(ctx context.Context, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) GetPreview(ctx context.Context, repoName, branch string) (*Preview, error) {
+	return scanPreview(s.db.QueryRowContext(ctx, `SELECT `+previewColumns+` FROM previews WHERE repo_name = ? AND branch = ?`, repoName, branch))
+}
+
+func (s *Store) ListPreviews(ctx context.Context, repoName string) ([]*Preview, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+previewColumns+` FROM previews WHERE repo_name = ? ORDER BY slot`, repoName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var previews []*Preview
+	for rows.Next() {
+		p, err := scanPreview(rows)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, p)
+	}
+	return previews, rows.Err()
+}
+
+func scanPreview(row scanner) (*Preview, error) {
+	var p Preview
+	var projectName sql.NullString
+	var retiredAt sql.NullTime
+	err := row.Scan(&p.RepoName, &p.Branch, &p.Slot, &p.Commit, &projectName, &p.CreatedAt, &p.UpdatedAt, &retiredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.ProjectName = projectName.String
+	p.RetiredAt = retiredAt.Time
+	return &p, nil
+}
+
+// PreviewApp is the app name a preview's deployments and events are recorded
+// under.
+func PreviewApp(branch string) string {
+	return "preview:" + branch
+}
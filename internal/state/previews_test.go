@@ -0,0 +1,90 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSavePreview(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	a, err := store.SavePreview(ctx, testRepoName, "preview/a", "aaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.SavePreview(ctx, testRepoName, "preview/b", "bbb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Slot != 1 || b.Slot != 2 {
+		t.Errorf("slots = %d, %d, want 1, 2", a.Slot, b.Slot)
+	}
+
+	if err := store.DeletePreview(ctx, testRepoName, "preview/a"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := store.SavePreview(ctx, testRepoName, "preview/c", "ccc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Slot != 1 {
+		t.Errorf("reused slot = %d, want 1", c.Slot)
+	}
+
+	if err := store.SetPreviewProject(ctx, testRepoName, "preview/b", "web-preview-b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RetirePreview(ctx, testRepoName, "preview/b"); err != nil {
+		t.Fatal(err)
+	}
+	b, err = store.SavePreview(ctx, testRepoName, "preview/b", "bbb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Retired() || b.ProjectName != "web-preview-b" {
+		t.Errorf("unchanged preview = %+v, want retired with project", b)
+	}
+	b, err = store.SavePreview(ctx, testRepoName, "preview/b", "bbb2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Retired() || b.Commit != "bbb2" || b.Slot != 2 {
+		t.Errorf("moved preview = %+v, want active on bbb2 in slot 2", b)
+	}
+
+	previews, err := store.ListPreviews(ctx, testRepoName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 2 || previews[0].Branch != "preview/c" || previews[1].Branch != "preview/b" {
+		t.Errorf("ListPreviews() = %+v", previews)
+	}
+
+	if err := store.DeletePreview(ctx, testRepoName, "preview/a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeletePreview() error = %v, want ErrNotFound", err)
+	}
+	if err := store.DeleteRepo(ctx, testRepoName); err != nil {
+		t.Fatal(err)
+	}
+	if previews, err := store.ListPreviews(ctx, testRepoName); err != nil || len(previews) != 0 {
+		t.Errorf("ListPreviews() after DeleteRepo = %v, %v", previews, err)
+	}
+}
+
+func TestSaveRepoPreview(t *testing.T) {
+	store := newTestStore(t)
+
+	preview := &RepoPreview{Pattern: "preview/*", TTL: 72 * time.Hour, PortOffset: 100}
+	if _, err := store.SaveRepo(t.Context(), RepoSpec{Name: "web", URL: "https://example.com/web.git", Branch: "main", Preview: preview}); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := store.GetRepo(t.Context(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.PreviewPattern != "preview/*" || repo.PreviewTTL != 72*time.Hour || repo.PreviewPortOffset != 100 {
+		t.Errorf("GetRepo() preview = %q, %v, %d", repo.PreviewPattern, repo.PreviewTTL, repo.PreviewPortOffset)
+	}
+}
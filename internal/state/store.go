@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	z "github.com/Oudwins/zog"
@@ -18,6 +20,7 @@ import (
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrInvalidStatus = errors.New("invalid deployment status")
+	ErrNotAwaiting   = errors.New("deployment is not awaiting approval")
 )
 
 const DefaultListLimit = 100
@@ -27,31 +30,102 @@ type Store struct {
 }
 
 type Repo struct {
-	Name        string
-	URL         string
-	Branch      string
-	CreatedAt   time.Time
-	AuthType    string
-	SSHKeyPath  string
-	Username    string
-	PasswordEnv string
+	Name             string
+	URL              string
+	Branch           string
+	CreatedAt        time.Time
+	AuthType         string
+	SSHKeyPath       string
+	Username         string
+	PasswordEnv      string
+	PassphraseEnv    string
+	PassphraseFile   string
+	KnownHostsPath   string
+	HostKeys         []string
+	TokenFile        string
+	AppID            int64
+	InstallationID   int64
+	AppKeyPath       string
+	GitHubAPIURL     string
+	CredentialHelper string
+	Secret           string
+	TagPattern       string
+	TagConstraint    string
+	TrustKeysPath    string
+	SignedTags       bool
+	Submodules       bool
+	Depth            int
+	SparsePaths      []string
+
+	PreviewPattern    string
+	PreviewTTL        time.Duration
+	PreviewPortOffset int
+}
+
+// RepoSpec describes a repository to save; nil sections are left unset.
+type RepoSpec struct {
+	Name     string
+	URL      string
+	Branch   string
+	Auth     *RepoAuth
+	Tags     *RepoTags
+	Trust    *RepoTrust
+	Checkout *RepoCheckout
+	Preview  *RepoPreview
 }
 
 type RepoAuth struct {
-	Type        string
-	SSHKeyPath  string
-	Username    string
-	PasswordEnv string
+	Type             string
+	SSHKeyPath       string
+	Username         string
+	PasswordEnv      string
+	PassphraseEnv    string
+	PassphraseFile   string
+	KnownHostsPath   string
+	HostKeys         []string
+	TokenFile        string
+	AppID            int64
+	InstallationID   int64
+	AppKeyPath       string
+	GitHubAPIURL     string
+	CredentialHelper string
+	Secret           string
+}
+
+type RepoTags struct {
+	Pattern    string
+	Constraint string
+}
+
+type RepoTrust struct {
+	KeysPath   string
+	SignedTags bool
+}
+
+type RepoCheckout struct {
+	Submodules  bool
+	Depth       int
+	SparsePaths []string
+}
+
+type RepoPreview struct {
+	Pattern    string
+	TTL        time.Duration
+	PortOffset int
 }
 
 type Deployment struct {
 	ID             int64
 	RepoName       string
+	App            string
 	CommitHash     string
 	ComposeContent string
 	DeployedAt     time.Time
 	Status         DeploymentStatus
 	Message        string
+	Diff           string
+	ReviewedBy     string
+	ReviewedAt     time.Time
 }
 
 type DeploymentStatus string
@@ -62,6 +136,8 @@ const (
 	StatusFailed     DeploymentStatus = "failed"
 	StatusSkipped    DeploymentStatus = "skipped"
 	StatusRolledBack DeploymentStatus = "rolled_back"
+	StatusAwaiting   DeploymentStatus = "awaiting_approval"
+	StatusRejected   DeploymentStatus = "rejected"
 )
 
 var statusSchema = z.String().OneOf([]string{
@@ -70,6 +146,8 @@ var statusSchema = z.String().OneOf([]string{
 	string(StatusFailed),
 	string(StatusSkipped),
 	string(StatusRolledBack),
+	string(StatusAwaiting),
+	string(StatusRejected),
 })
 
 func (s DeploymentStatus) IsValid() bool {
@@ -105,23 +183,63 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-func (s *Store) SaveRepo(ctx context.Context, name, url, branch string, auth *RepoAuth) (*Repo, error) {
-	var authType, sshKeyPath, username, passwordEnv any
-	if auth != nil {
+func (s *Store) SaveRepo(ctx context.Context, spec RepoSpec) (*Repo, error) {
+	var authType, sshKeyPath, username, passwordEnv, passphraseEnv, passphraseFile, knownHostsPath, hostKeys any
+	var tokenFile, appKeyPath, gitHubAPIURL, credHelper, secret any
+	var appID, installationID int64
+	if auth := spec.Auth; auth != nil {
 		authType = nullString(auth.Type)
 		sshKeyPath = nullString(auth.SSHKeyPath)
 		username = nullString(auth.Username)
 		passwordEnv = nullString(auth.PasswordEnv)
+		passphraseEnv = nullString(auth.PassphraseEnv)
+		passphraseFile = nullString(auth.PassphraseFile)
+		knownHostsPath = nullString(auth.KnownHostsPath)
+		hostKeys = nullString(strings.Join(auth.HostKeys, "\n"))
+		tokenFile = nullString(auth.TokenFile)
+		appID = auth.AppID
+		installationID = auth.InstallationID
+		appKeyPath = nullString(auth.AppKeyPath)
+		gitHubAPIURL = nullString(auth.GitHubAPIURL)
+		credHelper = nullString(auth.CredentialHelper)
+		secret = nullString(auth.Secret)
+	}
+	var tagPattern, tagConstraint any
+	if tags := spec.Tags; tags != nil {
+		tagPattern = nullString(tags.Pattern)
+		tagConstraint = nullString(tags.Constraint)
+	}
+	var trustKeysPath any
+	var signedTags bool
+	if trust := spec.Trust; trust != nil {
+		trustKeysPath = nullString(trust.KeysPath)
+		signedTags = trust.SignedTags
+	}
+	var submodules bool
+	var depth int
+	var sparsePaths any
+	if checkout := spec.Checkout; checkout != nil {
+		submodules = checkout.Submodules
+		depth = checkout.Depth
+		sparsePaths = nullString(strings.Join(checkout.SparsePaths, "\n"))
+	}
+	var previewPattern any
+	var previewTTL int64
+	var previewPortOffset int
+	if preview := spec.Preview; preview != nil {
+		previewPattern = nullString(preview.Pattern)
+		previewTTL = int64(preview.TTL / time.Second)
+		previewPortOffset = preview.PortOffset
 	}
 
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO repos (name, url, branch, auth_type, auth_ssh_key_path, auth_username, auth_password_env) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		name, url, branch, authType, sshKeyPath, username, passwordEnv,
+		`INSERT INTO repos (name, url, branch, auth_type, auth_ssh_key_path, auth_username, auth_password_env, tag_pattern, tag_constraint, trust_keys_path, trust_signed_tags, checkout_submodules, checkout_depth, checkout_sparse_paths, auth_passphrase_env, auth_passphrase_file, auth_known_hosts_path, auth_host_keys, auth_token_file, auth_app_id, auth_installation_id, auth_app_key_path, auth_github_api_url, auth_credential_helper, auth_secret, preview_pattern, preview_ttl, preview_port_offset) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		spec.Name, spec.URL, spec.Branch, authType, sshKeyPath, username, passwordEnv, tagPattern, tagConstraint, trustKeysPath, signedTags, submodules, depth, sparsePaths, passphraseEnv, passphraseFile, knownHostsPath, hostKeys, tokenFile, appID, installationID, appKeyPath, gitHubAPIURL, credHelper, secret, previewPattern, previewTTL, previewPortOffset,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return s.GetRepo(ctx, name)
+	return s.GetRepo(ctx, spec.Name)
 }
 
 func nullString(s string) any {
@@ -131,9 +249,11 @@ func nullString(s string) any {
 	return s
 }
 
+const repoColumns = `name, url, branch, created_at, auth_type, auth_ssh_key_path, auth_username, auth_password_env, tag_pattern, tag_constraint, trust_keys_path, trust_signed_tags, checkout_submodules, checkout_depth, checkout_sparse_paths, auth_passphrase_env, auth_passphrase_file, auth_known_hosts_path, auth_host_keys, auth_token_file, auth_app_id, auth_installation_id, auth_app_key_path, auth_github_api_url, auth_credential_helper, auth_secret, preview_pattern, preview_ttl, preview_port_offset`
+
 func (s *Store) GetRepo(ctx context.Context, name string) (*Repo, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT name, url, branch, created_at, auth_type, auth_ssh_key_path, auth_username, auth_password_env FROM repos WHERE name = ?`,
+		`SELECT `+repoColumns+` FROM repos WHERE name = ?`,
 		name,
 	)
 	r, err := scanRepo(row)
@@ -148,8 +268,11 @@ func (s *Store) GetRepo(ctx context.Context, name string) (*Repo, error) {
 
 func scanRepo(row *sql.Row) (*Repo, error) {
 	var r Repo
-	var authType, sshKeyPath, username, passwordEnv sql.NullString
-	err := row.Scan(&r.Name, &r.URL, &r.Branch, &r.CreatedAt, &authType, &sshKeyPath, &username, &passwordEnv)
+	var authType, sshKeyPath, username, passwordEnv, tagPattern, tagConstraint, trustKeysPath, sparsePaths sql.NullString
+	var passphraseEnv, passphraseFile, knownHostsPath, hostKeys sql.NullString
+	var tokenFile, appKeyPath, gitHubAPIURL, credHelper, secret, previewPattern sql.NullString
+	var previewTTL int64
+	err := row.Scan(&r.Name, &r.URL, &r.Branch, &r.CreatedAt, &authType, &sshKeyPath, &username, &passwordEnv, &tagPattern, &tagConstraint, &trustKeysPath, &r.SignedTags, &r.Submodules, &r.Depth, &sparsePaths, &passphraseEnv, &passphraseFile, &knownHostsPath, &hostKeys, &tokenFile, &r.AppID, &r.InstallationID, &appKeyPath, &gitHubAPIURL, &credHelper, &secret, &previewPattern, &previewTTL, &r.PreviewPortOffset)
 	if err != nil {
 		return nil, err
 	}
@@ -157,12 +280,31 @@ func scanRepo(row *sql.Row) (*Repo, error) {
 	r.SSHKeyPath = sshKeyPath.String
 	r.Username = username.String
 	r.PasswordEnv = passwordEnv.String
+	r.PassphraseEnv = passphraseEnv.String
+	r.PassphraseFile = passphraseFile.String
+	r.KnownHostsPath = knownHostsPath.String
+	if hostKeys.Valid {
+		r.HostKeys = strings.Split(hostKeys.String, "\n")
+	}
+	r.TokenFile = tokenFile.String
+	r.AppKeyPath = appKeyPath.String
+	r.GitHubAPIURL = gitHubAPIURL.String
+	r.CredentialHelper = credHelper.String
+	r.Secret = secret.String
+	r.TagPattern = tagPattern.String
+	r.TagConstraint = tagConstraint.String
+	r.TrustKeysPath = trustKeysPath.String
+	if sparsePaths.Valid {
+		r.SparsePaths = strings.Split(sparsePaths.String, "\n")
+	}
+	r.PreviewPattern = previewPattern.String
+	r.PreviewTTL = time.Duration(previewTTL) * time.Second
 	return &r, nil
 }
 
 func (s *Store) ListRepos(ctx context.Context) ([]*Repo, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT name, url, branch, created_at, auth_type, auth_ssh_key_path, auth_username, auth_password_env FROM repos ORDER BY name`,
+		`SELECT `+repoColumns+` FROM repos ORDER BY name`,
 	)
 	if err != nil {
 		return nil, err
@@ -182,8 +324,11 @@ func (s *Store) ListRepos(ctx context.Context) ([]*Repo, error) {
 
 func scanRepoRows(rows *sql.Rows) (*Repo, error) {
 	var r Repo
-	var authType, sshKeyPath, username, passwordEnv sql.NullString
-	err := rows.Scan(&r.Name, &r.URL, &r.Branch, &r.CreatedAt, &authType, &sshKeyPath, &username, &passwordEnv)
+	var authType, sshKeyPath, username, passwordEnv, tagPattern, tagConstraint, trustKeysPath, sparsePaths sql.NullString
+	var passphraseEnv, passphraseFile, knownHostsPath, hostKeys sql.NullString
+	var tokenFile, appKeyPath, gitHubAPIURL, credHelper, secret, previewPattern sql.NullString
+	var previewTTL int64
+	err := rows.Scan(&r.Name, &r.URL, &r.Branch, &r.CreatedAt, &authType, &sshKeyPath, &username, &passwordEnv, &tagPattern, &tagConstraint, &trustKeysPath, &r.SignedTags, &r.Submodules, &r.Depth, &sparsePaths, &passphraseEnv, &passphraseFile, &knownHostsPath, &hostKeys, &tokenFile, &r.AppID, &r.InstallationID, &appKeyPath, &gitHubAPIURL, &credHelper, &secret, &previewPattern, &previewTTL, &r.PreviewPortOffset)
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +336,25 @@ func scanRepoRows(rows *sql.Rows) (*Repo, error) {
 	r.SSHKeyPath = sshKeyPath.String
 	r.Username = username.String
 	r.PasswordEnv = passwordEnv.String
+	r.PassphraseEnv = passphraseEnv.String
+	r.PassphraseFile = passphraseFile.String
+	r.KnownHostsPath = knownHostsPath.String
+	if hostKeys.Valid {
+		r.HostKeys = strings.Split(hostKeys.String, "\n")
+	}
+	r.TokenFile = tokenFile.String
+	r.AppKeyPath = appKeyPath.String
+	r.GitHubAPIURL = gitHubAPIURL.String
+	r.CredentialHelper = credHelper.String
+	r.Secret = secret.String
+	r.TagPattern = tagPattern.String
+	r.TagConstraint = tagConstraint.String
+	r.TrustKeysPath = trustKeysPath.String
+	if sparsePaths.Valid {
+		r.SparsePaths = strings.Split(sparsePaths.String, "\n")
+	}
+	r.PreviewPattern = previewPattern.String
+	r.PreviewTTL = time.Duration(previewTTL) * time.Second
 	return &r, nil
 }
 
@@ -209,13 +373,80 @@ func (s *Store) DeleteRepo(ctx context.Context, name string) error {
 	return nil
 }
 
-func (s *Store) SaveDeployment(ctx context.Context, repoName, commit, composeContent string, status DeploymentStatus, message string) (*Deployment, error) {
+// RewriteSecrets replaces every stored repository secret and secret variable
+// with rewrite(secret) in a single transaction, so a failure leaves all of them unchanged.
+func (s *Store) RewriteSecrets(ctx context.Context, rewrite func(secret string) (string, error)) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT name, auth_secret FROM repos WHERE auth_secret IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+	secrets := map[string]string{}
+	for rows.Next() {
+		var name, secret string
+		if err := rows.Scan(&name, &secret); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		secrets[name] = secret
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	for name, secret := range secrets {
+		rewritten, err := rewrite(secret)
+		if err != nil {
+			return 0, fmt.Errorf("repo %s: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE repos SET auth_secret = ? WHERE name = ?`, rewritten, name); err != nil {
+			return 0, err
+		}
+	}
+
+	envRows, err := tx.QueryContext(ctx, `SELECT repo_name, key, value FROM repo_env WHERE secret = 1`)
+	if err != nil {
+		return 0, err
+	}
+	var vars []EnvVar
+	for envRows.Next() {
+		var v EnvVar
+		if err := envRows.Scan(&v.RepoName, &v.Key, &v.Value); err != nil {
+			_ = envRows.Close()
+			return 0, err
+		}
+		vars = append(vars, v)
+	}
+	if err := envRows.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, v := range vars {
+		rewritten, err := rewrite(v.Value)
+		if err != nil {
+			return 0, fmt.Errorf("repo %s variable %s: %w", v.RepoName, v.Key, err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE repo_env SET value = ? WHERE repo_name = ? AND key = ?`, rewritten, v.RepoName, v.Key); err != nil {
+			return 0, err
+		}
+	}
+	return len(secrets) + len(vars), tx.Commit()
+}
+
+const deploymentColumns = `id, repo_name, app, commit_hash, compose_content, deployed_at, status, message, diff, reviewed_by, reviewed_at`
+
+func (s *Store) SaveDeployment(ctx context.Context, repoName, app, commit, composeContent string, status DeploymentStatus, message string) (*Deployment, error) {
 	if !status.IsValid() {
 		return nil, ErrInvalidStatus
 	}
 	result, err := s.db.ExecContext(ctx,
-		`INSERT INTO deployments (repo_name, commit_hash, compose_content, status, message) VALUES (?, ?, ?, ?, ?)`,
-		repoName, commit, composeContent, status, message,
+		`INSERT INTO deployments (repo_name, app, commit_hash, compose_content, status, message) VALUES (?, ?, ?, ?, ?, ?)`,
+		repoName, app, commit, composeContent, status, message,
 	)
 	if err != nil {
 		return nil, err
@@ -231,35 +462,43 @@ func (s *Store) SaveDeployment(ctx context.Context, repoName, commit, composeCon
 
 func (s *Store) GetDeployment(ctx context.Context, id int64) (*Deployment, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, repo_name, commit_hash, compose_content, deployed_at, status, message FROM deployments WHERE id = ?`,
+		`SELECT `+deploymentColumns+` FROM deployments WHERE id = ?`,
 		id,
 	)
 	return scanDeployment(row)
 }
 
-func (s *Store) GetLastDeployment(ctx context.Context, repoName string) (*Deployment, error) {
+func (s *Store) GetLastDeployment(ctx context.Context, repoName, app string) (*Deployment, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, repo_name, commit_hash, compose_content, deployed_at, status, message FROM deployments WHERE repo_name = ? ORDER BY id DESC LIMIT 1`,
-		repoName,
+		`SELECT `+deploymentColumns+` FROM deployments WHERE repo_name = ? AND app = ? ORDER BY id DESC LIMIT 1`,
+		repoName, app,
 	)
 	return scanDeployment(row)
 }
 
-func (s *Store) GetDeploymentByCommit(ctx context.Context, repoName, commit string) (*Deployment, error) {
+func (s *Store) GetLastSuccessfulDeployment(ctx context.Context, repoName, app string) (*Deployment, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, repo_name, commit_hash, compose_content, deployed_at, status, message FROM deployments WHERE repo_name = ? AND commit_hash = ? ORDER BY id DESC LIMIT 1`,
-		repoName, commit,
+		`SELECT `+deploymentColumns+` FROM deployments WHERE repo_name = ? AND app = ? AND status = ? AND commit_hash != '' ORDER BY id DESC LIMIT 1`,
+		repoName, app, StatusSuccess,
 	)
 	return scanDeployment(row)
 }
 
-func (s *Store) ListDeployments(ctx context.Context, repoName string, limit int) ([]*Deployment, error) {
+func (s *Store) GetDeploymentByCommit(ctx context.Context, repoName, app, commit string) (*Deployment, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+deploymentColumns+` FROM deployments WHERE repo_name = ? AND app = ? AND commit_hash = ? ORDER BY id DESC LIMIT 1`,
+		repoName, app, commit,
+	)
+	return scanDeployment(row)
+}
+
+func (s *Store) ListDeployments(ctx context.Context, repoName, app string, limit int) ([]*Deployment, error) {
 	if limit <= 0 {
 		limit = DefaultListLimit
 	}
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, repo_name, commit_hash, compose_content, deployed_at, status, message FROM deployments WHERE repo_name = ? ORDER BY id DESC LIMIT ?`,
-		repoName, limit,
+		`SELECT `+deploymentColumns+` FROM deployments WHERE repo_name = ? AND app = ? ORDER BY id DESC LIMIT ?`,
+		repoName, app, limit,
 	)
 	if err != nil {
 		return nil, err
@@ -299,6 +538,94 @@ func (s *Store) UpdateDeploymentStatus(ctx context.Context, id int64, status Dep
 	return nil
 }
 
+func (s *Store) RequestApproval(ctx context.Context, id int64, diff string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var repoName, app, commit string
+	err = tx.QueryRowContext(ctx, `SELECT repo_name, app, commit_hash FROM deployments WHERE id = ?`, id).Scan(&repoName, &app, &commit)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE deployments SET status = ?, message = ? WHERE repo_name = ? AND app = ? AND status = ? AND id != ?`,
+		StatusSkipped, "superseded by "+shortCommit(commit), repoName, app, StatusAwaiting, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE deployments SET status = ?, message = ?, diff = ? WHERE id = ?`,
+		StatusAwaiting, "waiting for approval", nullString(diff), id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) ListAwaitingApproval(ctx context.Context, repoName, app string) ([]*Deployment, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+deploymentColumns+` FROM deployments WHERE repo_name = ? AND app = ? AND status = ? ORDER BY id DESC`,
+		repoName, app, StatusAwaiting,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deployments []*Deployment
+	for rows.Next() {
+		d, err := scanDeploymentRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, d)
+	}
+
+	return deployments, rows.Err()
+}
+
+func (s *Store) ReviewDeployment(ctx context.Context, id int64, status DeploymentStatus, message, reviewer string) error {
+	if !status.IsValid() {
+		return ErrInvalidStatus
+	}
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE deployments SET status = ?, message = ?, reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP WHERE id = ? AND status = ?`,
+		status, message, reviewer, id, StatusAwaiting,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+	if _, err := s.GetDeployment(ctx, id); err != nil {
+		return err
+	}
+	return ErrNotAwaiting
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
 func runMigrations(db *sql.DB) error {
 	source, err := iofs.New(migrations.FS, ".")
 	if err != nil {
@@ -322,27 +649,33 @@ func runMigrations(db *sql.DB) error {
 	return nil
 }
 
+type scanner interface {
+	Scan(dest ...any) error
+}
+
 func scanDeployment(row *sql.Row) (*Deployment, error) {
-	var d Deployment
-	var message sql.NullString
-	err := row.Scan(&d.ID, &d.RepoName, &d.CommitHash, &d.ComposeContent, &d.DeployedAt, &d.Status, &message)
+	d, err := scanDeploymentFrom(row)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
-	if err != nil {
-		return nil, err
-	}
-	d.Message = message.String
-	return &d, nil
+	return d, err
 }
 
 func scanDeploymentRows(rows *sql.Rows) (*Deployment, error) {
+	return scanDeploymentFrom(rows)
+}
+
+func scanDeploymentFrom(row scanner) (*Deployment, error) {
 	var d Deployment
-	var message sql.NullString
-	err := rows.Scan(&d.ID, &d.RepoName, &d.CommitHash, &d.ComposeContent, &d.DeployedAt, &d.Status, &message)
+	var message, diff, reviewedBy sql.NullString
+	var reviewedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.RepoName, &d.App, &d.CommitHash, &d.ComposeContent, &d.DeployedAt, &d.Status, &message, &diff, &reviewedBy, &reviewedAt)
 	if err != nil {
 		return nil, err
 	}
 	d.Message = message.String
+	d.Diff = diff.String
+	d.ReviewedBy = reviewedBy.String
+	d.ReviewedAt = reviewedAt.Time
 	return &d, nil
 }
@@ -1,7 +1,10 @@
 package state
 
 import (
+	"errors"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -19,7 +22,7 @@ func newTestStore(t *testing.T) *Store {
 		t.Fatal(err)
 	}
 	t.Cleanup(func() { _ = store.Close() })
-	_, err = store.SaveRepo(t.Context(), testRepoName, "https://example.com/repo.git", "main", nil)
+	_, err = store.SaveRepo(t.Context(), RepoSpec{Name: testRepoName, URL: "https://example.com/repo.git", Branch: "main"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -33,11 +36,169 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestSaveRepoTagsAndTrust(t *testing.T) {
+	store := newTestStore(t)
+	_, err := store.SaveRepo(t.Context(), RepoSpec{Name: "releases", URL: "https://example.com/releases.git", Tags: &RepoTags{Pattern: "v*", Constraint: ">=1.0.0"}, Trust: &RepoTrust{KeysPath: "/etc/kedge/trusted.keys", SignedTags: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := store.GetRepo(t.Context(), "releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.TagPattern != "v*" || repo.TagConstraint != ">=1.0.0" {
+		t.Errorf("tags: got %q %q", repo.TagPattern, repo.TagConstraint)
+	}
+	if repo.TrustKeysPath != "/etc/kedge/trusted.keys" || !repo.SignedTags {
+		t.Errorf("trust: got %q %v", repo.TrustKeysPath, repo.SignedTags)
+	}
+
+	repo, err = store.GetRepo(t.Context(), testRepoName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.TagPattern != "" || repo.TagConstraint != "" || repo.TrustKeysPath != "" || repo.SignedTags {
+		t.Errorf("expected branch repo without tags or trust, got %+v", repo)
+	}
+}
+
+func TestSaveRepoCheckout(t *testing.T) {
+	store := newTestStore(t)
+	checkout := &RepoCheckout{Submodules: true, Depth: 1, SparsePaths: []string{"apps/web", "shared"}}
+	if _, err := store.SaveRepo(t.Context(), RepoSpec{Name: "mono", URL: "https://example.com/mono.git", Branch: "main", Checkout: checkout}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := store.GetRepo(t.Context(), "mono")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !repo.Submodules || repo.Depth != 1 || !slices.Equal(repo.SparsePaths, checkout.SparsePaths) {
+		t.Errorf("checkout: got %v %d %v", repo.Submodules, repo.Depth, repo.SparsePaths)
+	}
+
+	repo, err = store.GetRepo(t.Context(), testRepoName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Submodules || repo.Depth != 0 || repo.SparsePaths != nil {
+		t.Errorf("expected full checkout, got %+v", repo)
+	}
+}
+
+func TestSaveRepoSSHAuth(t *testing.T) {
+	store := newTestStore(t)
+	auth := &RepoAuth{
+		Type:           "ssh-key",
+		SSHKeyPath:     "/etc/kedge/deploy_key",
+		Username:       "deploy",
+		PassphraseFile: "/etc/kedge/passphrase",
+		HostKeys:       []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"},
+	}
+	if _, err := store.SaveRepo(t.Context(), RepoSpec{Name: "private", URL: "git@github.com:acme/private.git", Branch: "main", Auth: auth}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := store.GetRepo(t.Context(), "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Username != "deploy" || repo.PassphraseFile != auth.PassphraseFile || repo.PassphraseEnv != "" || repo.KnownHostsPath != "" {
+		t.Errorf("auth: got %+v", repo)
+	}
+	if !slices.Equal(repo.HostKeys, auth.HostKeys) {
+		t.Errorf("host keys: got %v", repo.HostKeys)
+	}
+}
+
+func TestSaveRepoCredentialSources(t *testing.T) {
+	store := newTestStore(t)
+	auth := &RepoAuth{
+		Type:           "github-app",
+		AppID:          12345,
+		InstallationID: 67890,
+		AppKeyPath:     "/etc/kedge/app.pem",
+		GitHubAPIURL:   "https://github.example.com/api/v3",
+	}
+	if _, err := store.SaveRepo(t.Context(), RepoSpec{Name: "app", URL: "https://github.com/acme/app.git", Branch: "main", Auth: auth}); err != nil {
+		t.Fatal(err)
+	}
+	helper := &RepoAuth{Type: "credential-helper", CredentialHelper: "store"}
+	if _, err := store.SaveRepo(t.Context(), RepoSpec{Name: "helper", URL: "https://github.com/acme/helper.git", Branch: "main", Auth: helper}); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := store.GetRepo(t.Context(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	helperRepo, err := store.GetRepo(t.Context(), "helper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.AppID != 12345 || app.InstallationID != 67890 || app.AppKeyPath != auth.AppKeyPath || app.GitHubAPIURL != auth.GitHubAPIURL {
+		t.Errorf("github app: got %+v", app)
+	}
+	if helperRepo.CredentialHelper != "store" || helperRepo.AppID != 0 || helperRepo.TokenFile != "" {
+		t.Errorf("credential helper: got %+v", helperRepo)
+	}
+}
+
+func TestRewriteSecrets(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+	for _, name := range []string{"a", "b"} {
+		auth := &RepoAuth{Type: "token", Secret: "old-" + name}
+		if _, err := store.SaveRepo(ctx, RepoSpec{Name: name, URL: "https://github.com/acme/" + name + ".git", Branch: "main", Auth: auth}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.SetEnv(ctx, "a", "DB_PASSWORD", "old-env", true); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := store.RewriteSecrets(ctx, func(secret string) (string, error) {
+		return strings.Replace(secret, "old", "new", 1), nil
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("RewriteSecrets() = %d, %v", n, err)
+	}
+	repo, err := store.GetRepo(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Secret != "new-b" {
+		t.Errorf("secret = %q, want new-b", repo.Secret)
+	}
+	if vars, err := store.ListEnv(ctx, "a"); err != nil || vars[0].Value != "new-env" {
+		t.Errorf("ListEnv() = %v, %v, want rewritten secret", vars, err)
+	}
+
+	_, err = store.RewriteSecrets(ctx, func(secret string) (string, error) {
+		if secret == "new-b" {
+			return "", errors.New("boom")
+		}
+		return "newer", nil
+	})
+	if err == nil {
+		t.Fatal("RewriteSecrets() error = nil")
+	}
+	repo, err = store.GetRepo(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Secret != "new-a" {
+		t.Errorf("secret after failed rewrite = %q, want new-a", repo.Secret)
+	}
+}
+
 func TestSaveDeployment(t *testing.T) {
 	store := newTestStore(t)
 	ctx := t.Context()
 
-	d, err := store.SaveDeployment(ctx, testRepoName, "abc123", "services:\n  web:\n    image: nginx", StatusSuccess, "deployed successfully")
+	d, err := store.SaveDeployment(ctx, testRepoName, "", "abc123", "services:\n  web:\n    image: nginx", StatusSuccess, "deployed successfully")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -57,17 +218,17 @@ func TestGetLastDeployment(t *testing.T) {
 	store := newTestStore(t)
 	ctx := t.Context()
 
-	_, err := store.SaveDeployment(ctx, testRepoName, "commit1", "content1", StatusSuccess, "")
+	_, err := store.SaveDeployment(ctx, testRepoName, "", "commit1", "content1", StatusSuccess, "")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = store.SaveDeployment(ctx, testRepoName, "commit2", "content2", StatusSuccess, "")
+	_, err = store.SaveDeployment(ctx, testRepoName, "", "commit2", "content2", StatusSuccess, "")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	last, err := store.GetLastDeployment(ctx, testRepoName)
+	last, err := store.GetLastDeployment(ctx, testRepoName, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -80,22 +241,48 @@ func TestGetLastDeployment(t *testing.T) {
 func TestGetLastDeploymentEmpty(t *testing.T) {
 	store := newTestStore(t)
 
-	_, err := store.GetLastDeployment(t.Context(), testRepoName)
+	_, err := store.GetLastDeployment(t.Context(), testRepoName, "")
 	if err != ErrNotFound {
 		t.Errorf("error: got %v, want ErrNotFound", err)
 	}
 }
 
+func TestGetLastSuccessfulDeployment(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	for _, d := range []struct {
+		commit string
+		status DeploymentStatus
+	}{
+		{"commit1", StatusSuccess},
+		{"commit2", StatusFailed},
+		{"", StatusSuccess},
+	} {
+		if _, err := store.SaveDeployment(ctx, testRepoName, "", d.commit, "content", d.status, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	last, err := store.GetLastSuccessfulDeployment(ctx, testRepoName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.CommitHash != "commit1" {
+		t.Errorf(testCommitFmt, last.CommitHash, "commit1")
+	}
+}
+
 func TestGetDeploymentByCommit(t *testing.T) {
 	store := newTestStore(t)
 	ctx := t.Context()
 
-	_, err := store.SaveDeployment(ctx, testRepoName, "abc123", "content", StatusSuccess, "")
+	_, err := store.SaveDeployment(ctx, testRepoName, "", "abc123", "content", StatusSuccess, "")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	d, err := store.GetDeploymentByCommit(ctx, testRepoName, "abc123")
+	d, err := store.GetDeploymentByCommit(ctx, testRepoName, "", "abc123")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -108,7 +295,7 @@ func TestGetDeploymentByCommit(t *testing.T) {
 func TestGetDeploymentByCommitNotFound(t *testing.T) {
 	store := newTestStore(t)
 
-	_, err := store.GetDeploymentByCommit(t.Context(), testRepoName, "nonexistent")
+	_, err := store.GetDeploymentByCommit(t.Context(), testRepoName, "", "nonexistent")
 	if err != ErrNotFound {
 		t.Errorf("error: got %v, want ErrNotFound", err)
 	}
@@ -119,13 +306,13 @@ func TestListDeployments(t *testing.T) {
 	ctx := t.Context()
 
 	for i := range 5 {
-		_, err := store.SaveDeployment(ctx, testRepoName, "commit"+string(rune('0'+i)), "content", StatusSuccess, "")
+		_, err := store.SaveDeployment(ctx, testRepoName, "", "commit"+string(rune('0'+i)), "content", StatusSuccess, "")
 		if err != nil {
 			t.Fatal(err)
 		}
 	}
 
-	deployments, err := store.ListDeployments(ctx, testRepoName, 3)
+	deployments, err := store.ListDeployments(ctx, testRepoName, "", 3)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -139,11 +326,42 @@ func TestListDeployments(t *testing.T) {
 	}
 }
 
+func TestDeploymentsScopedByApp(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	for _, app := range []string{"grafana", "prometheus", "grafana"} {
+		if _, err := store.SaveDeployment(ctx, testRepoName, app, "commit-"+app, "content", StatusSuccess, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deployments, err := store.ListDeployments(ctx, testRepoName, "grafana", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments) != 2 {
+		t.Errorf("count: got %d, want 2", len(deployments))
+	}
+
+	last, err := store.GetLastDeployment(ctx, testRepoName, "prometheus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.App != "prometheus" || last.CommitHash != "commit-prometheus" {
+		t.Errorf("last: got %s/%s", last.App, last.CommitHash)
+	}
+
+	if _, err := store.GetLastDeployment(ctx, testRepoName, ""); err != ErrNotFound {
+		t.Errorf("repo-level deployment: got %v, want ErrNotFound", err)
+	}
+}
+
 func TestUpdateDeploymentStatus(t *testing.T) {
 	store := newTestStore(t)
 	ctx := t.Context()
 
-	d, err := store.SaveDeployment(ctx, testRepoName, "abc123", "content", StatusPending, "")
+	d, err := store.SaveDeployment(ctx, testRepoName, "", "abc123", "content", StatusPending, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -179,7 +397,7 @@ func TestStatusRolledBack(t *testing.T) {
 	store := newTestStore(t)
 	ctx := t.Context()
 
-	d, err := store.SaveDeployment(ctx, testRepoName, "abc123", "content", StatusSuccess, "")
+	d, err := store.SaveDeployment(ctx, testRepoName, "", "abc123", "content", StatusSuccess, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -202,7 +420,7 @@ func TestStatusRolledBack(t *testing.T) {
 func TestSaveDeploymentInvalidStatus(t *testing.T) {
 	store := newTestStore(t)
 
-	_, err := store.SaveDeployment(t.Context(), testRepoName, "abc123", "content", "invalid", "")
+	_, err := store.SaveDeployment(t.Context(), testRepoName, "", "abc123", "content", "invalid", "")
 	if err != ErrInvalidStatus {
 		t.Errorf("error: got %v, want ErrInvalidStatus", err)
 	}
@@ -212,7 +430,7 @@ func TestUpdateDeploymentStatusInvalid(t *testing.T) {
 	store := newTestStore(t)
 	ctx := t.Context()
 
-	d, err := store.SaveDeployment(ctx, testRepoName, "abc123", "content", StatusPending, "")
+	d, err := store.SaveDeployment(ctx, testRepoName, "", "abc123", "content", StatusPending, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -222,3 +440,91 @@ func TestUpdateDeploymentStatusInvalid(t *testing.T) {
 		t.Errorf("error: got %v, want ErrInvalidStatus", err)
 	}
 }
+
+func TestRequestApprovalSupersedesOlder(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	first, err := store.SaveDeployment(ctx, testRepoName, "", "commit1", "content1", StatusPending, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RequestApproval(ctx, first.ID, "web: create"); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := store.SaveDeployment(ctx, testRepoName, "", "commit2", "content2", StatusPending, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RequestApproval(ctx, second.ID, "web: update"); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := store.ListAwaitingApproval(ctx, testRepoName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("count: got %d, want 1", len(pending))
+	}
+	if pending[0].ID != second.ID {
+		t.Errorf("id: got %d, want %d", pending[0].ID, second.ID)
+	}
+	if pending[0].Diff != "web: update" {
+		t.Errorf("diff: got %q, want %q", pending[0].Diff, "web: update")
+	}
+
+	superseded, err := store.GetDeployment(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if superseded.Status != StatusSkipped {
+		t.Errorf("status: got %q, want %q", superseded.Status, StatusSkipped)
+	}
+}
+
+func TestReviewDeployment(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	d, err := store.SaveDeployment(ctx, testRepoName, "", "abc123", "content", StatusPending, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RequestApproval(ctx, d.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.ReviewDeployment(ctx, d.ID, StatusSuccess, "approved", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	reviewed, err := store.GetDeployment(ctx, d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewed.Status != StatusSuccess {
+		t.Errorf("status: got %q, want %q", reviewed.Status, StatusSuccess)
+	}
+	if reviewed.ReviewedBy != "alice" {
+		t.Errorf("reviewed by: got %q, want %q", reviewed.ReviewedBy, "alice")
+	}
+	if reviewed.ReviewedAt.IsZero() {
+		t.Error("expected reviewed at to be set")
+	}
+
+	err = store.ReviewDeployment(ctx, d.ID, StatusSuccess, "approved", "bob")
+	if err != ErrNotAwaiting {
+		t.Errorf("error: got %v, want ErrNotAwaiting", err)
+	}
+}
+
+func TestReviewDeploymentNotFound(t *testing.T) {
+	store := newTestStore(t)
+
+	err := store.ReviewDeployment(t.Context(), 999, StatusSuccess, "", "alice")
+	if err != ErrNotFound {
+		t.Errorf("error: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,199 @@
+package state
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	z "github.com/Oudwins/zog"
+)
+
+var (
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExists  = errors.New("token already exists")
+)
+
+const (
+	tokenPrefix        = "kedge_"
+	lastUsedResolution = time.Minute
+)
+
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+var roleSchema = z.String().OneOf([]string{
+	string(RoleViewer),
+	string(RoleOperator),
+	string(RoleAdmin),
+})
+
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+func (r Role) IsValid() bool {
+	str := string(r)
+	return roleSchema.Validate(&str) == nil
+}
+
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+type Token struct {
+	ID         int64
+	Name       string
+	Role       Role
+	RepoName   string
+	CreatedBy  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+func (t *Token) Permits(required Role, repoName string) bool {
+	if !t.Role.Allows(required) {
+		return false
+	}
+	return t.RepoName == "" || t.RepoName == repoName
+}
+
+func (t *Token) Identity() string {
+	return "token:" + t.Name
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+const tokenColumns = `id, name, role, repo_name, created_by, created_at, last_used_at`
+
+func (s *Store) CreateToken(ctx context.Context, name string, role Role, repoName, createdBy string) (*Token, string, error) {
+	if !role.IsValid() {
+		return nil, "", ErrInvalidRole
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO api_tokens (name, token_hash, role, repo_name, created_by) VALUES (?, ?, ?, ?, ?)`,
+		name, hashToken(secret), role, nullString(repoName), nullString(createdBy),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, "", ErrTokenExists
+		}
+		return nil, "", err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, "", err
+	}
+
+	row := s.db.QueryRowContext(ctx, `SELECT `+tokenColumns+` FROM api_tokens WHERE id = ?`, id)
+	t, err := scanToken(row)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, secret, nil
+}
+
+func (s *Store) AuthenticateToken(ctx context.Context, secret string) (*Token, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+
+	row := s.db.QueryRowContext(ctx, `SELECT `+tokenColumns+` FROM api_tokens WHERE token_hash = ?`, hashToken(secret))
+	t, err := scanToken(row)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Sub(t.LastUsedAt) >= lastUsedResolution {
+		if _, err := s.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, sqliteTime(now), t.ID); err != nil {
+			return nil, err
+		}
+		t.LastUsedAt = now
+	}
+	return t, nil
+}
+
+func (s *Store) ListTokens(ctx context.Context) ([]*Token, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+tokenColumns+` FROM api_tokens ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*Token
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *Store) RevokeToken(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanToken(row scanner) (*Token, error) {
+	var t Token
+	var repoName, createdBy sql.NullString
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.Name, &t.Role, &repoName, &createdBy, &t.CreatedAt, &lastUsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.RepoName = repoName.String
+	t.CreatedBy = createdBy.String
+	t.LastUsedAt = lastUsedAt.Time
+	return &t, nil
+}
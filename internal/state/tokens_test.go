@@ -0,0 +1,127 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreateAndAuthenticateToken(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	token, secret, err := store.CreateToken(ctx, "ci", RoleOperator, testRepoName, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		t.Errorf("secret: got %q, want prefix %q", secret, tokenPrefix)
+	}
+	if token.Role != RoleOperator || token.RepoName != testRepoName || token.CreatedBy != "alice" {
+		t.Errorf("unexpected token: %+v", token)
+	}
+
+	got, err := store.AuthenticateToken(ctx, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "ci" {
+		t.Errorf("name: got %q, want %q", got.Name, "ci")
+	}
+	if got.LastUsedAt.IsZero() {
+		t.Error("expected last used to be set")
+	}
+
+	if _, err := store.AuthenticateToken(ctx, secret+"x"); err != ErrInvalidToken {
+		t.Errorf("error: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := store.AuthenticateToken(ctx, "nope"); err != ErrInvalidToken {
+		t.Errorf("error: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestTokenSecretNotStored(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	_, secret, err := store.CreateToken(ctx, "ci", RoleViewer, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored string
+	if err := store.db.QueryRowContext(ctx, `SELECT token_hash FROM api_tokens WHERE name = ?`, "ci").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == secret || strings.Contains(stored, secret) {
+		t.Error("expected only the token hash to be stored")
+	}
+}
+
+func TestCreateTokenErrors(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	if _, _, err := store.CreateToken(ctx, "ci", "root", "", ""); err != ErrInvalidRole {
+		t.Errorf("error: got %v, want ErrInvalidRole", err)
+	}
+	if _, _, err := store.CreateToken(ctx, "ci", RoleViewer, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.CreateToken(ctx, "ci", RoleAdmin, "", ""); err != ErrTokenExists {
+		t.Errorf("error: got %v, want ErrTokenExists", err)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	store := newTestStore(t)
+	ctx := t.Context()
+
+	_, secret, err := store.CreateToken(ctx, "ci", RoleAdmin, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeToken(ctx, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AuthenticateToken(ctx, secret); err != ErrInvalidToken {
+		t.Errorf("error: got %v, want ErrInvalidToken", err)
+	}
+	if err := store.RevokeToken(ctx, "ci"); err != ErrNotFound {
+		t.Errorf("error: got %v, want ErrNotFound", err)
+	}
+
+	tokens, err := store.ListTokens(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("expected no tokens, got %d", len(tokens))
+	}
+}
+
+func TestTokenPermits(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    Token
+		required Role
+		repo     string
+		want     bool
+	}{
+		{"viewer reads", Token{Role: RoleViewer}, RoleViewer, "web", true},
+		{"viewer cannot operate", Token{Role: RoleViewer}, RoleOperator, "web", false},
+		{"operator operates", Token{Role: RoleOperator}, RoleOperator, "web", true},
+		{"operator cannot admin", Token{Role: RoleOperator}, RoleAdmin, "", false},
+		{"admin does anything", Token{Role: RoleAdmin}, RoleAdmin, "", true},
+		{"scoped to repo", Token{Role: RoleOperator, RepoName: "web"}, RoleOperator, "web", true},
+		{"scoped to other repo", Token{Role: RoleOperator, RepoName: "web"}, RoleOperator, "api", false},
+		{"scoped without repo", Token{Role: RoleAdmin, RepoName: "web"}, RoleViewer, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.Permits(tt.required, tt.repo); got != tt.want {
+				t.Errorf("Permits(%s, %q) = %v, want %v", tt.required, tt.repo, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package telemetry
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -10,21 +11,53 @@ import (
 	"github.com/LoriKarikari/kedge/internal/version"
 )
 
+type ServiceState struct {
+	Service  string
+	State    string
+	Restarts int
+}
+
+type repoSnapshot struct {
+	services           []ServiceState
+	outOfSync          int
+	pendingCommits     int
+	deployedCommitTime time.Time
+}
+
 type Metrics struct {
 	deploymentsTotal   metric.Int64Counter
 	driftDetectedTotal metric.Int64Counter
 	gitPollsTotal      metric.Int64Counter
+	imagePullBytes     metric.Int64Counter
 
 	reconciliationDuration metric.Float64Histogram
 	gitPollDuration        metric.Float64Histogram
+	imagePullDuration      metric.Float64Histogram
+	serviceDeployDuration  metric.Float64Histogram
 
-	servicesTotal           metric.Int64UpDownCounter
 	lastDeploymentTimestamp metric.Int64Gauge
+	lastSuccessfulReconcile metric.Int64Gauge
 	info                    metric.Int64Gauge
+
+	containers        metric.Int64ObservableGauge
+	containerRestarts metric.Int64ObservableGauge
+	servicesOutOfSync metric.Int64ObservableGauge
+	pendingCommits    metric.Int64ObservableGauge
+	deployedCommitAge metric.Float64ObservableGauge
+
+	mu    sync.Mutex
+	repos map[string]*repoSnapshot
+}
+
+func RepoLabel(repo, app string) string {
+	if app == "" {
+		return repo
+	}
+	return repo + "/" + app
 }
 
 func newMetrics(meter metric.Meter) (*Metrics, error) {
-	m := &Metrics{}
+	m := &Metrics{repos: make(map[string]*repoSnapshot)}
 	var err error
 
 	m.deploymentsTotal, err = meter.Int64Counter(
@@ -72,15 +105,98 @@ func newMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, err
 	}
 
-	m.servicesTotal, err = meter.Int64UpDownCounter(
-		"kedge_services_total",
-		metric.WithDescription("Current number of services by state"),
+	m.imagePullBytes, err = meter.Int64Counter(
+		"kedge_image_pull_bytes_total",
+		metric.WithDescription("Total bytes downloaded while pulling images"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.imagePullDuration, err = meter.Float64Histogram(
+		"kedge_image_pull_duration_seconds",
+		metric.WithDescription("Duration of image pulls"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.serviceDeployDuration, err = meter.Float64Histogram(
+		"kedge_service_deploy_duration_seconds",
+		metric.WithDescription("Duration of individual service deployments"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.lastSuccessfulReconcile, err = meter.Int64Gauge(
+		"kedge_last_successful_reconcile_timestamp",
+		metric.WithDescription("Unix timestamp of the last successful reconciliation"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.containers, err = meter.Int64ObservableGauge(
+		"kedge_containers",
+		metric.WithDescription("Managed containers by service and state"),
+		metric.WithUnit("{container}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.containerRestarts, err = meter.Int64ObservableGauge(
+		"kedge_container_restarts",
+		metric.WithDescription("Restart count of managed containers"),
+		metric.WithUnit("{restart}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.servicesOutOfSync, err = meter.Int64ObservableGauge(
+		"kedge_services_out_of_sync",
+		metric.WithDescription("Services whose running state differs from the compose file"),
 		metric.WithUnit("{service}"),
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	m.pendingCommits, err = meter.Int64ObservableGauge(
+		"kedge_pending_commits",
+		metric.WithDescription("Commits on the tracked branch that are not deployed"),
+		metric.WithUnit("{commit}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.deployedCommitAge, err = meter.Float64ObservableGauge(
+		"kedge_deployed_commit_age_seconds",
+		metric.WithDescription("Age of the currently deployed commit"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.RegisterCallback(m.observe,
+		m.containers,
+		m.containerRestarts,
+		m.servicesOutOfSync,
+		m.pendingCommits,
+		m.deployedCommitAge,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	m.lastDeploymentTimestamp, err = meter.Int64Gauge(
 		"kedge_last_deployment_timestamp",
 		metric.WithDescription("Unix timestamp of last deployment"),
@@ -153,11 +269,99 @@ func (m *Metrics) RecordGitPoll(ctx context.Context, repo string, duration time.
 	)
 }
 
-func (m *Metrics) SetServices(ctx context.Context, repo, state string, delta int64) {
-	m.servicesTotal.Add(ctx, delta,
+func (m *Metrics) RecordImagePull(ctx context.Context, repo, service string, duration time.Duration, bytes int64, success bool) {
+	m.imagePullDuration.Record(ctx, duration.Seconds(),
 		metric.WithAttributes(
 			attribute.String("repo", repo),
-			attribute.String("state", state),
+			attribute.String("service", service),
+			attribute.Bool("success", success),
+		),
+	)
+	if bytes > 0 {
+		m.imagePullBytes.Add(ctx, bytes,
+			metric.WithAttributes(
+				attribute.String("repo", repo),
+				attribute.String("service", service),
+			),
+		)
+	}
+}
+
+func (m *Metrics) RecordServiceDeploy(ctx context.Context, repo, service string, duration time.Duration, success bool) {
+	m.serviceDeployDuration.Record(ctx, duration.Seconds(),
+		metric.WithAttributes(
+			attribute.String("repo", repo),
+			attribute.String("service", service),
+			attribute.Bool("success", success),
 		),
 	)
 }
+
+func (m *Metrics) RecordSuccessfulReconcile(ctx context.Context, repo string) {
+	m.lastSuccessfulReconcile.Record(ctx, time.Now().Unix(),
+		metric.WithAttributes(attribute.String("repo", repo)),
+	)
+}
+
+func (m *Metrics) SetServiceStates(repo string, states []ServiceState) {
+	m.update(repo, func(s *repoSnapshot) { s.services = states })
+}
+
+func (m *Metrics) SetOutOfSync(repo string, services int) {
+	m.update(repo, func(s *repoSnapshot) { s.outOfSync = services })
+}
+
+func (m *Metrics) SetPendingCommits(repo string, commits int) {
+	m.update(repo, func(s *repoSnapshot) { s.pendingCommits = commits })
+}
+
+func (m *Metrics) SetDeployedCommit(repo string, committedAt time.Time) {
+	m.update(repo, func(s *repoSnapshot) { s.deployedCommitTime = committedAt })
+}
+
+func (m *Metrics) ForgetRepo(repo string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.repos, repo)
+}
+
+func (m *Metrics) update(repo string, fn func(*repoSnapshot)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot, ok := m.repos[repo]
+	if !ok {
+		snapshot = &repoSnapshot{}
+		m.repos[repo] = snapshot
+	}
+	fn(snapshot)
+}
+
+func (m *Metrics) observe(_ context.Context, o metric.Observer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for repo, snapshot := range m.repos {
+		repoAttr := attribute.String("repo", repo)
+
+		counts := make(map[[2]string]int64)
+		for _, svc := range snapshot.services {
+			counts[[2]string{svc.Service, svc.State}]++
+			o.ObserveInt64(m.containerRestarts, int64(svc.Restarts),
+				metric.WithAttributes(repoAttr, attribute.String("service", svc.Service)),
+			)
+		}
+		for key, count := range counts {
+			o.ObserveInt64(m.containers, count,
+				metric.WithAttributes(repoAttr, attribute.String("service", key[0]), attribute.String("state", key[1])),
+			)
+		}
+
+		o.ObserveInt64(m.servicesOutOfSync, int64(snapshot.outOfSync), metric.WithAttributes(repoAttr))
+		o.ObserveInt64(m.pendingCommits, int64(snapshot.pendingCommits), metric.WithAttributes(repoAttr))
+		if !snapshot.deployedCommitTime.IsZero() {
+			o.ObserveFloat64(m.deployedCommitAge, now.Sub(snapshot.deployedCommitTime).Seconds(), metric.WithAttributes(repoAttr))
+		}
+	}
+	return nil
+}
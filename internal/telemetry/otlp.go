@@ -0,0 +1,160 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/grpc/credentials"
+)
+
+type Protocol string
+
+const (
+	ProtocolHTTP Protocol = "http"
+	ProtocolGRPC Protocol = "grpc"
+
+	DefaultExportInterval = time.Minute
+
+	metricsPath = "/v1/metrics"
+	tracesPath  = "/v1/traces"
+)
+
+var Protocols = []string{
+	string(ProtocolHTTP),
+	string(ProtocolGRPC),
+}
+
+var protocolSchema = z.String().OneOf(Protocols)
+
+var ErrInvalidProtocol = errors.New("invalid otlp protocol")
+
+type OTLPConfig struct {
+	Protocol Protocol
+	Endpoint string
+	Headers  map[string]string
+	Interval time.Duration
+	TLS      TLSConfig
+}
+
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+func (t TLSConfig) enabled() bool {
+	return t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" || t.InsecureSkipVerify
+}
+
+func (t TLSConfig) load() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec // opt-in for self-signed collectors
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func newOTLPReader(ctx context.Context, cfg OTLPConfig) (sdkmetric.Reader, error) {
+	exporter, err := newOTLPMetricExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultExportInterval
+	}
+	return sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval)), nil
+}
+
+func newOTLPMetricExporter(ctx context.Context, cfg OTLPConfig) (sdkmetric.Exporter, error) {
+	protocol := string(cfg.Protocol)
+	if protocol == "" {
+		protocol = string(ProtocolHTTP)
+	}
+	if err := protocolSchema.Validate(&protocol); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidProtocol, cfg.Protocol)
+	}
+
+	var tlsCfg *tls.Config
+	if cfg.TLS.enabled() {
+		var err error
+		if tlsCfg, err = cfg.TLS.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if Protocol(protocol) == ProtocolGRPC {
+		var opts []otlpmetricgrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpointURL(cfg.Endpoint))
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
+		}
+		if tlsCfg != nil {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	}
+
+	var opts []otlpmetrichttp.Option
+	if cfg.Endpoint != "" {
+		endpoint, err := signalURL(cfg.Endpoint, metricsPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, otlpmetrichttp.WithEndpointURL(endpoint))
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+	}
+	if tlsCfg != nil {
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(tlsCfg))
+	}
+	return otlpmetrichttp.New(ctx, opts...)
+}
+
+func signalURL(endpoint, path string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parse otlp endpoint: %w", err)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = path
+	}
+	return u.String(), nil
+}
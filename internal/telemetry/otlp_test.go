@@ -0,0 +1,175 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+type otlpReceiver struct {
+	mu       sync.Mutex
+	paths    []string
+	headers  []http.Header
+	requests []*collectormetrics.ExportMetricsServiceRequest
+}
+
+func newOTLPReceiver(t *testing.T) (*otlpReceiver, *httptest.Server) {
+	t.Helper()
+	r := &otlpReceiver{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		msg := &collectormetrics.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		r.paths = append(r.paths, req.URL.Path)
+		r.headers = append(r.headers, req.Header.Clone())
+		r.requests = append(r.requests, msg)
+		r.mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return r, srv
+}
+
+func (r *otlpReceiver) metricNames() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make(map[string]bool)
+	for _, req := range r.requests {
+		for _, rm := range req.GetResourceMetrics() {
+			for _, sm := range rm.GetScopeMetrics() {
+				for _, m := range sm.GetMetrics() {
+					names[m.GetName()] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+func (r *otlpReceiver) resourceAttributes() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attrs := make(map[string]string)
+	for _, req := range r.requests {
+		for _, rm := range req.GetResourceMetrics() {
+			for _, kv := range rm.GetResource().GetAttributes() {
+				attrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+			}
+		}
+	}
+	return attrs
+}
+
+func TestOTLPExportPushesMetrics(t *testing.T) {
+	receiver, srv := newOTLPReceiver(t)
+
+	provider, err := New(
+		WithoutPrometheus(),
+		WithOTLP(OTLPConfig{
+			Endpoint: srv.URL,
+			Headers:  map[string]string{"Authorization": "Bearer secret"},
+		}),
+		WithResourceAttributes(map[string]string{"deployment.environment": "staging"}),
+	)
+	if err != nil {
+		t.Fatalf(errNewFmt, err)
+	}
+	if provider.ServesPrometheus() {
+		t.Error("expected prometheus to be disabled")
+	}
+
+	provider.Metrics.RecordDeployment(context.Background(), testRepo, "success")
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if !receiver.metricNames()["kedge_deployments_total"] {
+		t.Errorf("expected kedge_deployments_total to be pushed, got %v", receiver.metricNames())
+	}
+
+	receiver.mu.Lock()
+	path, auth := receiver.paths[0], receiver.headers[0].Get("Authorization")
+	receiver.mu.Unlock()
+	if path != metricsPath {
+		t.Errorf("path: got %q, want %q", path, metricsPath)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("authorization header: got %q", auth)
+	}
+
+	attrs := receiver.resourceAttributes()
+	if attrs["deployment.environment"] != "staging" {
+		t.Errorf("deployment.environment: got %q, want %q", attrs["deployment.environment"], "staging")
+	}
+	if attrs["service.name"] != serviceName {
+		t.Errorf("service.name: got %q, want %q", attrs["service.name"], serviceName)
+	}
+	if attrs["host.name"] == "" {
+		t.Error("expected host.name resource attribute")
+	}
+}
+
+func TestOTLPAlongsidePrometheus(t *testing.T) {
+	receiver, srv := newOTLPReceiver(t)
+
+	provider, err := New(WithOTLP(OTLPConfig{Endpoint: srv.URL + "/custom/metrics"}))
+	if err != nil {
+		t.Fatalf(errNewFmt, err)
+	}
+
+	provider.Metrics.RecordDrift(context.Background(), testRepo, "web")
+	body := getMetricsBody(t, provider)
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if !receiver.metricNames()["kedge_drift_detected_total"] {
+		t.Error("expected kedge_drift_detected_total to be pushed")
+	}
+	if receiver.paths[0] != "/custom/metrics" {
+		t.Errorf("path: got %q, want %q", receiver.paths[0], "/custom/metrics")
+	}
+	if !provider.ServesPrometheus() || body == "" {
+		t.Error("expected prometheus endpoint to keep serving metrics")
+	}
+}
+
+func TestOTLPInvalidProtocol(t *testing.T) {
+	_, err := New(WithOTLP(OTLPConfig{Protocol: "udp"}))
+	if !errors.Is(err, ErrInvalidProtocol) {
+		t.Errorf("expected ErrInvalidProtocol, got %v", err)
+	}
+}
+
+func TestOTLPMissingCAFile(t *testing.T) {
+	_, err := New(WithOTLP(OTLPConfig{TLS: TLSConfig{CAFile: "/nonexistent/ca.pem"}}))
+	if err == nil {
+		t.Error("expected error for missing CA file")
+	}
+}
+
+func TestOTLPGRPCExporter(t *testing.T) {
+	provider, err := New(WithoutPrometheus(), WithOTLP(OTLPConfig{Protocol: ProtocolGRPC, Endpoint: "http://127.0.0.1:4317"}))
+	if err != nil {
+		t.Fatalf(errNewFmt, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = provider.Shutdown(ctx)
+}
@@ -7,8 +7,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+
+	"github.com/LoriKarikari/kedge/internal/version"
 )
 
 const (
@@ -24,7 +29,10 @@ type Provider struct {
 type Option func(*options)
 
 type options struct {
-	registry *prometheus.Registry
+	registry     *prometheus.Registry
+	noPrometheus bool
+	otlp         *OTLPConfig
+	attributes   map[string]string
 }
 
 func WithRegistry(r *prometheus.Registry) Option {
@@ -33,25 +41,59 @@ func WithRegistry(r *prometheus.Registry) Option {
 	}
 }
 
+func WithoutPrometheus() Option {
+	return func(o *options) {
+		o.noPrometheus = true
+	}
+}
+
+func WithOTLP(cfg OTLPConfig) Option {
+	return func(o *options) {
+		o.otlp = &cfg
+	}
+}
+
+func WithResourceAttributes(attrs map[string]string) Option {
+	return func(o *options) {
+		o.attributes = attrs
+	}
+}
+
 func New(opts ...Option) (*Provider, error) {
 	cfg := &options{}
 	for _, opt := range opts {
 		opt(cfg)
 	}
 
-	registry := cfg.registry
-	if registry == nil {
-		registry = prometheus.NewRegistry()
-	}
-
-	exporter, err := otelprom.New(otelprom.WithRegisterer(registry))
+	ctx := context.Background()
+	res, err := newResource(ctx, cfg.attributes)
 	if err != nil {
 		return nil, err
 	}
+	providerOpts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+
+	var registry *prometheus.Registry
+	if !cfg.noPrometheus {
+		registry = cfg.registry
+		if registry == nil {
+			registry = prometheus.NewRegistry()
+		}
+		exporter, err := otelprom.New(otelprom.WithRegisterer(registry))
+		if err != nil {
+			return nil, err
+		}
+		providerOpts = append(providerOpts, sdkmetric.WithReader(exporter))
+	}
 
-	provider := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(exporter),
-	)
+	if cfg.otlp != nil {
+		reader, err := newOTLPReader(ctx, *cfg.otlp)
+		if err != nil {
+			return nil, err
+		}
+		providerOpts = append(providerOpts, sdkmetric.WithReader(reader))
+	}
+
+	provider := sdkmetric.NewMeterProvider(providerOpts...)
 
 	meter := provider.Meter(meterName)
 	metrics, err := newMetrics(meter)
@@ -70,6 +112,10 @@ func (p *Provider) SetGlobal() {
 	otel.SetMeterProvider(p.meterProvider)
 }
 
+func (p *Provider) ServesPrometheus() bool {
+	return p.registry != nil
+}
+
 func (p *Provider) Handler() http.Handler {
 	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
 }
@@ -77,3 +123,19 @@ func (p *Provider) Handler() http.Handler {
 func (p *Provider) Shutdown(ctx context.Context) error {
 	return p.meterProvider.Shutdown(ctx)
 }
+
+func newResource(ctx context.Context, attrs map[string]string) (*resource.Resource, error) {
+	kvs := []attribute.KeyValue{
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version.Version()),
+	}
+	for key, value := range attrs {
+		kvs = append(kvs, attribute.String(key, value))
+	}
+	return resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+		resource.WithAttributes(kvs...),
+	)
+}
@@ -190,33 +190,91 @@ func TestMetricsRecordGitPoll(t *testing.T) {
 	}
 }
 
-func TestMetricsSetServices(t *testing.T) {
+func TestMetricsSetServiceStates(t *testing.T) {
 	provider := setupProvider(t)
 
-	provider.Metrics.SetServices(context.Background(), testRepo, "running", 3)
+	provider.Metrics.SetServiceStates(testRepo, []ServiceState{
+		{Service: "web", State: "running", Restarts: 2},
+		{Service: "web", State: "running"},
+		{Service: "worker", State: "exited"},
+	})
 
 	body := getMetricsBody(t, provider)
-	if !strings.Contains(body, "kedge_services_total") {
-		t.Error("expected kedge_services_total metric")
+	if !strings.Contains(body, `repo="`+testRepo+`",service="web",state="running"} 2`) {
+		t.Errorf("expected two running web containers, got:\n%s", body)
 	}
-	if !strings.Contains(body, `state="running"`) {
-		t.Error("expected metric to have state label")
+	if !strings.Contains(body, `service="worker",state="exited"} 1`) {
+		t.Error("expected one exited worker container")
 	}
-	if !strings.Contains(body, `repo="`+testRepo+`"`) {
-		t.Error("expected metric to have repo label")
+	if !strings.Contains(body, "kedge_container_restarts") {
+		t.Error("expected kedge_container_restarts metric")
 	}
 }
 
-func TestMetricsSetServicesUpDown(t *testing.T) {
+func TestMetricsServiceStatesReplaced(t *testing.T) {
 	provider := setupProvider(t)
-	ctx := context.Background()
 
-	provider.Metrics.SetServices(ctx, testRepo, "running", 5)
-	provider.Metrics.SetServices(ctx, testRepo, "running", -2)
+	provider.Metrics.SetServiceStates(testRepo, []ServiceState{{Service: "web", State: "running"}})
+	provider.Metrics.SetServiceStates(testRepo, []ServiceState{{Service: "api", State: "running"}})
 
 	body := getMetricsBody(t, provider)
-	if !strings.Contains(body, "kedge_services_total") {
-		t.Error("expected kedge_services_total metric")
+	if strings.Contains(body, `service="web"`) {
+		t.Error("expected stale web series to be dropped")
+	}
+	if !strings.Contains(body, `service="api"`) {
+		t.Error("expected api series")
+	}
+}
+
+func TestMetricsRepoGauges(t *testing.T) {
+	provider := setupProvider(t)
+
+	provider.Metrics.SetOutOfSync(testRepo, 2)
+	provider.Metrics.SetPendingCommits(testRepo, 3)
+	provider.Metrics.SetDeployedCommit(testRepo, time.Now().Add(-time.Hour))
+
+	if v, ok := getGaugeValue(t, provider, "kedge_services_out_of_sync", "repo", testRepo); !ok || v != 2 {
+		t.Errorf("kedge_services_out_of_sync = %v, %v, want 2", v, ok)
+	}
+	if v, ok := getGaugeValue(t, provider, "kedge_pending_commits", "repo", testRepo); !ok || v != 3 {
+		t.Errorf("kedge_pending_commits = %v, %v, want 3", v, ok)
+	}
+	if v, ok := getGaugeValue(t, provider, "kedge_deployed_commit_age_seconds", "repo", testRepo); !ok || v < 3600 {
+		t.Errorf("kedge_deployed_commit_age_seconds = %v, %v, want >= 3600", v, ok)
+	}
+
+	provider.Metrics.ForgetRepo(testRepo)
+	if _, ok := getGaugeValue(t, provider, "kedge_pending_commits", "repo", testRepo); ok {
+		t.Error("expected gauges to be removed after ForgetRepo")
+	}
+}
+
+func TestMetricsRecordImagePull(t *testing.T) {
+	provider := setupProvider(t)
+
+	provider.Metrics.RecordImagePull(context.Background(), testRepo, "web", 2*time.Second, 1024, true)
+
+	body := getMetricsBody(t, provider)
+	if !strings.Contains(body, "kedge_image_pull_duration_seconds") {
+		t.Error("expected kedge_image_pull_duration_seconds metric")
+	}
+	if !strings.Contains(body, "kedge_image_pull_bytes_total") {
+		t.Errorf("expected kedge_image_pull_bytes_total metric, got:\n%s", body)
+	}
+}
+
+func TestMetricsRecordServiceDeploy(t *testing.T) {
+	provider := setupProvider(t)
+
+	provider.Metrics.RecordServiceDeploy(context.Background(), testRepo, "web", time.Second, true)
+	provider.Metrics.RecordSuccessfulReconcile(context.Background(), testRepo)
+
+	body := getMetricsBody(t, provider)
+	if !strings.Contains(body, "kedge_service_deploy_duration_seconds") {
+		t.Error("expected kedge_service_deploy_duration_seconds metric")
+	}
+	if _, ok := getGaugeValue(t, provider, "kedge_last_successful_reconcile_timestamp_seconds", "repo", testRepo); !ok {
+		t.Errorf("expected kedge_last_successful_reconcile_timestamp_seconds metric, got:\n%s", body)
 	}
 }
 
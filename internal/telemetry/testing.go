@@ -0,0 +1,27 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func NewTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := NewTracerProvider(context.Background(), TracingConfig{}, WithSpanExporter(exporter), WithSyncExport())
+	if err != nil {
+		t.Fatalf("NewTracerProvider() error = %v", err)
+	}
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider.provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "kedge"
+
+	AttrRepo    = attribute.Key("kedge.repo")
+	AttrCommit  = attribute.Key("kedge.commit")
+	AttrService = attribute.Key("kedge.service")
+	AttrProject = attribute.Key("kedge.project")
+	AttrImage   = attribute.Key("kedge.image")
+)
+
+type TracingConfig struct {
+	Endpoint           string
+	Headers            map[string]string
+	SampleRatio        float64
+	ResourceAttributes map[string]string
+}
+
+type TracerProvider struct {
+	provider *sdktrace.TracerProvider
+}
+
+type TracingOption func(*tracingOptions)
+
+type tracingOptions struct {
+	exporter sdktrace.SpanExporter
+	sync     bool
+}
+
+func WithSpanExporter(exporter sdktrace.SpanExporter) TracingOption {
+	return func(o *tracingOptions) {
+		o.exporter = exporter
+	}
+}
+
+func WithSyncExport() TracingOption {
+	return func(o *tracingOptions) {
+		o.sync = true
+	}
+}
+
+func NewTracerProvider(ctx context.Context, cfg TracingConfig, opts ...TracingOption) (*TracerProvider, error) {
+	o := &tracingOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	exporter := o.exporter
+	if exporter == nil {
+		var exporterOpts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			endpoint, err := signalURL(cfg.Endpoint, tracesPath)
+			if err != nil {
+				return nil, err
+			}
+			exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		if len(cfg.Headers) > 0 {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		var err error
+		exporter, err = otlptracehttp.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := newResource(ctx, cfg.ResourceAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	export := sdktrace.WithBatcher(exporter)
+	if o.sync {
+		export = sdktrace.WithSyncer(exporter)
+	}
+
+	return &TracerProvider{
+		provider: sdktrace.NewTracerProvider(
+			export,
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		),
+	}, nil
+}
+
+func (p *TracerProvider) SetGlobal() {
+	otel.SetTracerProvider(p.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+func (p *TracerProvider) Shutdown(ctx context.Context) error {
+	return p.provider.Shutdown(ctx)
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(meterName)
+}
+
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
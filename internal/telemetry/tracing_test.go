@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttr(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestStartSpanRecordsAttributes(t *testing.T) {
+	exporter := NewTestTracer(t)
+
+	_, span := StartSpan(context.Background(), "Test.Span", AttrRepo.String(testRepo), AttrCommit.String("abc123"))
+	EndSpan(span, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "Test.Span" {
+		t.Errorf("name: got %q, want %q", spans[0].Name, "Test.Span")
+	}
+	if v, ok := spanAttr(spans[0], AttrRepo); !ok || v.AsString() != testRepo {
+		t.Errorf("repo attribute: got %v", v)
+	}
+	if v, ok := spanAttr(spans[0], AttrCommit); !ok || v.AsString() != "abc123" {
+		t.Errorf("commit attribute: got %v", v)
+	}
+	if spans[0].Status.Code != codes.Unset {
+		t.Errorf("status: got %v, want Unset", spans[0].Status.Code)
+	}
+}
+
+func TestEndSpanRecordsError(t *testing.T) {
+	exporter := NewTestTracer(t)
+
+	_, span := StartSpan(context.Background(), "Test.Failure")
+	EndSpan(span, errors.New("pull failed"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description != "pull failed" {
+		t.Errorf("status: got %+v", spans[0].Status)
+	}
+	if len(spans[0].Events) != 1 || spans[0].Events[0].Name != "exception" {
+		t.Errorf("expected an exception event, got %+v", spans[0].Events)
+	}
+}
+
+func TestNestedSpansShareTrace(t *testing.T) {
+	exporter := NewTestTracer(t)
+
+	ctx, parent := StartSpan(context.Background(), "Parent")
+	_, child := StartSpan(ctx, "Child")
+	EndSpan(child, nil)
+	EndSpan(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Error("expected child span to reference the parent")
+	}
+	if spans[0].SpanContext.TraceID() != spans[1].SpanContext.TraceID() {
+		t.Error("expected spans to share a trace")
+	}
+}
+
+func TestTracerProviderResource(t *testing.T) {
+	exporter := NewTestTracer(t)
+
+	_, span := StartSpan(context.Background(), "Test.Resource")
+	EndSpan(span, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	found := false
+	for _, kv := range spans[0].Resource.Attributes() {
+		if kv.Key == "service.name" && kv.Value.AsString() == serviceName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected service.name=%s in resource, got %v", serviceName, spans[0].Resource.Attributes())
+	}
+}
@@ -0,0 +1,150 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/LoriKarikari/kedge/internal/docker"
+)
+
+var supportedTopLevelKeys = map[string]bool{
+	"name":     true,
+	"version":  true,
+	"services": true,
+	"networks": true,
+	"secrets":  true,
+}
+
+var supportedServiceKeys = map[string]bool{
+	"image":       true,
+	"command":     true,
+	"entrypoint":  true,
+	"environment": true,
+	"env_file":    true,
+	"labels":      true,
+	"ports":       true,
+	"networks":    true,
+	"restart":     true,
+	"working_dir": true,
+	"deploy":      true,
+	"profiles":    true,
+	"secrets":     true,
+}
+
+var supportedDeployKeys = map[string]bool{
+	"restart_policy": true,
+}
+
+// Compose checks the compose files a repository deploys, merged in order with
+// profiles enabled. Unsupported keys are reported in the file that sets them.
+func Compose(ctx context.Context, paths []string, projectName string, profiles []string) ([]Diagnostic, error) {
+	roots := make([]*yaml.Node, len(paths))
+	var diags []Diagnostic
+	for i, path := range paths {
+		data, err := os.ReadFile(path) //nolint:gosec // path comes from the repository config being validated
+		if err != nil {
+			if os.IsNotExist(err) {
+				diags = append(diags, Diagnostic{File: path, Severity: SeverityError, Message: "compose file not found"})
+				continue
+			}
+			return nil, err
+		}
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			diags = append(diags, parseYAMLError(path, err)...)
+			continue
+		}
+		roots[i] = &root
+	}
+	if len(diags) > 0 {
+		return diags, nil
+	}
+
+	if projectName == "" || !projectNamePattern.MatchString(projectName) {
+		projectName = "kedge"
+	}
+	project, err := docker.LoadProject(ctx, paths, projectName, docker.WithProfiles(profiles...), docker.WithoutDecryption())
+	if err != nil {
+		return []Diagnostic{{File: paths[0], Severity: SeverityError, Message: err.Error()}}, nil
+	}
+
+	reported := map[string]bool{}
+	for i, path := range paths {
+		var fileDiags []Diagnostic
+		if len(roots[i].Content) == 0 {
+			continue
+		}
+		for _, top := range entries(roots[i].Content[0]) {
+			key := top.key.Value
+			if !supportedTopLevelKeys[key] && !isExtension(key) {
+				fileDiags = append(fileDiags, unsupported(path, top.key.Line, fmt.Sprintf("top-level %q", key)))
+			}
+			if key == "secrets" {
+				for _, secret := range entries(top.value) {
+					if project.Secrets[secret.key.Value].File == "" && !isExtension(secret.key.Value) {
+						fileDiags = append(fileDiags, unsupported(path, secret.key.Line, fmt.Sprintf("secret %q without \"file\"", secret.key.Value)))
+					}
+				}
+			}
+			if key != "services" {
+				continue
+			}
+			for _, svc := range entries(top.value) {
+				name := svc.key.Value
+				if s, ok := project.Services[name]; ok && s.Image == "" && !reported[name] {
+					reported[name] = true
+					fileDiags = append(fileDiags, Diagnostic{
+						File:     path,
+						Line:     svc.key.Line,
+						Severity: SeverityError,
+						Message:  fmt.Sprintf("service %q: image is required, kedge does not build images", name),
+					})
+				}
+				fileDiags = append(fileDiags, unsupportedServiceKeys(path, name, svc.value)...)
+			}
+		}
+		sortByLine(fileDiags)
+		diags = append(diags, fileDiags...)
+	}
+	return diags, nil
+}
+
+func unsupportedServiceKeys(path, service string, node *yaml.Node) []Diagnostic {
+	var diags []Diagnostic
+	for _, field := range entries(node) {
+		key := field.key.Value
+		if isExtension(key) {
+			continue
+		}
+		if !supportedServiceKeys[key] {
+			diags = append(diags, unsupported(path, field.key.Line, fmt.Sprintf("service %q: %q", service, key)))
+			continue
+		}
+		if key != "deploy" {
+			continue
+		}
+		for _, sub := range entries(field.value) {
+			if !supportedDeployKeys[sub.key.Value] && !isExtension(sub.key.Value) {
+				diags = append(diags, unsupported(path, sub.key.Line, fmt.Sprintf("service %q: %q", service, "deploy."+sub.key.Value)))
+			}
+		}
+	}
+	return diags
+}
+
+func unsupported(path string, line int, what string) Diagnostic {
+	return Diagnostic{
+		File:     path,
+		Line:     line,
+		Severity: SeverityWarning,
+		Message:  what + " is not supported by kedge and will be ignored",
+	}
+}
+
+func isExtension(key string) bool {
+	return strings.HasPrefix(key, "x-")
+}
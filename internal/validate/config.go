@@ -0,0 +1,159 @@
+package validate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+	"github.com/LoriKarikari/kedge/internal/git"
+	"github.com/LoriKarikari/kedge/internal/logging"
+	"github.com/LoriKarikari/kedge/internal/notify"
+	"github.com/LoriKarikari/kedge/internal/reconcile"
+)
+
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var unknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type config\.(\w+)$`)
+
+var (
+	durationSchema = z.CustomFunc(func(d *time.Duration, _ z.Ctx) bool {
+		return *d >= 0
+	}, z.Message("must not be negative"))
+
+	pathPatternSchema = z.String().TestFunc(func(pattern *string, _ z.Ctx) bool {
+		return git.ValidatePattern(*pattern) == nil
+	}, z.Message("must be a valid glob pattern"))
+
+	relativePathSchema = z.String().Required().TestFunc(func(path *string, _ z.Ctx) bool {
+		return !filepath.IsAbs(*path)
+	}, z.Message("must be relative to the repository root"))
+
+	configSchema = z.Struct(z.Shape{
+		"git": z.Struct(z.Shape{
+			"pollInterval": durationSchema,
+			"paths": z.Struct(z.Shape{
+				"include": z.Slice(pathPatternSchema),
+				"exclude": z.Slice(pathPatternSchema),
+			}),
+		}),
+		"docker": z.Struct(z.Shape{
+			"projectName": z.String().Required().Match(projectNamePattern,
+				z.Message("must contain only lowercase letters, digits, dashes and underscores")),
+			"composeFile":  relativePathSchema,
+			"composeFiles": z.Slice(relativePathSchema),
+		}),
+		"reconciliation": z.Struct(z.Shape{
+			"mode": z.String().TestFunc(func(mode *string, _ z.Ctx) bool {
+				_, err := reconcile.ParseMode(*mode)
+				return err == nil
+			}, z.Message("must be one of "+strings.Join(reconcile.Modes, ", "))),
+			"interval": durationSchema,
+		}),
+		"logging": z.Struct(z.Shape{
+			"level": z.String().TestFunc(func(level *string, _ z.Ctx) bool {
+				_, err := logging.ParseLevel(*level)
+				return err == nil
+			}, z.Message("must be one of "+strings.Join(logging.Levels, ", "))),
+			"format": z.String().OneOf(logging.Formats, z.Message("must be one of "+strings.Join(logging.Formats, ", "))),
+		}),
+	})
+)
+
+func Config(path string) (*config.Config, []Diagnostic, error) {
+	data, err := config.ReadExpanded(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return config.Default(), parseYAMLError(path, err), nil
+	}
+
+	cfg := config.Default()
+	var diags []Diagnostic
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		diags = parseYAMLError(path, err)
+		for i := range diags {
+			if m := unknownFieldPattern.FindStringSubmatch(diags[i].Message); m != nil {
+				diags[i].Message = fmt.Sprintf("unknown field %q", m[1])
+			}
+		}
+	}
+
+	for _, issue := range configSchema.Validate(cfg) {
+		keys := yamlPath(reflect.TypeFor[config.Config](), issue.Path)
+		diags = append(diags, Diagnostic{
+			File:     path,
+			Line:     lineOf(&root, keys),
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s %s", strings.ReplaceAll(strings.Join(keys, "."), ".[", "["), issue.Message),
+		})
+	}
+
+	if file := composeFileEntry(&root); file != nil && len(cfg.Docker.ComposeFiles) > 0 {
+		diags = append(diags, Diagnostic{
+			File:     path,
+			Line:     file.key.Line,
+			Severity: SeverityWarning,
+			Message:  "docker.compose_file is ignored when docker.compose_files is set",
+		})
+	}
+
+	if _, err := notify.New(cfg.Notifications, slog.New(slog.DiscardHandler)); err != nil {
+		diags = append(diags, Diagnostic{
+			File:     path,
+			Line:     lineOf(&root, []string{"notifications"}),
+			Severity: SeverityError,
+			Message:  "notifications: " + err.Error(),
+		})
+	}
+
+	return cfg, diags, nil
+}
+
+func composeFileEntry(root *yaml.Node) *entry {
+	if len(root.Content) == 0 {
+		return nil
+	}
+	docker := child(root.Content[0], "docker")
+	if docker == nil {
+		return nil
+	}
+	return child(docker.value, "compose_file")
+}
+
+func yamlPath(t reflect.Type, path []string) []string {
+	keys := make([]string, 0, len(path))
+	for _, p := range path {
+		for t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if strings.HasPrefix(p, "[") || t.Kind() != reflect.Struct {
+			keys = append(keys, p)
+			continue
+		}
+		field, ok := t.FieldByName(strings.ToUpper(p[:1]) + p[1:])
+		if !ok {
+			keys = append(keys, p)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		keys = append(keys, name)
+		t = field.Type
+	}
+	return keys
+}
@@ -0,0 +1,198 @@
+package validate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+
+	"github.com/LoriKarikari/kedge/internal/config"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Diagnostic struct {
+	File     string
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", d.File, d.Line, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.File, d.Severity, d.Message)
+}
+
+func HasErrors(diags []Diagnostic) bool {
+	return lo.SomeBy(diags, func(d Diagnostic) bool { return d.Severity == SeverityError })
+}
+
+func Errors(diags []Diagnostic) []Diagnostic {
+	return lo.Filter(diags, func(d Diagnostic, _ int) bool { return d.Severity == SeverityError })
+}
+
+func Warnings(diags []Diagnostic) []Diagnostic {
+	return lo.Filter(diags, func(d Diagnostic, _ int) bool { return d.Severity == SeverityWarning })
+}
+
+func Repo(ctx context.Context, configPath string) ([]Diagnostic, error) {
+	cfg, diags, err := Config(configPath)
+	if err != nil {
+		return nil, err
+	}
+	sortByLine(diags)
+
+	if len(cfg.Apps) == 0 {
+		return project(ctx, configPath, cfg, diags)
+	}
+
+	apps, err := config.ResolveApps(filepath.Dir(configPath), cfg.Apps)
+	if err != nil {
+		return append(diags, Diagnostic{File: configPath, Severity: SeverityError, Message: err.Error()}), nil
+	}
+	for _, app := range apps {
+		appDiags, err := appConfig(ctx, configPath, app)
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, appDiags...)
+	}
+	return diags, nil
+}
+
+func App(ctx context.Context, configPath string, app config.App) ([]Diagnostic, error) {
+	_, diags, err := Config(configPath)
+	if err != nil {
+		return nil, err
+	}
+	sortByLine(diags)
+
+	appDiags, err := appConfig(ctx, configPath, app)
+	if err != nil {
+		return nil, err
+	}
+	return append(diags, appDiags...), nil
+}
+
+func appConfig(ctx context.Context, rootPath string, app config.App) ([]Diagnostic, error) {
+	dir := filepath.Join(filepath.Dir(rootPath), app.Path)
+	path, err := config.FindRepo(dir)
+	if err != nil {
+		return []Diagnostic{{File: dir, Severity: SeverityError, Message: fmt.Sprintf("app %q: %v", app.Name, err)}}, nil
+	}
+
+	cfg, diags, err := Config(path)
+	if err != nil {
+		return nil, err
+	}
+	sortByLine(diags)
+	if len(cfg.Apps) > 0 {
+		diags = append(diags, Diagnostic{File: path, Severity: SeverityError, Message: "apps can only be listed in the repository root kedge.yaml"})
+	}
+	return project(ctx, path, cfg, diags)
+}
+
+func project(ctx context.Context, configPath string, cfg *config.Config, diags []Diagnostic) ([]Diagnostic, error) {
+	files := cfg.Docker.Files()
+	if slices.ContainsFunc(files, func(file string) bool { return file == "" || filepath.IsAbs(file) }) {
+		return diags, nil
+	}
+	paths := lo.Map(files, func(file string, _ int) string { return filepath.Join(filepath.Dir(configPath), file) })
+	composeDiags, err := Compose(ctx, paths, cfg.Docker.ProjectName, cfg.Docker.Profiles)
+	if err != nil {
+		return nil, err
+	}
+	return append(diags, composeDiags...), nil
+}
+
+func sortByLine(diags []Diagnostic) {
+	slices.SortStableFunc(diags, func(a, b Diagnostic) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+}
+
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func parseYAMLError(file string, err error) []Diagnostic {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	return lo.Map(messages, func(msg string, _ int) Diagnostic {
+		d := Diagnostic{File: file, Severity: SeverityError, Message: msg}
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Message = m[2]
+		}
+		return d
+	})
+}
+
+func lineOf(node *yaml.Node, path []string) int {
+	line := 0
+	if node == nil {
+		return line
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, key := range path {
+		next := child(node, key)
+		if next == nil {
+			return line
+		}
+		line = next.key.Line
+		node = next.value
+	}
+	return line
+}
+
+type entry struct {
+	key   *yaml.Node
+	value *yaml.Node
+}
+
+func child(node *yaml.Node, key string) *entry {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return &entry{key: node.Content[i], value: node.Content[i+1]}
+			}
+		}
+	case yaml.SequenceNode:
+		var idx int
+		if _, err := fmt.Sscanf(key, "[%d]", &idx); err == nil && idx >= 0 && idx < len(node.Content) {
+			return &entry{key: node.Content[idx], value: node.Content[idx]}
+		}
+	}
+	return nil
+}
+
+func entries(node *yaml.Node) []entry {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	out := make([]entry, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		out = append(out, entry{key: node.Content[i], value: node.Content[i+1]})
+	}
+	return out
+}
@@ -0,0 +1,247 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validCompose = `services:
+  web:
+    image: nginx:alpine
+    ports:
+      - "8080:80"
+    restart: always
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func findDiagnostic(diags []Diagnostic, line int, substr string) bool {
+	for _, d := range diags {
+		if d.Line == line && strings.Contains(d.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRepoValid(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "kedge.yaml", "docker:\n  project_name: webapp\n  compose_file: docker-compose.yaml\n")
+	writeFile(t, dir, "docker-compose.yaml", validCompose)
+
+	diags, err := Repo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Repo() error = %v", err)
+	}
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+}
+
+func TestConfigDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "kedge.yaml", `git:
+  pol_interval: 30s
+docker:
+  project_name: webapp
+reconciliation:
+  mode: sometimes
+  interval: -1m
+logging:
+  level: loud
+`)
+
+	_, diags, err := Config(path)
+	if err != nil {
+		t.Fatalf("Config() error = %v", err)
+	}
+
+	tests := []struct {
+		line   int
+		substr string
+	}{
+		{2, `unknown field "pol_interval"`},
+		{6, "reconciliation.mode must be one of"},
+		{7, "reconciliation.interval must not be negative"},
+		{9, "logging.level must be one of"},
+	}
+	for _, tt := range tests {
+		if !findDiagnostic(diags, tt.line, tt.substr) {
+			t.Errorf("missing diagnostic at line %d containing %q in %v", tt.line, tt.substr, diags)
+		}
+	}
+	if !HasErrors(diags) {
+		t.Error("expected errors")
+	}
+}
+
+func TestConfigSyntaxError(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "kedge.yaml", "docker:\n  project_name: [webapp\n")
+
+	_, diags, err := Config(path)
+	if err != nil {
+		t.Fatalf("Config() error = %v", err)
+	}
+	if len(diags) != 1 || diags[0].Line == 0 {
+		t.Errorf("expected one syntax error with a line, got %v", diags)
+	}
+}
+
+func TestComposeUnsupportedKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "docker-compose.yaml", `services:
+  web:
+    image: nginx:alpine
+    volumes:
+      - ./data:/data
+    deploy:
+      resources:
+        limits:
+          cpus: "1"
+      restart_policy:
+        condition: always
+    x-owner: platform
+volumes:
+  data: {}
+`)
+
+	diags, err := Compose(t.Context(), []string{path}, "webapp", nil)
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+
+	if !findDiagnostic(diags, 4, `service "web": "volumes"`) {
+		t.Errorf("expected volumes warning, got %v", diags)
+	}
+	if !findDiagnostic(diags, 7, `"deploy.resources"`) {
+		t.Errorf("expected deploy.resources warning, got %v", diags)
+	}
+	if !findDiagnostic(diags, 13, `top-level "volumes"`) {
+		t.Errorf("expected top-level volumes warning, got %v", diags)
+	}
+	if len(diags) != 3 || HasErrors(diags) {
+		t.Errorf("expected exactly three warnings, got %v", diags)
+	}
+}
+
+func TestComposeSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "db_password.txt", "s3cret")
+	path := writeFile(t, dir, "docker-compose.yaml", `services:
+  web:
+    image: nginx:alpine
+    secrets:
+      - db_password
+      - api_key
+secrets:
+  db_password:
+    file: db_password.txt
+  api_key:
+    environment: API_KEY
+`)
+
+	diags, err := Compose(t.Context(), []string{path}, "webapp", nil)
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+	if len(diags) != 1 || !findDiagnostic(diags, 10, `secret "api_key" without "file"`) {
+		t.Errorf("expected one api_key warning, got %v", diags)
+	}
+}
+
+func TestComposeRequiresImage(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "docker-compose.yaml", "services:\n  api:\n    build: .\n")
+
+	diags, err := Compose(t.Context(), []string{path}, "webapp", nil)
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+	if !findDiagnostic(diags, 2, "image is required") {
+		t.Errorf("expected missing image error, got %v", diags)
+	}
+}
+
+func TestRepoMissingCompose(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "kedge.yaml", "docker:\n  project_name: webapp\n  compose_file: missing.yaml\n")
+
+	diags, err := Repo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Repo() error = %v", err)
+	}
+	if !findDiagnostic(diags, 0, "compose file not found") {
+		t.Errorf("expected missing compose error, got %v", diags)
+	}
+}
+
+func TestRepoComposeFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "base.yaml", "services:\n  web:\n    build: .\n  debug:\n    build: .\n    profiles: [debug]\n")
+	writeFile(t, dir, "prod.yaml", "services:\n  web:\n    image: nginx:alpine\n    volumes:\n      - ./data:/data\n")
+	path := writeFile(t, dir, "kedge.yaml", "docker:\n  project_name: webapp\n  compose_file: compose.yaml\n  compose_files: [base.yaml, prod.yaml]\n")
+
+	diags, err := Repo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Repo() error = %v", err)
+	}
+	if !findDiagnostic(diags, 3, "compose_file is ignored") {
+		t.Errorf("expected compose_file warning, got %v", diags)
+	}
+	if !findDiagnostic(diags, 4, `service "web": "volumes"`) || diags[len(diags)-1].File != filepath.Join(dir, "prod.yaml") {
+		t.Errorf("expected volumes warning in prod.yaml, got %v", diags)
+	}
+	if HasErrors(diags) {
+		t.Errorf("expected the override to supply the image, got %v", diags)
+	}
+}
+
+func TestRepoApps(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "kedge.yaml", "apps:\n  - path: grafana\n  - name: prom\n    path: monitoring/prometheus\n")
+	for _, app := range []string{"grafana", filepath.Join("monitoring", "prometheus")} {
+		if err := os.MkdirAll(filepath.Join(dir, app), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, app), "docker-compose.yaml", validCompose)
+	}
+	writeFile(t, filepath.Join(dir, "grafana"), "kedge.yaml", "docker:\n  project_name: grafana\n")
+	writeFile(t, filepath.Join(dir, "monitoring", "prometheus"), "kedge.yaml", "reconciliation:\n  mode: sometimes\n")
+
+	diags, err := Repo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Repo() error = %v", err)
+	}
+	if len(diags) != 1 || !strings.Contains(diags[0].File, "prometheus") || !findDiagnostic(diags, 2, "reconciliation.mode") {
+		t.Errorf("expected one error in the prom app, got %v", diags)
+	}
+
+	writeFile(t, dir, "kedge.yaml", "apps:\n  - path: missing\n")
+	diags, err = Repo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Repo() error = %v", err)
+	}
+	if !findDiagnostic(diags, 0, "directory not found") {
+		t.Errorf("expected missing app error, got %v", diags)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	d := Diagnostic{File: "kedge.yaml", Line: 3, Severity: SeverityError, Message: "bad"}
+	if got := d.String(); got != "kedge.yaml:3: error: bad" {
+		t.Errorf("String() = %q", got)
+	}
+	d.Line = 0
+	if got := d.String(); got != "kedge.yaml: error: bad" {
+		t.Errorf("String() = %q", got)
+	}
+}